@@ -5,6 +5,7 @@ package azapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -28,10 +29,19 @@ func newErrorLine(code string, message string, inner []*DeploymentErrorLine) *De
 	}
 }
 
+// The response header ARM uses to return the ID correlating all the operations of a request
+const correlationIdHeaderName = "x-ms-correlation-request-id"
+
 type AzureDeploymentError struct {
 	Json string
 
 	Details *DeploymentErrorLine
+
+	// The structured ARM error parsed from the response, nil when the response is not a recognized ARM error.
+	// Json remains available as the raw fallback.
+	Response *AzCliDeploymentErrorResponse
+
+	correlationId string
 }
 
 func NewAzureDeploymentError(jsonErrorResponse string) *AzureDeploymentError {
@@ -44,7 +54,59 @@ func (e *AzureDeploymentError) init() {
 	var errorMap map[string]interface{}
 	if err := json.Unmarshal([]byte(e.Json), &errorMap); err == nil {
 		e.Details = getErrorsFromMap(errorMap)
+		e.correlationId = findCorrelationId(errorMap)
+		e.Response = parseErrorResponse(e.Json)
+	}
+}
+
+// CorrelationID gets the ID correlating the ARM operations of the failed deployment, which Azure support uses to
+// investigate the failure. Empty when the correlation ID is unknown.
+func (e *AzureDeploymentError) CorrelationID() string {
+	return e.correlationId
+}
+
+// Parses the ARM error from a response body, which is either the error itself, an error response with an 'error'
+// property or a failed deployment with the error within its properties.
+func parseErrorResponse(body string) *AzCliDeploymentErrorResponse {
+	var response struct {
+		AzCliDeploymentErrorResponse
+		Error      *AzCliDeploymentErrorResponse `json:"error"`
+		Properties *struct {
+			Error *AzCliDeploymentErrorResponse `json:"error"`
+		} `json:"properties"`
+	}
+
+	// Mismatched field types (ex. an unexpected additionalInfo shape) only skip that field, keep everything else
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal([]byte(body), &response); err != nil && !errors.As(err, &typeErr) {
+		return nil
 	}
+
+	switch {
+	case response.Error != nil && response.Error.Code != "":
+		return response.Error
+	case response.Properties != nil && response.Properties.Error != nil && response.Properties.Error.Code != "":
+		return response.Properties.Error
+	case response.Code != "":
+		return &response.AzCliDeploymentErrorResponse
+	default:
+		return nil
+	}
+}
+
+// Finds the correlation ID of the deployment, either at the root or within the properties of a deployment response
+func findCorrelationId(errorMap map[string]interface{}) string {
+	if correlationId, ok := errorMap["correlationId"].(string); ok {
+		return correlationId
+	}
+
+	if properties, ok := errorMap["properties"].(map[string]interface{}); ok {
+		if correlationId, ok := properties["correlationId"].(string); ok {
+			return correlationId
+		}
+	}
+
+	return ""
 }
 
 func (e *AzureDeploymentError) Error() string {
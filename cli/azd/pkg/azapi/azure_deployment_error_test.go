@@ -4,10 +4,14 @@
 package azapi
 
 import (
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/stretchr/testify/require"
 )
 
@@ -59,3 +63,98 @@ func assertOutputsMatch(t *testing.T, jsonPath string, expectedOutputPath string
 		require.Equal(t, expectedLines[index], value)
 	}
 }
+
+func Test_CorrelationID(t *testing.T) {
+	t.Run("FromDeploymentProperties", func(t *testing.T) {
+		deploymentError := NewAzureDeploymentError(`{
+			"properties": {
+				"correlationId": "00000000-0000-0000-0000-000000000001",
+				"error": {"code": "DeploymentFailed", "message": "At least one resource deployment operation failed."}
+			}
+		}`)
+		require.Equal(t, "00000000-0000-0000-0000-000000000001", deploymentError.CorrelationID())
+	})
+
+	t.Run("FromResponseHeader", func(t *testing.T) {
+		response := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(`{"error": {"code": "InvalidTemplate", "message": "bad"}}`)),
+			Request:    &http.Request{Method: http.MethodPut, URL: &url.URL{Path: "/deployments/DEPLOYMENT_NAME"}},
+		}
+		response.Header.Set("x-ms-correlation-request-id", "00000000-0000-0000-0000-000000000002")
+
+		err := createDeploymentError(runtime.NewResponseError(response))
+
+		var deploymentError *AzureDeploymentError
+		require.ErrorAs(t, err, &deploymentError)
+		require.Equal(t, "00000000-0000-0000-0000-000000000002", deploymentError.CorrelationID())
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		require.Empty(t, NewAzureDeploymentError("not json").CorrelationID())
+	})
+}
+
+func Test_ErrorResponse(t *testing.T) {
+	t.Run("NestedDetails", func(t *testing.T) {
+		deploymentError := NewAzureDeploymentError(`{
+			"error": {
+				"code": "DeploymentFailed",
+				"message": "At least one resource deployment operation failed.",
+				"details": [{
+					"code": "ResourceDeploymentFailure",
+					"target": "/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Web/sites/app",
+					"message": "The resource operation completed with terminal provisioning state 'Failed'.",
+					"details": [{
+						"code": "Conflict",
+						"message": "Website with given name app already exists.",
+						"details": [{
+							"code": "NameInUse",
+							"message": "The name 'app' is already in use.",
+							"additionalInfo": [{"type": "PolicyViolation", "info": {}}]
+						}]
+					}]
+				}, {
+					"code": "BadRequest",
+					"message": "Invalid SKU."
+				}]
+			}
+		}`)
+
+		response := deploymentError.Response
+		require.NotNil(t, response)
+		require.Equal(t, "DeploymentFailed", response.Code)
+		require.Len(t, response.Details, 2)
+		require.Equal(t, "/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Web/sites/app", response.Details[0].Target)
+		require.Equal(t, "Conflict", response.Details[0].Details[0].Code)
+		require.Equal(t, "NameInUse", response.Details[0].Details[0].Details[0].Code)
+		require.Equal(t, "The name 'app' is already in use.", response.Details[0].Details[0].Details[0].Message)
+		require.Equal(t, "BadRequest", response.Details[1].Code)
+	})
+
+	t.Run("DeploymentProperties", func(t *testing.T) {
+		deploymentError := NewAzureDeploymentError(`{
+			"properties": {
+				"error": {"code": "InvalidTemplate", "message": "Deployment template validation failed."}
+			}
+		}`)
+
+		require.NotNil(t, deploymentError.Response)
+		require.Equal(t, "InvalidTemplate", deploymentError.Response.Code)
+	})
+
+	t.Run("RootError", func(t *testing.T) {
+		deploymentError := NewAzureDeploymentError(`{"code": "InvalidTemplate", "message": "bad"}`)
+
+		require.NotNil(t, deploymentError.Response)
+		require.Equal(t, "bad", deploymentError.Response.Message)
+	})
+
+	t.Run("RawFallback", func(t *testing.T) {
+		deploymentError := NewAzureDeploymentError("upstream request timeout")
+
+		require.Nil(t, deploymentError.Response)
+		require.Equal(t, "upstream request timeout", deploymentError.Error())
+	})
+}
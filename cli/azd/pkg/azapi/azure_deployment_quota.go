@@ -0,0 +1,210 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ARM error codes that indicate a deployment failed because a quota or capacity limit was reached
+var quotaErrorCodes = []string{
+	"QuotaExceeded",
+	resourceQuotaExceededCode,
+	"SkuNotAvailable",
+	"OperationNotAllowed",
+}
+
+var (
+	// Matches "exceeding approved standardDSv3Family Cores quota"
+	quotaResourceTypeRegex = regexp.MustCompile(`(?i)exceeding approved (.+?) quota`)
+	// Matches "Current Limit: 10" and "Current Limit (Basic VMs): 10"
+	quotaLimitRegex = regexp.MustCompile(`(?i)current limit(?:\s*\(([^)]+)\))?\s*:\s*(\d+)`)
+	// Matches "Current Usage: 8"
+	quotaUsageRegex = regexp.MustCompile(`(?i)current usage\s*:\s*(\d+)`)
+	// Matches "Additional Required: 4" and "Amount required for this deployment (Basic VMs): 1"
+	quotaRequiredRegex = regexp.MustCompile(`(?i)(?:additional required|amount required for this deployment` +
+		`(?:\s*\([^)]+\))?)\s*:\s*(\d+)`)
+	// Matches "Location: eastus," and "location 'eastus'"
+	quotaLocationRegex = regexp.MustCompile(`(?i)location(?::\s*|\s+')([^,'\r\n]+)`)
+)
+
+const (
+	// The ARM error code returned when the deployment history of a scope reached the limit of 800 deployments
+	deploymentQuotaExceededCode = "DeploymentQuotaExceeded"
+	// The ARM error code returned when a resource quota of the subscription or a resource group is exceeded
+	resourceQuotaExceededCode = "ResourceQuotaExceeded"
+)
+
+// DeploymentQuotaExceededError is returned when a deployment fails because the deployment history of its scope reached
+// the ARM limit of 800 deployments. Deleting old deployments of the scope, ex. with DeleteSubscriptionDeployment, frees
+// up the quota.
+type DeploymentQuotaExceededError struct {
+	*AzureDeploymentError
+}
+
+func (e *DeploymentQuotaExceededError) Unwrap() error {
+	return e.AzureDeploymentError
+}
+
+// ResourceQuotaExceededError is returned when a deployment fails because it exceeds a resource quota of the subscription
+// or resource group.
+type ResourceQuotaExceededError struct {
+	*AzureDeploymentError
+	// The details of the exceeded quota parsed from the error message
+	Quota QuotaInfo
+}
+
+func (e *ResourceQuotaExceededError) Unwrap() error {
+	return e.AzureDeploymentError
+}
+
+// Wraps the deployment error into a typed error when it is caused by an exceeded deployment or resource quota
+func classifyQuotaError(err *AzureDeploymentError) error {
+	if err.Details == nil {
+		return err
+	}
+
+	if findErrorLine(err.Details, deploymentQuotaExceededCode) != nil {
+		return &DeploymentQuotaExceededError{AzureDeploymentError: err}
+	}
+
+	if line := findErrorLine(err.Details, resourceQuotaExceededCode); line != nil {
+		return &ResourceQuotaExceededError{
+			AzureDeploymentError: err,
+			Quota:                newQuotaInfo(line.Code, line.Message),
+		}
+	}
+
+	return err
+}
+
+// Walks the error line tree and returns the first line with the error code
+func findErrorLine(line *DeploymentErrorLine, code string) *DeploymentErrorLine {
+	if line == nil {
+		return nil
+	}
+
+	if strings.EqualFold(line.Code, code) {
+		return line
+	}
+
+	for _, inner := range line.Inner {
+		if match := findErrorLine(inner, code); match != nil {
+			return match
+		}
+	}
+
+	return nil
+}
+
+// QuotaInfo contains the details of a quota-exceeded deployment error
+type QuotaInfo struct {
+	// The ARM error code, e.g. QuotaExceeded
+	Code string
+	// The full error message returned by ARM
+	Message string
+	// The quota resource type (e.g. 'Total Regional Cores'), when available in the message
+	ResourceType string
+	// The location the quota applies to, when available in the message
+	Location string
+	// The current quota limit, when available in the message
+	Limit int
+	// The current quota usage, when available in the message
+	Usage int
+	// The additional amount required by the deployment, when available in the message
+	Required int
+}
+
+// IsQuotaExceeded returns true when the error, or any of its inner deployment errors, represents a quota or capacity
+// failure. When the error message contains quota details they are parsed into the returned QuotaInfo.
+func IsQuotaExceeded(err error) (QuotaInfo, bool) {
+	if err == nil {
+		return QuotaInfo{}, false
+	}
+
+	var deploymentErr *AzureDeploymentError
+	if errors.As(err, &deploymentErr) && deploymentErr.Details != nil {
+		if line := findQuotaErrorLine(deploymentErr.Details); line != nil {
+			return newQuotaInfo(line.Code, line.Message), true
+		}
+
+		return QuotaInfo{}, false
+	}
+
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && isQuotaErrorCode(responseErr.ErrorCode) {
+		return newQuotaInfo(responseErr.ErrorCode, responseErr.Error()), true
+	}
+
+	return QuotaInfo{}, false
+}
+
+// Walks the error line tree and returns the first line with a quota related error code
+func findQuotaErrorLine(line *DeploymentErrorLine) *DeploymentErrorLine {
+	if line == nil {
+		return nil
+	}
+
+	if isQuotaErrorCode(line.Code) {
+		return line
+	}
+
+	for _, inner := range line.Inner {
+		if match := findQuotaErrorLine(inner); match != nil {
+			return match
+		}
+	}
+
+	return nil
+}
+
+func isQuotaErrorCode(code string) bool {
+	for _, quotaCode := range quotaErrorCodes {
+		if strings.EqualFold(code, quotaCode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newQuotaInfo(code string, message string) QuotaInfo {
+	// Error lines are formatted as '<code>: <message>', only the message is relevant here
+	message = strings.TrimPrefix(message, code+": ")
+
+	info := QuotaInfo{
+		Code:    code,
+		Message: message,
+	}
+
+	if matches := quotaResourceTypeRegex.FindStringSubmatch(message); matches != nil {
+		info.ResourceType = strings.TrimSpace(matches[1])
+	}
+
+	if matches := quotaLimitRegex.FindStringSubmatch(message); matches != nil {
+		if info.ResourceType == "" {
+			info.ResourceType = strings.TrimSpace(matches[1])
+		}
+		info.Limit, _ = strconv.Atoi(matches[2])
+	}
+
+	if matches := quotaUsageRegex.FindStringSubmatch(message); matches != nil {
+		info.Usage, _ = strconv.Atoi(matches[1])
+	}
+
+	if matches := quotaRequiredRegex.FindStringSubmatch(message); matches != nil {
+		info.Required, _ = strconv.Atoi(matches[1])
+	}
+
+	if matches := quotaLocationRegex.FindStringSubmatch(message); matches != nil {
+		info.Location = strings.TrimSpace(matches[1])
+	}
+
+	return info
+}
@@ -0,0 +1,181 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsQuotaExceeded(t *testing.T) {
+	tests := map[string]struct {
+		json     string
+		expected QuotaInfo
+		isQuota  bool
+	}{
+		"RegionalCores": {
+			json: `{
+				"error": {
+					"code": "DeploymentFailed",
+					"message": "At least one resource deployment operation failed.",
+					"details": [{
+						"code": "QuotaExceeded",
+						"message": "Operation could not be completed as it results in exceeding approved ` +
+				`Total Regional Cores quota. Additional details - Deployment Model: Resource Manager, ` +
+				`Location: eastus, Current Limit: 10, Current Usage: 8, Additional Required: 4, ` +
+				`(Minimum) New Limit Required: 12."
+					}]
+				}
+			}`,
+			isQuota: true,
+			expected: QuotaInfo{
+				Code:         "QuotaExceeded",
+				ResourceType: "Total Regional Cores",
+				Location:     "eastus",
+				Limit:        10,
+				Usage:        8,
+				Required:     4,
+			},
+		},
+		"AppServicePlan": {
+			json: `{
+				"error": {
+					"code": "DeploymentFailed",
+					"details": [{
+						"code": "Unauthorized",
+						"message": "{\"Code\":\"Unauthorized\",` +
+				`\"Message\":\"Operation cannot be completed without additional quota.\",` +
+				`\"Details\":[{\"Code\":\"OperationNotAllowed\",` +
+				`\"Message\":\"Operation cannot be completed without additional quota. ` +
+				`\\r\\nAdditional Details - Location: East US \\r\\nCurrent Limit (Basic VMs): 0 ` +
+				`\\r\\nCurrent Usage: 0\\r\\nAmount required for this deployment (Basic VMs): 1 \\r\\n\"}]}"
+					}]
+				}
+			}`,
+			isQuota: true,
+			expected: QuotaInfo{
+				Code:         "OperationNotAllowed",
+				ResourceType: "Basic VMs",
+				Location:     "East US",
+				Limit:        0,
+				Usage:        0,
+				Required:     1,
+			},
+		},
+		"SkuNotAvailable": {
+			json: `{
+				"error": {
+					"code": "SkuNotAvailable",
+					"message": "The requested size for resource ` +
+				`'/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Compute/virtualMachines/vm' ` +
+				`is currently not available in location 'westus2' zones '' for subscription 'SUB'."
+				}
+			}`,
+			isQuota: true,
+			expected: QuotaInfo{
+				Code:     "SkuNotAvailable",
+				Location: "westus2",
+			},
+		},
+		"NotQuota": {
+			json: `{
+				"error": {
+					"code": "InvalidTemplate",
+					"message": "Deployment template validation failed."
+				}
+			}`,
+			isQuota: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := fmt.Errorf("deploying to subscription: %w", NewAzureDeploymentError(test.json))
+			info, ok := IsQuotaExceeded(err)
+			require.Equal(t, test.isQuota, ok)
+			if !test.isQuota {
+				return
+			}
+
+			require.NotEmpty(t, info.Message)
+			require.Equal(t, test.expected.Code, info.Code)
+			require.Equal(t, test.expected.ResourceType, info.ResourceType)
+			require.Equal(t, test.expected.Location, info.Location)
+			require.Equal(t, test.expected.Limit, info.Limit)
+			require.Equal(t, test.expected.Usage, info.Usage)
+			require.Equal(t, test.expected.Required, info.Required)
+		})
+	}
+
+	t.Run("NonDeploymentError", func(t *testing.T) {
+		_, ok := IsQuotaExceeded(errors.New("QuotaExceeded"))
+		require.False(t, ok)
+	})
+}
+
+func Test_CreateDeploymentError_Quota(t *testing.T) {
+	newResponseError := func(body string) error {
+		return runtime.NewResponseError(&http.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    &http.Request{Method: http.MethodPut, URL: &url.URL{Path: "/deployments/DEPLOYMENT_NAME"}},
+		})
+	}
+
+	t.Run("DeploymentQuotaExceeded", func(t *testing.T) {
+		err := createDeploymentError(newResponseError(`{
+			"error": {
+				"code": "DeploymentQuotaExceeded",
+				"message": "Creating the deployment 'dev-1' would exceed the quota of '800'."
+			}
+		}`))
+
+		var quotaErr *DeploymentQuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Contains(t, err.Error(), "would exceed the quota of '800'")
+
+		// The typed error still unwraps to the deployment error
+		var deploymentErr *AzureDeploymentError
+		require.ErrorAs(t, err, &deploymentErr)
+		require.Contains(t, RemediationHint(err), "Delete old deployments")
+	})
+
+	t.Run("ResourceQuotaExceeded", func(t *testing.T) {
+		err := createDeploymentError(newResponseError(`{
+			"error": {
+				"code": "DeploymentFailed",
+				"details": [{
+					"code": "ResourceQuotaExceeded",
+					"message": "Creating the resource of type 'Microsoft.Web/serverFarms' would exceed the quota of ` +
+			`'10' resources of type 'Microsoft.Web/serverFarms' per resource group. Location: eastus2, Current Usage: 10."
+				}]
+			}
+		}`))
+
+		var quotaErr *ResourceQuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		require.Equal(t, "ResourceQuotaExceeded", quotaErr.Quota.Code)
+		require.Equal(t, "eastus2", quotaErr.Quota.Location)
+		require.Equal(t, 10, quotaErr.Quota.Usage)
+		require.Contains(t, RemediationHint(err), "quota increase")
+	})
+
+	t.Run("OtherErrors", func(t *testing.T) {
+		err := createDeploymentError(newResponseError(`{"error": {"code": "InvalidTemplate", "message": "bad"}}`))
+
+		var deploymentErr *AzureDeploymentError
+		require.ErrorAs(t, err, &deploymentErr)
+		require.False(t, errors.As(err, new(*DeploymentQuotaExceededError)))
+		require.False(t, errors.As(err, new(*ResourceQuotaExceededError)))
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// A category of deployment failures that share the same remediation
+type remediationCategory struct {
+	codes []string
+	hint  string
+}
+
+// The categories of deployment failures with a known remediation, in priority order. Quota failures are handled
+// separately since their hint includes the details parsed from the error message.
+var remediationCategories = []remediationCategory{
+	{
+		codes: []string{deploymentQuotaExceededCode},
+		hint: "The deployment history of the target scope reached the limit of 800 deployments. Delete old " +
+			"deployments, ex. with 'az deployment sub delete --name <deployment name>', and retry.",
+	},
+	{
+		codes: []string{"AuthorizationFailed", "LinkedAuthorizationFailed", "AuthorizationPermissionMismatch"},
+		hint: "The account running the deployment is missing permissions. Assign it the 'Contributor' role on the " +
+			"target scope, and the 'User Access Administrator' or 'Owner' role when the template creates role " +
+			"assignments: https://learn.microsoft.com/azure/role-based-access-control/role-assignments-portal",
+	},
+	{
+		codes: []string{"RequestDisallowedByPolicy"},
+		hint: "An Azure Policy assignment denied the deployment. Update the template to comply with the policy or " +
+			"ask an administrator for a policy exemption.",
+	},
+	{
+		codes: []string{"MissingSubscriptionRegistration", "NoRegisteredProviderFound"},
+		hint: "A resource provider used by the template is not registered for the subscription. Register it with " +
+			"'az provider register --namespace <provider namespace>' and retry.",
+	},
+	{
+		codes: []string{"LocationNotAvailableForResourceType", "NoRegisteredProviderFoundForLocation"},
+		hint:  "A resource type used by the template is not available in the selected location. Choose a different location.",
+	},
+	{
+		codes: []string{"InvalidTemplate", "InvalidTemplateDeployment", "InvalidDeploymentParameterValue"},
+		hint:  "The template or its parameters failed validation. Fix the validation errors reported above and retry.",
+	},
+}
+
+// RemediationHint returns actionable guidance for a failed deployment based on the error codes it contains,
+// ex. how to request a quota increase or which role is required. Returns empty when the failure has no known
+// remediation.
+func RemediationHint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if info, ok := IsQuotaExceeded(err); ok {
+		return quotaRemediationHint(info)
+	}
+
+	codes := remediationErrorCodes(err)
+	for _, category := range remediationCategories {
+		for _, code := range codes {
+			for _, categoryCode := range category.codes {
+				if strings.EqualFold(code, categoryCode) {
+					return category.hint
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func quotaRemediationHint(info QuotaInfo) string {
+	quota := "the quota"
+	if info.ResourceType != "" {
+		quota = fmt.Sprintf("the '%s' quota", info.ResourceType)
+	}
+
+	if info.Location != "" {
+		quota = fmt.Sprintf("%s in '%s'", quota, info.Location)
+	}
+
+	return fmt.Sprintf(
+		"The deployment exceeds %s. Request a quota increase "+
+			"(https://learn.microsoft.com/azure/quotas/quickstart-increase-quota-portal), "+
+			"or deploy to a different location or SKU.",
+		quota,
+	)
+}
+
+// Gets the error codes of the deployment error and all its inner errors
+func remediationErrorCodes(err error) []string {
+	codes := []string{}
+
+	var deploymentErr *AzureDeploymentError
+	if errors.As(err, &deploymentErr) && deploymentErr.Details != nil {
+		var walk func(line *DeploymentErrorLine)
+		walk = func(line *DeploymentErrorLine) {
+			if line == nil {
+				return
+			}
+
+			if line.Code != "" {
+				codes = append(codes, line.Code)
+			}
+
+			for _, inner := range line.Inner {
+				walk(inner)
+			}
+		}
+
+		walk(deploymentErr.Details)
+	}
+
+	var responseErr *azcore.ResponseError
+	if errors.As(err, &responseErr) && responseErr.ErrorCode != "" {
+		codes = append(codes, responseErr.ErrorCode)
+	}
+
+	return codes
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RemediationHint(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		contains []string
+	}{
+		"Quota": {
+			err: NewAzureDeploymentError(`{
+				"error": {
+					"code": "DeploymentFailed",
+					"details": [{
+						"code": "QuotaExceeded",
+						"message": "Operation could not be completed as it results in exceeding approved ` +
+				`Total Regional Cores quota. Additional details - Location: eastus, Current Limit: 10."
+					}]
+				}
+			}`),
+			contains: []string{"'Total Regional Cores' quota in 'eastus'", "quota increase"},
+		},
+		"Authorization": {
+			err: NewAzureDeploymentError(`{
+				"error": {
+					"code": "DeploymentFailed",
+					"details": [{
+						"code": "Forbidden",
+						"details": [{"code": "AuthorizationFailed", "message": "The client does not have authorization."}]
+					}]
+				}
+			}`),
+			contains: []string{"'Contributor'", "'User Access Administrator'"},
+		},
+		"Policy": {
+			err:      NewAzureDeploymentError(`{"error": {"code": "RequestDisallowedByPolicy", "message": "denied"}}`),
+			contains: []string{"Azure Policy"},
+		},
+		"ProviderRegistration": {
+			err:      &azcore.ResponseError{ErrorCode: "MissingSubscriptionRegistration"},
+			contains: []string{"az provider register"},
+		},
+		"Wrapped": {
+			err: fmt.Errorf(
+				"deploying to subscription: %w",
+				NewAzureDeploymentError(`{"error": {"code": "InvalidTemplate", "message": "bad template"}}`)),
+			contains: []string{"failed validation"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			hint := RemediationHint(test.err)
+			for _, expected := range test.contains {
+				require.Contains(t, hint, expected)
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		require.Empty(t, RemediationHint(nil))
+		require.Empty(t, RemediationHint(errors.New("something went wrong")))
+		require.Empty(t, RemediationHint(
+			NewAzureDeploymentError(`{"error": {"code": "Conflict", "message": "operation in progress"}}`)))
+	})
+}
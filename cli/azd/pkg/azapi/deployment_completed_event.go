@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/convert"
+)
+
+// The value reported in place of secure outputs
+const maskedOutputValue = "********"
+
+// CompletedEvent is a structured summary of a finished deployment, meant to be serialized as JSON for tooling that
+// consumes azd output as events. JSON field names are part of the contract and must not change.
+type CompletedEvent struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// The ISO 8601 duration of the deployment as reported by ARM, ex. 'PT1M30S'
+	Duration      string                 `json:"duration"`
+	ResourceCount int                    `json:"resourceCount"`
+	Outputs       map[string]EventOutput `json:"outputs"`
+	// Set when the deployment did not succeed
+	Error *EventError `json:"error,omitempty"`
+}
+
+// EventOutput is a deployment output within a CompletedEvent, the value of secure outputs is masked
+type EventOutput struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// EventError describes the failure of a deployment within a CompletedEvent
+type EventError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DeploymentCompletedEvent creates the completed event for the specified deployment
+func DeploymentCompletedEvent(d *armresources.DeploymentExtended) CompletedEvent {
+	event := CompletedEvent{
+		Name:    convert.ToValueWithDefault(d.Name, ""),
+		Outputs: map[string]EventOutput{},
+	}
+
+	if d.Properties == nil {
+		return event
+	}
+
+	event.State = string(convert.ToValueWithDefault(d.Properties.ProvisioningState, ""))
+	event.Duration = convert.ToValueWithDefault(d.Properties.Duration, "")
+	event.ResourceCount = len(d.Properties.OutputResources)
+
+	if outputs, ok := d.Properties.Outputs.(map[string]any); ok {
+		for name, raw := range outputs {
+			output, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			eventOutput := EventOutput{Value: output["value"]}
+			eventOutput.Type, _ = output["type"].(string)
+			if isSecureOutputType(eventOutput.Type) {
+				eventOutput.Value = maskedOutputValue
+			}
+
+			event.Outputs[name] = eventOutput
+		}
+	}
+
+	if d.Properties.Error != nil {
+		event.Error = &EventError{
+			Code:    convert.ToValueWithDefault(d.Properties.Error.Code, ""),
+			Message: convert.ToValueWithDefault(d.Properties.Error.Message, ""),
+		}
+	} else if d.Properties.ProvisioningState != nil &&
+		*d.Properties.ProvisioningState != armresources.ProvisioningStateSucceeded &&
+		isTerminalProvisioningState(*d.Properties.ProvisioningState) {
+		event.Error = &EventError{
+			Code:    event.State,
+			Message: fmt.Sprintf("deployment '%s' finished in state '%s'", event.Name, event.State),
+		}
+	}
+
+	return event
+}
+
+func isSecureOutputType(outputType string) bool {
+	return strings.EqualFold(outputType, "securestring") || strings.EqualFold(outputType, "secureobject")
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeploymentCompletedEvent(t *testing.T) {
+	t.Run("Succeeded", func(t *testing.T) {
+		event := DeploymentCompletedEvent(&armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Duration:          to.Ptr("PT1M30S"),
+				OutputResources: []*armresources.ResourceReference{
+					{ID: to.Ptr("/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Web/sites/app")},
+					{ID: to.Ptr("/subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Web/serverfarms/plan")},
+				},
+				Outputs: map[string]any{
+					"endpoint": map[string]any{"type": "String", "value": "https://app.azurewebsites.net"},
+					"password": map[string]any{"type": "SecureString", "value": "P@ssw0rd"},
+				},
+			},
+		})
+
+		raw, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"name": "DEPLOYMENT_NAME",
+			"state": "Succeeded",
+			"duration": "PT1M30S",
+			"resourceCount": 2,
+			"outputs": {
+				"endpoint": {"type": "String", "value": "https://app.azurewebsites.net"},
+				"password": {"type": "SecureString", "value": "********"}
+			}
+		}`, string(raw))
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		event := DeploymentCompletedEvent(&armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateFailed),
+				Duration:          to.Ptr("PT10S"),
+				Error: &armresources.ErrorResponse{
+					Code:    to.Ptr("DeploymentFailed"),
+					Message: to.Ptr("At least one resource deployment operation failed."),
+				},
+			},
+		})
+
+		raw, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"name": "DEPLOYMENT_NAME",
+			"state": "Failed",
+			"duration": "PT10S",
+			"resourceCount": 0,
+			"outputs": {},
+			"error": {"code": "DeploymentFailed", "message": "At least one resource deployment operation failed."}
+		}`, string(raw))
+	})
+
+	t.Run("CanceledWithoutError", func(t *testing.T) {
+		event := DeploymentCompletedEvent(&armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateCanceled),
+			},
+		})
+
+		require.NotNil(t, event.Error)
+		require.Equal(t, "Canceled", event.Error.Code)
+	})
+}
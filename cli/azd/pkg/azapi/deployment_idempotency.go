@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// IdempotencyKeyTagName is the deployment tag holding a caller-provided idempotency key.
+// When the tags of a deployment contain this key, the deploy methods first look for an existing deployment at the same
+// scope with the same key and return it instead of starting a duplicate deployment.
+const IdempotencyKeyTagName = "azd-idempotency-key"
+
+// Finds an existing deployment tagged with the same idempotency key that is either still in progress or succeeded.
+// Returns nil when no idempotency key is set, when no deployment matches, or when the matching deployment
+// ended in a state that allows the deployment to be retried (failed, canceled or deleted).
+//
+// A retried deployment usually reuses its deployment name, so the deployment with that name is checked first. Only
+// when it doesn't match are the deployments of the scope listed, stopping at the first match.
+func findIdempotentDeployment(
+	tags map[string]*string,
+	getDeployment func() (*armresources.DeploymentExtended, error),
+	listDeployments func(options *ListDeploymentsOptions) ([]*armresources.DeploymentExtended, error),
+) (*armresources.DeploymentExtended, error) {
+	key, has := tags[IdempotencyKeyTagName]
+	if !has || key == nil || *key == "" {
+		return nil, nil
+	}
+
+	deployment, err := getDeployment()
+	if err != nil && !errors.Is(err, ErrDeploymentNotFound) {
+		return nil, fmt.Errorf("finding deployment with idempotency key '%s': %w", *key, err)
+	}
+	if deployment != nil && isIdempotentMatch(deployment, *key) {
+		return deployment, nil
+	}
+
+	deployments, err := listDeployments(&ListDeploymentsOptions{
+		Predicate: func(deployment *armresources.DeploymentExtended) bool {
+			return isIdempotentMatch(deployment, *key)
+		},
+		MaxCount: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding deployments with idempotency key '%s': %w", *key, err)
+	}
+
+	if len(deployments) == 0 {
+		return nil, nil
+	}
+
+	return deployments[0], nil
+}
+
+// Gets whether the deployment is tagged with the idempotency key and is either still in progress or succeeded
+func isIdempotentMatch(deployment *armresources.DeploymentExtended, key string) bool {
+	existingKey, has := deployment.Tags[IdempotencyKeyTagName]
+	if !has || existingKey == nil || *existingKey != key {
+		return false
+	}
+
+	if deployment.Properties != nil && deployment.Properties.ProvisioningState != nil {
+		switch *deployment.Properties.ProvisioningState {
+		case armresources.ProvisioningStateFailed,
+			armresources.ProvisioningStateCanceled,
+			armresources.ProvisioningStateDeleted:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The maximum length of the name of a deployment in ARM
+const deploymentNameLengthMax = 64
+
+// Matches the characters that are not allowed within ARM deployment names. ARM allows alphanumerics, underscores,
+// parentheses, hyphens and periods.
+var invalidDeploymentNameCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9_().-]`)
+
+// GenerateDeploymentName creates a unique deployment name for the environment by appending the unix time to the
+// environment name, separated by a hyphen, ex. 'my-env-1683303710'. Unique names keep the deployment history of the
+// environment instead of overwriting the previous deployment.
+//
+// Characters of the environment name that are not allowed by ARM are replaced with hyphens. When the name is longer
+// than the ARM limit, the start of the environment name is truncated so that the timestamp is always kept.
+func GenerateDeploymentName(envName string, t time.Time) string {
+	envName = strings.Trim(invalidDeploymentNameCharsRegex.ReplaceAllString(envName, "-"), "-")
+
+	timestamp := fmt.Sprintf("%d", t.Unix())
+	if envName == "" {
+		return timestamp
+	}
+
+	name := fmt.Sprintf("%s-%s", envName, timestamp)
+	if len(name) <= deploymentNameLengthMax {
+		return name
+	}
+
+	return strings.TrimLeft(name[len(name)-deploymentNameLengthMax:], "-")
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GenerateDeploymentName(t *testing.T) {
+	now := time.Unix(1683303710, 0)
+	validName := regexp.MustCompile(`^[a-zA-Z0-9_().-]{1,64}$`)
+
+	tests := map[string]struct {
+		envName  string
+		expected string
+	}{
+		"Simple": {
+			envName:  "simple-name",
+			expected: "simple-name-1683303710",
+		},
+		"Truncated": {
+			envName:  "azd-template-test-apim-todo-csharp-sql-swa-func-2750207-2",
+			expected: "template-test-apim-todo-csharp-sql-swa-func-2750207-2-1683303710",
+		},
+		"InvalidCharacters": {
+			envName:  "my env/dev#1",
+			expected: "my-env-dev-1-1683303710",
+		},
+		"TruncatedAtHyphen": {
+			envName:  "ab-" + strings.Repeat("c", 52),
+			expected: strings.Repeat("c", 52) + "-1683303710",
+		},
+		"Empty": {
+			envName:  "",
+			expected: "1683303710",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			deploymentName := GenerateDeploymentName(test.envName, now)
+			require.Equal(t, test.expected, deploymentName)
+			require.Regexp(t, validName, deploymentName)
+		})
+	}
+
+	t.Run("Unique", func(t *testing.T) {
+		require.NotEqual(t, GenerateDeploymentName("env", now), GenerateDeploymentName("env", now.Add(time.Second)))
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/convert"
+)
+
+// DeploymentOperationFailure describes a deployment operation that failed along with the error reported by the
+// resource provider for the target resource.
+type DeploymentOperationFailure struct {
+	OperationId  string
+	ResourceId   string
+	ResourceType string
+	ResourceName string
+	// The HTTP status code returned by the resource provider, ex. 'Conflict'
+	StatusCode string
+	// The error returned by the resource provider, from the status message of the operation
+	Error *armresources.ErrorResponse
+}
+
+// FailedDeploymentOperations returns the failed operations with the nested status message error of each operation,
+// so callers can report which resource failed and why instead of the generic deployment failure.
+func FailedDeploymentOperations(operations []*armresources.DeploymentOperation) []DeploymentOperationFailure {
+	failures := []DeploymentOperationFailure{}
+
+	for _, operation := range operations {
+		if operation == nil || operation.Properties == nil || operation.Properties.ProvisioningState == nil ||
+			*operation.Properties.ProvisioningState != string(armresources.ProvisioningStateFailed) {
+			continue
+		}
+
+		failure := DeploymentOperationFailure{
+			OperationId: convert.ToValueWithDefault(operation.OperationID, ""),
+			StatusCode:  convert.ToValueWithDefault(operation.Properties.StatusCode, ""),
+		}
+
+		if target := operation.Properties.TargetResource; target != nil {
+			failure.ResourceId = convert.ToValueWithDefault(target.ID, "")
+			failure.ResourceType = convert.ToValueWithDefault(target.ResourceType, "")
+			failure.ResourceName = convert.ToValueWithDefault(target.ResourceName, "")
+		}
+
+		if statusMessage := operation.Properties.StatusMessage; statusMessage != nil {
+			failure.Error = statusMessage.Error
+		}
+
+		failures = append(failures, failure)
+	}
+
+	return failures
+}
+
+// GetDeploymentErrorReport lists the operations of the deployment and formats its failed operations into a readable
+// report, see FormatDeploymentErrorReport. Returns empty when no operation failed.
+func (ds *deployments) GetDeploymentErrorReport(
+	ctx context.Context,
+	scope DeploymentScope,
+	deploymentName string,
+) (string, error) {
+	operations, err := ds.ListDeploymentOperations(ctx, scope, deploymentName)
+	if err != nil {
+		return "", fmt.Errorf("listing operations of deployment '%s': %w", deploymentName, err)
+	}
+
+	return FormatDeploymentErrorReport(deploymentName, FailedDeploymentOperations(operations)), nil
+}
+
+// FormatDeploymentErrorReport formats the failed deployment operations into an indented report listing the type,
+// name and status code of each failed resource followed by its error and nested error details, ex.
+//
+//	Deployment 'dev-1683303710' has 1 failed operation:
+//	  Microsoft.Web/sites 'app' (Conflict)
+//	    WebsiteNameTaken: Website with given name app already exists.
+//
+// Returns empty when there are no failures.
+func FormatDeploymentErrorReport(deploymentName string, failures []DeploymentOperationFailure) string {
+	if len(failures) == 0 {
+		return ""
+	}
+
+	operations := "operations"
+	if len(failures) == 1 {
+		operations = "operation"
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Deployment '%s' has %d failed %s:\n", deploymentName, len(failures), operations))
+
+	for _, failure := range failures {
+		resource := failure.ResourceType
+		if failure.ResourceName != "" {
+			resource = strings.TrimSpace(fmt.Sprintf("%s '%s'", resource, failure.ResourceName))
+		}
+		if resource == "" {
+			resource = fmt.Sprintf("Operation '%s'", failure.OperationId)
+		}
+
+		if failure.StatusCode != "" {
+			resource = fmt.Sprintf("%s (%s)", resource, failure.StatusCode)
+		}
+
+		sb.WriteString(fmt.Sprintf("  %s\n", resource))
+		writeErrorResponse(&sb, failure.Error, 2)
+	}
+
+	return sb.String()
+}
+
+// Writes the error and its nested details, each nesting level indented further
+func writeErrorResponse(sb *strings.Builder, err *armresources.ErrorResponse, depth int) {
+	if err == nil {
+		return
+	}
+
+	code := convert.ToValueWithDefault(err.Code, "")
+	message := strings.TrimSpace(convert.ToValueWithDefault(err.Message, ""))
+
+	line := message
+	if code != "" && message != "" {
+		line = fmt.Sprintf("%s: %s", code, message)
+	} else if code != "" {
+		line = code
+	}
+
+	// Generic deployment failures only wrap the details of the actual error
+	if line != "" && code != "DeploymentFailed" && code != "ResourceDeploymentFailure" {
+		sb.WriteString(fmt.Sprintf("%s%s\n", strings.Repeat("  ", depth), line))
+		depth++
+	}
+
+	for _, detail := range err.Details {
+		writeErrorResponse(sb, detail, depth)
+	}
+}
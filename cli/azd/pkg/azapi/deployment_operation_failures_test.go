@@ -0,0 +1,175 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListDeploymentOperations_Failures(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+
+	// Operations are returned across two pages
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/deployments/DEPLOYMENT_NAME/operations") &&
+			request.URL.Query().Get("page") == ""
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+			NextLink: to.Ptr("https://management.azure.com" + request.URL.Path + "?page=2"),
+			Value: []*armresources.DeploymentOperation{
+				{
+					OperationID: to.Ptr("1"),
+					Properties: &armresources.DeploymentOperationProperties{
+						ProvisioningState: to.Ptr("Succeeded"),
+					},
+				},
+			},
+		})
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/deployments/DEPLOYMENT_NAME/operations") &&
+			request.URL.Query().Get("page") == "2"
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+			Value: []*armresources.DeploymentOperation{
+				{
+					OperationID: to.Ptr("2"),
+					Properties: &armresources.DeploymentOperationProperties{
+						ProvisioningState: to.Ptr("Failed"),
+						StatusCode:        to.Ptr("Conflict"),
+						TargetResource: &armresources.TargetResource{
+							ID:           to.Ptr("/subscriptions/SUBSCRIPTION_ID/resourceGroups/RG/providers/Microsoft.Web/sites/app"),
+							ResourceName: to.Ptr("app"),
+							ResourceType: to.Ptr("Microsoft.Web/sites"),
+						},
+						StatusMessage: &armresources.StatusMessage{
+							Status: to.Ptr("Failed"),
+							Error: &armresources.ErrorResponse{
+								Code:    to.Ptr("WebsiteNameTaken"),
+								Message: to.Ptr("Website with given name app already exists."),
+							},
+						},
+					},
+				},
+			},
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	operations, err := ds.ListDeploymentOperations(
+		*mockContext.Context,
+		DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"},
+		"DEPLOYMENT_NAME",
+	)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+
+	failures := FailedDeploymentOperations(operations)
+	require.Len(t, failures, 1)
+	require.Equal(t, "2", failures[0].OperationId)
+	require.Equal(t, "app", failures[0].ResourceName)
+	require.Equal(t, "Microsoft.Web/sites", failures[0].ResourceType)
+	require.Equal(t, "Conflict", failures[0].StatusCode)
+	require.Equal(t, "WebsiteNameTaken", *failures[0].Error.Code)
+}
+
+func Test_FormatDeploymentErrorReport(t *testing.T) {
+	failures := []DeploymentOperationFailure{
+		{
+			OperationId:  "1",
+			ResourceType: "Microsoft.Web/sites",
+			ResourceName: "app",
+			StatusCode:   "Conflict",
+			Error: &armresources.ErrorResponse{
+				Code:    to.Ptr("WebsiteNameTaken"),
+				Message: to.Ptr("Website with given name app already exists."),
+			},
+		},
+		{
+			OperationId:  "2",
+			ResourceType: "Microsoft.Resources/deployments",
+			ResourceName: "resources",
+			StatusCode:   "BadRequest",
+			Error: &armresources.ErrorResponse{
+				Code:    to.Ptr("DeploymentFailed"),
+				Message: to.Ptr("At least one resource deployment operation failed."),
+				Details: []*armresources.ErrorResponse{
+					{
+						Code:    to.Ptr("BadRequest"),
+						Message: to.Ptr("The request is invalid."),
+						Details: []*armresources.ErrorResponse{
+							{Code: to.Ptr("InvalidSku"), Message: to.Ptr("The SKU 'F0' is not available.")},
+						},
+					},
+				},
+			},
+		},
+		{
+			OperationId: "3",
+		},
+	}
+
+	require.Equal(t, `Deployment 'DEPLOYMENT_NAME' has 3 failed operations:
+  Microsoft.Web/sites 'app' (Conflict)
+    WebsiteNameTaken: Website with given name app already exists.
+  Microsoft.Resources/deployments 'resources' (BadRequest)
+    BadRequest: The request is invalid.
+      InvalidSku: The SKU 'F0' is not available.
+  Operation '3'
+`, FormatDeploymentErrorReport("DEPLOYMENT_NAME", failures))
+
+	require.Empty(t, FormatDeploymentErrorReport("DEPLOYMENT_NAME", nil))
+}
+
+func Test_GetDeploymentErrorReport(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/deployments/DEPLOYMENT_NAME/operations")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+			Value: []*armresources.DeploymentOperation{
+				{
+					OperationID: to.Ptr("1"),
+					Properties: &armresources.DeploymentOperationProperties{
+						ProvisioningState: to.Ptr("Failed"),
+						StatusCode:        to.Ptr("Forbidden"),
+						TargetResource: &armresources.TargetResource{
+							ResourceName: to.Ptr("vault"),
+							ResourceType: to.Ptr("Microsoft.KeyVault/vaults"),
+						},
+						StatusMessage: &armresources.StatusMessage{
+							Error: &armresources.ErrorResponse{
+								Code:    to.Ptr("AuthorizationFailed"),
+								Message: to.Ptr("The client does not have authorization."),
+							},
+						},
+					},
+				},
+			},
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	report, err := ds.GetDeploymentErrorReport(
+		*mockContext.Context,
+		DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"},
+		"DEPLOYMENT_NAME",
+	)
+	require.NoError(t, err)
+	require.Equal(t, `Deployment 'DEPLOYMENT_NAME' has 1 failed operation:
+  Microsoft.KeyVault/vaults 'vault' (Forbidden)
+    AuthorizationFailed: The client does not have authorization.
+`, report)
+}
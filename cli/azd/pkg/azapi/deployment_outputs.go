@@ -0,0 +1,326 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/azure/azure-dev/cli/azd/internal/scaffold"
+)
+
+// RequireOutputs verifies that every required output name is present in the deployment outputs.
+// Output names are compared case-insensitively, matching ARM semantics.
+// The returned error names every missing output, in the order they were required.
+func RequireOutputs(outputs map[string]AzCliDeploymentOutput, required []string) error {
+	existing := make(map[string]struct{}, len(outputs))
+	for key := range outputs {
+		existing[strings.ToLower(key)] = struct{}{}
+	}
+
+	missing := []string{}
+	for _, name := range required {
+		if _, has := existing[strings.ToLower(name)]; !has {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("deployment is missing required outputs: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// OutputByPointer resolves an RFC 6901 JSON pointer (ex. '/endpoints/0/url') within the value of the named output.
+// The output name is compared case-insensitively and an empty pointer returns the whole output value.
+func OutputByPointer(outputs map[string]AzCliDeploymentOutput, outputName string, pointer string) (any, error) {
+	output, err := findOutput(outputs, outputName)
+	if err != nil {
+		return nil, err
+	}
+
+	value := output.Value
+
+	if pointer == "" {
+		return value, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s': must be empty or start with '/'", pointer)
+	}
+
+	path := ""
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		path += "/" + token
+
+		switch current := value.(type) {
+		case map[string]any:
+			child, has := current[token]
+			if !has {
+				return nil, fmt.Errorf("output '%s' has no value at '%s'", outputName, path)
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(current) || (len(token) > 1 && token[0] == '0') {
+				return nil, fmt.Errorf("output '%s' has no array element at '%s'", outputName, path)
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("output '%s' value at '%s' is not an object or array", outputName, path)
+		}
+	}
+
+	return value, nil
+}
+
+// GetStringOutput gets the value of a 'String' or 'SecureString' output.
+// Errors never include the output value, so secure values are not logged.
+func GetStringOutput(outputs map[string]AzCliDeploymentOutput, outputName string) (string, error) {
+	output, err := findTypedOutput(outputs, outputName, "string", "securestring")
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := output.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("deployment output '%s' of type '%s' does not have a string value", outputName, output.Type)
+	}
+
+	return value, nil
+}
+
+// GetIntOutput gets the value of an 'Int' output.
+func GetIntOutput(outputs map[string]AzCliDeploymentOutput, outputName string) (int, error) {
+	output, err := findTypedOutput(outputs, outputName, "int")
+	if err != nil {
+		return 0, err
+	}
+
+	switch value := output.Value.(type) {
+	case int:
+		return value, nil
+	case int64:
+		return int(value), nil
+	// JSON numbers are decoded as float64
+	case float64:
+		if value == math.Trunc(value) {
+			return int(value), nil
+		}
+	}
+
+	return 0, fmt.Errorf("deployment output '%s' of type '%s' does not have an integer value", outputName, output.Type)
+}
+
+// GetBoolOutput gets the value of a 'Bool' output.
+func GetBoolOutput(outputs map[string]AzCliDeploymentOutput, outputName string) (bool, error) {
+	output, err := findTypedOutput(outputs, outputName, "bool")
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := output.Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("deployment output '%s' of type '%s' does not have a boolean value", outputName, output.Type)
+	}
+
+	return value, nil
+}
+
+// OutputsToEnvMap flattens the deployment outputs into environment variables, ex. 'websiteUrl' becomes 'WEBSITE_URL'.
+// String, int and bool values are formatted as plain strings while array and object values are serialized to JSON.
+// Secure output values are included, use SecureOutputEnvKeys to find the keys that should not be persisted in plaintext.
+//
+// Fails when the names of multiple outputs map to the same environment variable, ex. 'apiUrl' and 'API_URL', since
+// either value could be the intended one.
+func OutputsToEnvMap(outputs map[string]AzCliDeploymentOutput) (map[string]string, error) {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make(map[string]string, len(outputs))
+	// The output name each environment variable was set from
+	envKeyOutputs := make(map[string]string, len(outputs))
+
+	for _, name := range names {
+		output := outputs[name]
+		key := OutputEnvKey(name)
+		if existing, has := envKeyOutputs[key]; has {
+			return nil, fmt.Errorf(
+				"deployment outputs '%s' and '%s' both map to the environment variable '%s'", existing, name, key)
+		}
+		envKeyOutputs[key] = name
+
+		var value string
+		switch typedValue := output.Value.(type) {
+		case nil:
+			value = ""
+		// JSON numbers are decoded as float64, which are formatted without exponents
+		case float64:
+			value = strconv.FormatFloat(typedValue, 'f', -1, 64)
+		case map[string]any, []any:
+			bytes, err := json.Marshal(output.Value)
+			if err != nil {
+				// Values decoded from a deployment response are always serializable
+				value = fmt.Sprintf("%v", output.Value)
+			} else {
+				value = string(bytes)
+			}
+		default:
+			value = fmt.Sprintf("%v", output.Value)
+		}
+
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// SecureOutputEnvKeys gets the sorted environment variable keys of the 'SecureString' and 'SecureObject' outputs
+func SecureOutputEnvKeys(outputs map[string]AzCliDeploymentOutput) []string {
+	keys := []string{}
+	for name, output := range outputs {
+		if isSecureOutputType(output.Type) {
+			keys = append(keys, OutputEnvKey(name))
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// OutputEnvKey converts the output name into an upper snake case environment variable key.
+// Camel case words are only split when the name has no separators, because ARM can return names with inconsistent
+// casing like 'azurE_RESOURCE_GROUP'.
+func OutputEnvKey(name string) string {
+	if strings.ContainsAny(name, "-_.") {
+		return scaffold.AlphaSnakeUpper(name)
+	}
+
+	sb := strings.Builder{}
+	runes := []rune(name)
+	for i, r := range runes {
+		// Starts a new word on a lower to upper case change (webUrl) or at the end of an acronym (APIUrl)
+		if i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			sb.WriteRune('_')
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return scaffold.AlphaSnakeUpper(sb.String())
+}
+
+// The kind of difference of an output between two deployments
+type OutputDiffKind string
+
+const (
+	OutputAdded   OutputDiffKind = "Added"
+	OutputRemoved OutputDiffKind = "Removed"
+	OutputChanged OutputDiffKind = "Changed"
+)
+
+// A difference of an output between two deployments
+type OutputDiff struct {
+	Name string
+	Kind OutputDiffKind
+	// The value in the first deployment, nil when the output was added
+	Before any
+	// The value in the second deployment, nil when the output was removed
+	After any
+}
+
+// DiffOutputs compares the outputs of two deployments, ex. a what-if environment and production, and returns the
+// outputs that were added, removed or changed from a to b sorted by name. Output names are compared
+// case-insensitively, matching ARM semantics. The values of 'SecureString' and 'SecureObject' outputs are masked.
+func DiffOutputs(a, b map[string]AzCliDeploymentOutput) []OutputDiff {
+	before := make(map[string]string, len(a))
+	for name := range a {
+		before[strings.ToLower(name)] = name
+	}
+
+	diffs := []OutputDiff{}
+	matched := map[string]struct{}{}
+	for name, after := range b {
+		beforeName, has := before[strings.ToLower(name)]
+		if !has {
+			diffs = append(diffs, OutputDiff{Name: name, Kind: OutputAdded, After: diffOutputValue(after)})
+			continue
+		}
+
+		matched[beforeName] = struct{}{}
+		if !strings.EqualFold(a[beforeName].Type, after.Type) || !reflect.DeepEqual(a[beforeName].Value, after.Value) {
+			diffs = append(diffs, OutputDiff{
+				Name:   name,
+				Kind:   OutputChanged,
+				Before: diffOutputValue(a[beforeName]),
+				After:  diffOutputValue(after),
+			})
+		}
+	}
+
+	for name, output := range a {
+		if _, has := matched[name]; !has {
+			diffs = append(diffs, OutputDiff{Name: name, Kind: OutputRemoved, Before: diffOutputValue(output)})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return strings.ToLower(diffs[i].Name) < strings.ToLower(diffs[j].Name)
+	})
+
+	return diffs
+}
+
+// Gets the value of the output to report in a diff, masking secure outputs
+func diffOutputValue(output AzCliDeploymentOutput) any {
+	if isSecureOutputType(output.Type) {
+		return maskedOutputValue
+	}
+
+	return output.Value
+}
+
+// Finds the output with the specified name, compared case-insensitively
+func findOutput(outputs map[string]AzCliDeploymentOutput, outputName string) (AzCliDeploymentOutput, error) {
+	for key, output := range outputs {
+		if strings.EqualFold(key, outputName) {
+			return output, nil
+		}
+	}
+
+	return AzCliDeploymentOutput{}, fmt.Errorf("deployment output '%s' not found", outputName)
+}
+
+// Finds the output with the specified name and validates its declared type is one of the expected types
+func findTypedOutput(
+	outputs map[string]AzCliDeploymentOutput,
+	outputName string,
+	expectedTypes ...string,
+) (AzCliDeploymentOutput, error) {
+	output, err := findOutput(outputs, outputName)
+	if err != nil {
+		return output, err
+	}
+
+	for _, expectedType := range expectedTypes {
+		if strings.EqualFold(output.Type, expectedType) {
+			return output, nil
+		}
+	}
+
+	return output, fmt.Errorf(
+		"deployment output '%s' is of type '%s', expected '%s'", outputName, output.Type, strings.Join(expectedTypes, "' or '"))
+}
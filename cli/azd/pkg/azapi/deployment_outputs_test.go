@@ -0,0 +1,222 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RequireOutputs(t *testing.T) {
+	outputs := map[string]AzCliDeploymentOutput{
+		"WEBSITE_URL":    {Type: "string", Value: "https://contoso.com"},
+		"AZURE_LOCATION": {Type: "string", Value: "eastus2"},
+	}
+
+	t.Run("AllPresent", func(t *testing.T) {
+		err := RequireOutputs(outputs, []string{"WEBSITE_URL", "azure_location"})
+		require.NoError(t, err)
+	})
+
+	t.Run("SomeMissing", func(t *testing.T) {
+		err := RequireOutputs(outputs, []string{"WEBSITE_URL", "API_URL", "AZURE_KEY_VAULT_NAME"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "API_URL")
+		require.Contains(t, err.Error(), "AZURE_KEY_VAULT_NAME")
+		require.NotContains(t, err.Error(), "WEBSITE_URL")
+	})
+
+	t.Run("EmptyRequired", func(t *testing.T) {
+		require.NoError(t, RequireOutputs(outputs, nil))
+		require.NoError(t, RequireOutputs(nil, []string{}))
+	})
+}
+
+func Test_OutputByPointer(t *testing.T) {
+	outputs := map[string]AzCliDeploymentOutput{
+		"SERVICE_CONFIG": {
+			Type: "object",
+			Value: map[string]any{
+				"name": "api",
+				"endpoints": []any{
+					map[string]any{"url": "https://api.contoso.com", "port": float64(443)},
+					map[string]any{"url": "https://internal.contoso.com"},
+				},
+				"a/b": map[string]any{"c~d": "escaped"},
+			},
+		},
+		"REGIONS": {Type: "array", Value: []any{"eastus", "westus"}},
+	}
+
+	tests := map[string]struct {
+		output   string
+		pointer  string
+		expected any
+	}{
+		"NestedObject":  {output: "SERVICE_CONFIG", pointer: "/name", expected: "api"},
+		"ArrayElement":  {output: "SERVICE_CONFIG", pointer: "/endpoints/1/url", expected: "https://internal.contoso.com"},
+		"NumberValue":   {output: "service_config", pointer: "/endpoints/0/port", expected: float64(443)},
+		"EscapedTokens": {output: "SERVICE_CONFIG", pointer: "/a~1b/c~0d", expected: "escaped"},
+		"ArrayOutput":   {output: "REGIONS", pointer: "/1", expected: "westus"},
+		"WholeValue":    {output: "REGIONS", pointer: "", expected: []any{"eastus", "westus"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			value, err := OutputByPointer(outputs, test.output, test.pointer)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, value)
+		})
+	}
+
+	errorTests := map[string]struct {
+		output  string
+		pointer string
+	}{
+		"MissingOutput":   {output: "MISSING", pointer: "/name"},
+		"MissingKey":      {output: "SERVICE_CONFIG", pointer: "/missing"},
+		"IndexOutOfRange": {output: "SERVICE_CONFIG", pointer: "/endpoints/2/url"},
+		"LeadingZero":     {output: "REGIONS", pointer: "/01"},
+		"NotContainer":    {output: "SERVICE_CONFIG", pointer: "/name/first"},
+		"InvalidPointer":  {output: "SERVICE_CONFIG", pointer: "name"},
+	}
+
+	for name, test := range errorTests {
+		t.Run(name, func(t *testing.T) {
+			_, err := OutputByPointer(outputs, test.output, test.pointer)
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_TypedOutputs(t *testing.T) {
+	outputs := map[string]AzCliDeploymentOutput{
+		"ENDPOINT":       {Type: "String", Value: "https://api.contoso.com"},
+		"ADMIN_PASSWORD": {Type: "SecureString", Value: "P@ssw0rd"},
+		"REPLICAS":       {Type: "Int", Value: float64(3)},
+		"FRACTION":       {Type: "Int", Value: float64(1.5)},
+		"ENABLED":        {Type: "Bool", Value: true},
+	}
+
+	t.Run("String", func(t *testing.T) {
+		value, err := GetStringOutput(outputs, "endpoint")
+		require.NoError(t, err)
+		require.Equal(t, "https://api.contoso.com", value)
+	})
+
+	t.Run("SecureString", func(t *testing.T) {
+		value, err := GetStringOutput(outputs, "ADMIN_PASSWORD")
+		require.NoError(t, err)
+		require.Equal(t, "P@ssw0rd", value)
+
+		// Errors must not leak the secure value
+		_, err = GetIntOutput(outputs, "ADMIN_PASSWORD")
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "P@ssw0rd")
+	})
+
+	t.Run("Int", func(t *testing.T) {
+		value, err := GetIntOutput(outputs, "REPLICAS")
+		require.NoError(t, err)
+		require.Equal(t, 3, value)
+
+		_, err = GetIntOutput(outputs, "FRACTION")
+		require.ErrorContains(t, err, "does not have an integer value")
+	})
+
+	t.Run("Bool", func(t *testing.T) {
+		value, err := GetBoolOutput(outputs, "ENABLED")
+		require.NoError(t, err)
+		require.True(t, value)
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		_, err := GetBoolOutput(outputs, "REPLICAS")
+		require.ErrorContains(t, err, "deployment output 'REPLICAS' is of type 'Int', expected 'bool'")
+
+		_, err = GetStringOutput(outputs, "ENABLED")
+		require.ErrorContains(t, err, "expected 'string' or 'securestring'")
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := GetStringOutput(outputs, "MISSING")
+		require.ErrorContains(t, err, "deployment output 'MISSING' not found")
+	})
+}
+
+func Test_OutputsToEnvMap(t *testing.T) {
+	outputs := map[string]AzCliDeploymentOutput{
+		"websiteUrl":           {Type: "String", Value: "https://contoso.com"},
+		"AZURE_LOCATION":       {Type: "String", Value: "eastus2"},
+		"azurE_RESOURCE_GROUP": {Type: "String", Value: "rg-contoso"},
+		"APIPort":              {Type: "Int", Value: float64(8080)},
+		"enabled":              {Type: "Bool", Value: true},
+		"regions":              {Type: "Array", Value: []any{"eastus", "westus"}},
+		"serviceConfig":        {Type: "Object", Value: map[string]any{"name": "api"}},
+		"connectionString":     {Type: "SecureString", Value: "secret"},
+		"emptyValue":           {Type: "String"},
+	}
+
+	env, err := OutputsToEnvMap(outputs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"WEBSITE_URL":          "https://contoso.com",
+		"AZURE_LOCATION":       "eastus2",
+		"AZURE_RESOURCE_GROUP": "rg-contoso",
+		"API_PORT":             "8080",
+		"ENABLED":              "true",
+		"REGIONS":              `["eastus","westus"]`,
+		"SERVICE_CONFIG":       `{"name":"api"}`,
+		"CONNECTION_STRING":    "secret",
+		"EMPTY_VALUE":          "",
+	}, env)
+
+	require.Equal(t, []string{"CONNECTION_STRING"}, SecureOutputEnvKeys(outputs))
+
+	// The error is the same regardless of the map iteration order
+	for i := 0; i < 10; i++ {
+		_, err := OutputsToEnvMap(map[string]AzCliDeploymentOutput{
+			"websiteUrl":  {Type: "String", Value: "https://contoso.com"},
+			"WEBSITE_URL": {Type: "String", Value: "https://fabrikam.com"},
+			"location":    {Type: "String", Value: "eastus2"},
+		})
+		require.EqualError(t, err,
+			"deployment outputs 'WEBSITE_URL' and 'websiteUrl' both map to the environment variable 'WEBSITE_URL'")
+	}
+}
+
+func Test_DiffOutputs(t *testing.T) {
+	production := map[string]AzCliDeploymentOutput{
+		"WEBSITE_URL":       {Type: "String", Value: "https://contoso.com"},
+		"AZURE_LOCATION":    {Type: "String", Value: "eastus2"},
+		"SQL_CONNECTION":    {Type: "SecureString", Value: "Server=prod"},
+		"REPLICAS":          {Type: "Int", Value: float64(3)},
+		"STORAGE_ENDPOINTS": {Type: "Object", Value: map[string]any{"blob": "https://prod.blob"}},
+	}
+	whatIf := map[string]AzCliDeploymentOutput{
+		"website_url":       {Type: "String", Value: "https://contoso.com"},
+		"AZURE_LOCATION":    {Type: "String", Value: "westus3"},
+		"SQL_CONNECTION":    {Type: "SecureString", Value: "Server=staging"},
+		"REPLICAS":          {Type: "Int", Value: float64(3)},
+		"STORAGE_ENDPOINTS": {Type: "Object", Value: map[string]any{"blob": "https://staging.blob"}},
+		"API_URL":           {Type: "String", Value: "https://api.contoso.com"},
+	}
+	delete(whatIf, "REPLICAS")
+
+	require.Equal(t, []OutputDiff{
+		{Name: "API_URL", Kind: OutputAdded, After: "https://api.contoso.com"},
+		{Name: "AZURE_LOCATION", Kind: OutputChanged, Before: "eastus2", After: "westus3"},
+		{Name: "REPLICAS", Kind: OutputRemoved, Before: float64(3)},
+		{Name: "SQL_CONNECTION", Kind: OutputChanged, Before: maskedOutputValue, After: maskedOutputValue},
+		{
+			Name:   "STORAGE_ENDPOINTS",
+			Kind:   OutputChanged,
+			Before: map[string]any{"blob": "https://prod.blob"},
+			After:  map[string]any{"blob": "https://staging.blob"},
+		},
+	}, DiffOutputs(production, whatIf))
+
+	require.Empty(t, DiffOutputs(production, production))
+}
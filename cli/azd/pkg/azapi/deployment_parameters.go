@@ -0,0 +1,284 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+)
+
+// ParameterSourceKind identifies where a set of deployment parameters came from
+type ParameterSourceKind string
+
+const (
+	// Parameters read from a parameters file, lowest precedence
+	ParameterSourceFile ParameterSourceKind = "file"
+	// Parameters read from the azd environment, overrides parameters from a file
+	ParameterSourceEnvironment ParameterSourceKind = "environment"
+	// Parameters set explicitly by the user (ex. command flags), highest precedence
+	ParameterSourceFlag ParameterSourceKind = "flag"
+)
+
+// The precedence of each parameter source, sources with a higher precedence override lower ones
+var parameterSourcePrecedence = map[ParameterSourceKind]int{
+	ParameterSourceFile:        0,
+	ParameterSourceEnvironment: 1,
+	ParameterSourceFlag:        2,
+}
+
+// ParameterSource is a set of deployment parameters along with where they came from
+type ParameterSource struct {
+	Kind       ParameterSourceKind
+	Parameters azure.ArmParameters
+}
+
+// ResolveParameters merges the parameters of all sources into the final deployment parameters.
+// Regardless of the order of the sources, values are overridden in the following order of precedence:
+// flag > environment > file. Parameter names are compared case-insensitively, matching ARM semantics, and the name
+// from the winning source is used.
+//
+// Along with the parameters, a map of each parameter name to the kind of the source its value came from is returned.
+func ResolveParameters(sources []ParameterSource) (azure.ArmParameters, map[string]string, error) {
+	ordered := make([]ParameterSource, len(sources))
+	copy(ordered, sources)
+
+	seen := map[ParameterSourceKind]bool{}
+	for _, source := range ordered {
+		if _, has := parameterSourcePrecedence[source.Kind]; !has {
+			return nil, nil, fmt.Errorf("unknown parameter source '%s'", source.Kind)
+		}
+
+		if seen[source.Kind] {
+			return nil, nil, fmt.Errorf("parameter source '%s' specified more than once", source.Kind)
+		}
+		seen[source.Kind] = true
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return parameterSourcePrecedence[ordered[i].Kind] < parameterSourcePrecedence[ordered[j].Kind]
+	})
+
+	parameters := azure.ArmParameters{}
+	sourceByName := map[string]string{}
+	// Maps the lower-cased parameter name to the name currently used in the results
+	names := map[string]string{}
+
+	for _, source := range ordered {
+		for name, value := range source.Parameters {
+			if existing, has := names[strings.ToLower(name)]; has {
+				delete(parameters, existing)
+				delete(sourceByName, existing)
+			}
+
+			names[strings.ToLower(name)] = name
+			parameters[name] = value
+			sourceByName[name] = string(source.Kind)
+		}
+	}
+
+	return parameters, sourceByName, nil
+}
+
+const armParametersFileSchema = "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#"
+
+// Optional settings used when generating a parameters file from deployment outputs
+type ParametersFileOptions struct {
+	// Whether the values of 'SecureString' and 'SecureObject' outputs are written to the file.
+	// By default secure outputs are omitted so that secrets are not persisted in plain text.
+	IncludeSecureValues bool
+}
+
+// GenerateParametersFileFromOutputs writes an ARM parameters file to the specified path with the values of the
+// deployment outputs, so that the outputs of one deployment stage can be used as the parameters of the next one.
+// The mapping is from the parameter name to the name of the output providing its value, output names are compared
+// case-insensitively. Mapped outputs that don't exist are an error.
+func GenerateParametersFileFromOutputs(
+	path string,
+	outputs map[string]AzCliDeploymentOutput,
+	mapping map[string]string,
+	options *ParametersFileOptions,
+) error {
+	if options == nil {
+		options = &ParametersFileOptions{}
+	}
+
+	parameters := azure.ArmParameters{}
+	for parameterName, outputName := range mapping {
+		output, err := findOutput(outputs, outputName)
+		if err != nil {
+			return fmt.Errorf("generating parameter '%s': %w", parameterName, err)
+		}
+
+		if isSecureOutputType(output.Type) && !options.IncludeSecureValues {
+			log.Printf("omitting parameter '%s' from parameters file, output '%s' is secure", parameterName, outputName)
+			continue
+		}
+
+		parameters[parameterName] = azure.ArmParameterValue{Value: output.Value}
+	}
+
+	parametersFile, err := json.MarshalIndent(azure.ArmParameterFile{
+		Schema:         armParametersFileSchema,
+		ContentVersion: "1.0.0.0",
+		Parameters:     parameters,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling parameters file: %w", err)
+	}
+
+	if err := os.WriteFile(path, parametersFile, osutil.PermissionFile); err != nil {
+		return fmt.Errorf("writing parameters file: %w", err)
+	}
+
+	return nil
+}
+
+// ParametersFromBicepParamJSON converts the parameters compiled from a '.bicepparam' file, in the
+// '{ "parameters": { "name": { "value": ... } } }' shape, into ArmParameters. The output of 'bicep build-params',
+// which holds the parameters as a JSON string within 'parametersJson', is also accepted.
+//
+// Key Vault references are kept as references, so that secret values are resolved by ARM and never read by azd.
+func ParametersFromBicepParamJSON(bicepParamJson []byte) (azure.ArmParameters, error) {
+	var compiled struct {
+		ParametersJson *string                    `json:"parametersJson"`
+		Parameters     map[string]json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(bicepParamJson, &compiled); err != nil {
+		return nil, fmt.Errorf("parsing bicepparam JSON: %w", err)
+	}
+
+	if compiled.ParametersJson != nil {
+		return ParametersFromBicepParamJSON([]byte(*compiled.ParametersJson))
+	}
+
+	parameters := azure.ArmParameters{}
+	for name, rawParameter := range compiled.Parameters {
+		var parameter struct {
+			Value     any                               `json:"value"`
+			Reference *azure.KeyVaultParameterReference `json:"reference"`
+		}
+		if err := json.Unmarshal(rawParameter, &parameter); err != nil {
+			return nil, fmt.Errorf("parsing parameter '%s': %w", name, err)
+		}
+
+		if parameter.Reference != nil &&
+			(parameter.Reference.KeyVault.Id == "" || parameter.Reference.SecretName == "") {
+			return nil, fmt.Errorf(
+				"parameter '%s' has an invalid Key Vault reference, 'keyVault.id' and 'secretName' are required", name)
+		}
+
+		parameters[name] = azure.ArmParameterValue{Value: parameter.Value, Reference: parameter.Reference}
+	}
+
+	return parameters, nil
+}
+
+// ParameterError is a problem with a deployment parameter found by ValidateParameters
+type ParameterError struct {
+	// The name of the parameter, as defined by the template or, for unknown parameters, as supplied
+	Parameter string
+	Message   string
+}
+
+func (e ParameterError) Error() string {
+	return fmt.Sprintf("parameter '%s': %s", e.Parameter, e.Message)
+}
+
+// ValidateParameters checks the parameters against the 'parameters' block of the template, without calling ARM.
+// Required parameters without a value, parameters not defined by the template and values that don't match the type
+// of their definition are reported. All the problems are returned, sorted by parameter name, and an empty result
+// means the parameters are valid.
+//
+// Parameter names are compared case-insensitively, matching ARM semantics. Values referencing a Key Vault secret are
+// resolved by ARM, so only their presence is checked.
+func ValidateParameters(template azure.RawArmTemplate, params azure.ArmParameters) []ParameterError {
+	var armTemplate azure.ArmTemplate
+	if err := json.Unmarshal(template, &armTemplate); err != nil {
+		return []ParameterError{{Message: fmt.Sprintf("parsing template: %v", err)}}
+	}
+
+	supplied := make(map[string]string, len(params))
+	for name := range params {
+		supplied[strings.ToLower(name)] = name
+	}
+
+	problems := []ParameterError{}
+	for name, definition := range armTemplate.Parameters {
+		suppliedName, has := supplied[strings.ToLower(name)]
+		delete(supplied, strings.ToLower(name))
+
+		var value azure.ArmParameterValue
+		if has {
+			value = params[suppliedName]
+		}
+
+		if value.Reference != nil {
+			continue
+		}
+
+		if value.Value == nil {
+			if definition.DefaultValue == nil {
+				problems = append(problems, ParameterError{Parameter: name, Message: "a value is required"})
+			}
+
+			continue
+		}
+
+		if !parameterValueMatchesType(value.Value, definition.Type) {
+			problems = append(problems, ParameterError{
+				Parameter: name,
+				Message:   fmt.Sprintf("expected a value of type '%s', got %T", definition.Type, value.Value),
+			})
+		}
+	}
+
+	for _, name := range supplied {
+		problems = append(problems, ParameterError{Parameter: name, Message: "not defined by the template"})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return strings.ToLower(problems[i].Parameter) < strings.ToLower(problems[j].Parameter)
+	})
+
+	return problems
+}
+
+// parameterValueMatchesType reports whether the value can be used for a parameter of the ARM type. Values may have
+// been decoded from JSON or set directly, so any Go value of a matching kind is accepted. Unknown types aren't checked.
+func parameterValueMatchesType(value any, armType string) bool {
+	kind := reflect.TypeOf(value).Kind()
+
+	switch strings.ToLower(armType) {
+	case "string", "securestring":
+		return kind == reflect.String
+	case "int":
+		switch kind {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			number := reflect.ValueOf(value).Float()
+			return number == math.Trunc(number)
+		}
+
+		return false
+	case "bool":
+		return kind == reflect.Bool
+	case "object", "secureobject":
+		return kind == reflect.Map || kind == reflect.Struct
+	case "array":
+		return kind == reflect.Slice || kind == reflect.Array
+	default:
+		return true
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResolveParameters(t *testing.T) {
+	// Sources are intentionally not in precedence order
+	sources := []ParameterSource{
+		{
+			Kind: ParameterSourceFlag,
+			Parameters: azure.ArmParameters{
+				"location": {Value: "westus3"},
+			},
+		},
+		{
+			Kind: ParameterSourceFile,
+			Parameters: azure.ArmParameters{
+				"location":        {Value: "eastus"},
+				"environmentName": {Value: "dev"},
+				"principalId":     {Value: ""},
+				"sku":             {Value: "B1"},
+			},
+		},
+		{
+			Kind: ParameterSourceEnvironment,
+			Parameters: azure.ArmParameters{
+				"Location":    {Value: "eastus2"},
+				"principalId": {Value: "00000000-0000-0000-0000-000000000000"},
+			},
+		},
+	}
+
+	parameters, attribution, err := ResolveParameters(sources)
+	require.NoError(t, err)
+
+	require.Equal(t, azure.ArmParameters{
+		"location":        {Value: "westus3"},
+		"environmentName": {Value: "dev"},
+		"principalId":     {Value: "00000000-0000-0000-0000-000000000000"},
+		"sku":             {Value: "B1"},
+	}, parameters)
+
+	require.Equal(t, map[string]string{
+		"location":        "flag",
+		"environmentName": "file",
+		"principalId":     "environment",
+		"sku":             "file",
+	}, attribution)
+}
+
+func Test_ResolveParameters_InvalidSources(t *testing.T) {
+	_, _, err := ResolveParameters([]ParameterSource{{Kind: "unknown"}})
+	require.Error(t, err)
+
+	_, _, err = ResolveParameters([]ParameterSource{{Kind: ParameterSourceFile}, {Kind: ParameterSourceFile}})
+	require.Error(t, err)
+
+	parameters, sources, err := ResolveParameters(nil)
+	require.NoError(t, err)
+	require.Empty(t, parameters)
+	require.Empty(t, sources)
+}
+
+func Test_GenerateParametersFileFromOutputs(t *testing.T) {
+	outputs := map[string]AzCliDeploymentOutput{
+		"AZURE_CONTAINER_REGISTRY_ENDPOINT": {Type: "String", Value: "contoso.azurecr.io"},
+		"REPLICAS":                          {Type: "Int", Value: float64(3)},
+		"ADMIN_PASSWORD":                    {Type: "SecureString", Value: "P@ssw0rd"},
+	}
+	mapping := map[string]string{
+		"registryEndpoint": "azure_container_registry_endpoint",
+		"replicas":         "REPLICAS",
+		"adminPassword":    "ADMIN_PASSWORD",
+	}
+
+	readParametersFile := func(t *testing.T, path string) azure.ArmParameterFile {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var parametersFile azure.ArmParameterFile
+		require.NoError(t, json.Unmarshal(raw, &parametersFile))
+		return parametersFile
+	}
+
+	t.Run("OmitsSecureValues", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.parameters.json")
+		require.NoError(t, GenerateParametersFileFromOutputs(path, outputs, mapping, nil))
+
+		parametersFile := readParametersFile(t, path)
+		require.Equal(t, armParametersFileSchema, parametersFile.Schema)
+		require.Equal(t, "1.0.0.0", parametersFile.ContentVersion)
+		require.Equal(t, azure.ArmParameters{
+			"registryEndpoint": {Value: "contoso.azurecr.io"},
+			"replicas":         {Value: float64(3)},
+		}, parametersFile.Parameters)
+	})
+
+	t.Run("IncludesSecureValues", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.parameters.json")
+		require.NoError(t, GenerateParametersFileFromOutputs(
+			path, outputs, mapping, &ParametersFileOptions{IncludeSecureValues: true}))
+
+		parametersFile := readParametersFile(t, path)
+		require.Equal(t, "P@ssw0rd", parametersFile.Parameters["adminPassword"].Value)
+	})
+
+	t.Run("MissingOutput", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "main.parameters.json")
+		err := GenerateParametersFileFromOutputs(path, outputs, map[string]string{"name": "MISSING"}, nil)
+		require.ErrorContains(t, err, "deployment output 'MISSING' not found")
+		require.NoFileExists(t, path)
+	})
+}
+
+func Test_ParametersFromBicepParamJSON(t *testing.T) {
+	keyVaultId := "/subscriptions/SUBSCRIPTION_ID/resourceGroups/RESOURCE_GROUP/providers/Microsoft.KeyVault/vaults/vault"
+	parametersJson := `{
+		"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#",
+		"contentVersion": "1.0.0.0",
+		"parameters": {
+			"location": {"value": "eastus2"},
+			"replicas": {"value": 3},
+			"tags": {"value": {"env": "dev"}},
+			"adminPassword": {
+				"reference": {
+					"keyVault": {"id": "` + keyVaultId + `"},
+					"secretName": "admin-password"
+				}
+			}
+		}
+	}`
+
+	expected := azure.ArmParameters{
+		"location": {Value: "eastus2"},
+		"replicas": {Value: float64(3)},
+		"tags":     {Value: map[string]any{"env": "dev"}},
+		"adminPassword": {
+			Reference: &azure.KeyVaultParameterReference{
+				KeyVault:   azure.KeyVaultReference{Id: keyVaultId},
+				SecretName: "admin-password",
+			},
+		},
+	}
+
+	t.Run("Parameters", func(t *testing.T) {
+		parameters, err := ParametersFromBicepParamJSON([]byte(parametersJson))
+		require.NoError(t, err)
+		require.Equal(t, expected, parameters)
+
+		// The reference is kept when deploying instead of being flattened into a value
+		deployment, err := json.Marshal(parameters)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"location": {"value": "eastus2"},
+			"replicas": {"value": 3},
+			"tags": {"value": {"env": "dev"}},
+			"adminPassword": {
+				"reference": {"keyVault": {"id": "`+keyVaultId+`"}, "secretName": "admin-password"}
+			}
+		}`, string(deployment))
+	})
+
+	t.Run("BuildParamsOutput", func(t *testing.T) {
+		buildOutput, err := json.Marshal(map[string]string{
+			"templateJson":   "{}",
+			"parametersJson": parametersJson,
+		})
+		require.NoError(t, err)
+
+		parameters, err := ParametersFromBicepParamJSON(buildOutput)
+		require.NoError(t, err)
+		require.Equal(t, expected, parameters)
+	})
+
+	t.Run("InvalidReference", func(t *testing.T) {
+		_, err := ParametersFromBicepParamJSON([]byte(`{
+			"parameters": {"adminPassword": {"reference": {"secretName": "admin-password"}}}
+		}`))
+		require.ErrorContains(t, err, "parameter 'adminPassword' has an invalid Key Vault reference")
+	})
+
+	t.Run("InvalidJson", func(t *testing.T) {
+		_, err := ParametersFromBicepParamJSON([]byte(`not json`))
+		require.Error(t, err)
+	})
+}
+
+func Test_ValidateParameters(t *testing.T) {
+	template := azure.RawArmTemplate(`{
+		"parameters": {
+			"environmentName": { "type": "string" },
+			"location": { "type": "string", "defaultValue": "eastus" },
+			"replicas": { "type": "int" },
+			"enabled": { "type": "bool" },
+			"tags": { "type": "object" },
+			"zones": { "type": "array" },
+			"adminPassword": { "type": "securestring" }
+		}
+	}`)
+
+	t.Run("Valid", func(t *testing.T) {
+		problems := ValidateParameters(template, azure.ArmParameters{
+			"EnvironmentName": {Value: "dev"},
+			"replicas":        {Value: float64(3)},
+			"enabled":         {Value: true},
+			"tags":            {Value: map[string]any{"env": "dev"}},
+			"zones":           {Value: []any{"1", "2"}},
+			"adminPassword": {Reference: &azure.KeyVaultParameterReference{
+				KeyVault:   azure.KeyVaultReference{Id: "/subscriptions/SUBSCRIPTION_ID/vaults/kv"},
+				SecretName: "password",
+			}},
+		})
+		require.Empty(t, problems)
+	})
+
+	t.Run("AllProblems", func(t *testing.T) {
+		problems := ValidateParameters(template, azure.ArmParameters{
+			"replicas": {Value: 1.5},
+			"enabled":  {Value: "true"},
+			"tags":     {Value: []any{}},
+			"zones":    {Value: []any{"1"}},
+			"unknown":  {Value: "value"},
+		})
+		require.Equal(t, []ParameterError{
+			{Parameter: "adminPassword", Message: "a value is required"},
+			{Parameter: "enabled", Message: "expected a value of type 'bool', got string"},
+			{Parameter: "environmentName", Message: "a value is required"},
+			{Parameter: "replicas", Message: "expected a value of type 'int', got float64"},
+			{Parameter: "tags", Message: "expected a value of type 'object', got []interface {}"},
+			{Parameter: "unknown", Message: "not defined by the template"},
+		}, problems)
+	})
+
+	t.Run("InvalidTemplate", func(t *testing.T) {
+		problems := ValidateParameters(azure.RawArmTemplate(`{`), azure.ArmParameters{})
+		require.Len(t, problems, 1)
+		require.Contains(t, problems[0].Message, "parsing template")
+	})
+}
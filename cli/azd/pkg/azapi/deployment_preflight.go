@@ -0,0 +1,55 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+)
+
+// The result of a preflight validation of a deployment
+type PreflightResult struct {
+	// Whether ARM accepted the template and parameters
+	Passed bool
+	// The structured validation error, nil when the validation passed
+	Error *AzureDeploymentError
+}
+
+// PreflightDeploy validates the template and parameters of a subscription deployment, or a resource group deployment
+// when the scope has a resource group, without computing the what-if changes. This is much faster than WhatIf for
+// checking whether a template still validates.
+//
+// A validation failure is reported by the result, not as an error, errors are only returned when the validation
+// couldn't run, ex. because ARM couldn't be reached. The location is ignored for resource group deployments.
+func (ds *deployments) PreflightDeploy(
+	ctx context.Context,
+	scope DeploymentScope,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *DeployOptions,
+) (*PreflightResult, error) {
+	var err error
+	if scope.ResourceGroupName == "" {
+		_, err = ds.ValidateDeployToSubscription(
+			ctx, scope.SubscriptionId, location, deploymentName, armTemplate, parameters, nil, options)
+	} else {
+		_, err = ds.ValidateDeployToResourceGroup(
+			ctx, scope.SubscriptionId, scope.ResourceGroupName, deploymentName, armTemplate, parameters, nil, options)
+	}
+
+	var deploymentErr *AzureDeploymentError
+	if errors.As(err, &deploymentErr) {
+		return &PreflightResult{Error: deploymentErr}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreflightResult{Passed: true}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PreflightDeploy(t *testing.T) {
+	scopes := map[string]DeploymentScope{
+		"Subscription":  {SubscriptionId: "SUBSCRIPTION_ID"},
+		"ResourceGroup": {SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"},
+	}
+
+	mockValidate := func(mockContext *mocks.MockContext, statusCode int, body any) {
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/DEPLOYMENT_NAME/validate")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, statusCode, body)
+		})
+	}
+
+	for name, scope := range scopes {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Passed", func(t *testing.T) {
+				mockContext := mocks.NewMockContext(context.Background())
+				mockValidate(mockContext, http.StatusOK, armresources.DeploymentValidateResult{
+					Properties: &armresources.DeploymentPropertiesExtended{
+						ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+					},
+				})
+
+				ds := newTestDeployments(mockContext)
+				result, err := ds.PreflightDeploy(
+					*mockContext.Context, scope, "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+				require.NoError(t, err)
+				require.True(t, result.Passed)
+				require.Nil(t, result.Error)
+			})
+
+			t.Run("Failed", func(t *testing.T) {
+				mockContext := mocks.NewMockContext(context.Background())
+				mockValidate(mockContext, http.StatusBadRequest, map[string]any{
+					"error": map[string]any{
+						"code":    "InvalidTemplate",
+						"message": "Deployment template validation failed: 'The template parameter 'location' is not found.'",
+					},
+				})
+
+				ds := newTestDeployments(mockContext)
+				result, err := ds.PreflightDeploy(
+					*mockContext.Context, scope, "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+				require.NoError(t, err)
+				require.False(t, result.Passed)
+				require.Equal(t, "InvalidTemplate", result.Error.Details.Inner[0].Code)
+				require.Contains(t, result.Error.Error(), "Deployment template validation failed")
+			})
+		})
+	}
+
+	t.Run("LocationRequired", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		ds := newTestDeployments(mockContext)
+
+		_, err := ds.PreflightDeploy(
+			*mockContext.Context, scopes["Subscription"], "", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+	})
+}
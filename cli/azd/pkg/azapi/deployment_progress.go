@@ -0,0 +1,247 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/benbjohnson/clock"
+)
+
+// The default frequency at which deployment operations are reported to a progress callback
+const defaultProgressFrequency = 10 * time.Second
+
+// DeploymentProgressFn is invoked with the current deployment operations while a deployment is in progress.
+// Returned errors are logged and never abort the deployment.
+type DeploymentProgressFn func(operations []*armresources.DeploymentOperation) error
+
+// DeploymentStateChangedFn is invoked with the new provisioning state of a deployment and the time the change was
+// observed, ex. when a deployment moves from Accepted to Running.
+type DeploymentStateChangedFn func(state armresources.ProvisioningState, timestamp time.Time)
+
+// Gets the policies observing the provisioning state of the deployment, empty when no state callback is configured.
+// The clock timestamps the observed state changes.
+func (o *DeployOptions) stateChangedPolicies(clock clock.Clock) []policy.Policy {
+	if o == nil || o.StateChanged == nil {
+		return nil
+	}
+
+	return []policy.Policy{&deploymentStatePolicy{stateChanged: o.StateChanged, clock: clock}}
+}
+
+// Observes the provisioning state of a deployment from the response starting the deployment and the responses of the
+// operation status polls, and invokes the callback when the state changes. Only the status URL returned when starting
+// the deployment is observed, so other requests of the client, ex. getting an existing deployment, are ignored.
+type deploymentStatePolicy struct {
+	stateChanged DeploymentStateChangedFn
+	clock        clock.Clock
+
+	lock      sync.Mutex
+	statusUrl string
+	state     armresources.ProvisioningState
+}
+
+func (p *deploymentStatePolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		return resp, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	request := req.Raw()
+	switch {
+	case request.Method == http.MethodPut:
+		p.statusUrl = resp.Header.Get("Azure-AsyncOperation")
+		if p.statusUrl == "" {
+			p.statusUrl = resp.Header.Get("Location")
+		}
+
+		var body struct {
+			Properties struct {
+				ProvisioningState armresources.ProvisioningState `json:"provisioningState"`
+			} `json:"properties"`
+		}
+		if p.readBody(resp, &body) {
+			p.observe(body.Properties.ProvisioningState)
+		}
+	case request.Method == http.MethodGet && p.statusUrl != "" && request.URL.String() == p.statusUrl:
+		var body struct {
+			Status armresources.ProvisioningState `json:"status"`
+		}
+		if p.readBody(resp, &body) {
+			p.observe(body.Status)
+		}
+	}
+
+	return resp, nil
+}
+
+// Unmarshals the response body, restoring it so that it can still be read by the poller
+func (p *deploymentStatePolicy) readBody(resp *http.Response, body any) bool {
+	contents, err := runtime.Payload(resp)
+	if err != nil {
+		log.Printf("failed reading deployment state: %v", err)
+		return false
+	}
+
+	return len(contents) > 0 && json.Unmarshal(contents, body) == nil
+}
+
+// Invokes the callback when the state differs from the last observed state
+func (p *deploymentStatePolicy) observe(state armresources.ProvisioningState) {
+	if state == "" || strings.EqualFold(string(state), string(p.state)) {
+		return
+	}
+
+	p.state = state
+	p.stateChanged(state, p.clock.Now())
+}
+
+// Polls the deployment operation until it completes. When a progress callback is configured, the deployment operations
+// are listed and reported at the progress frequency while polling, and once more after the deployment completes.
+// When the context ends before the deployment completes, ErrDeploymentTimeout or ErrDeploymentCanceled is returned.
+// When the deployment timeout elapses, the deployment is canceled in Azure and ErrDeploymentTimeout is returned.
+func pollDeploymentUntilDone[T any](
+	ctx context.Context,
+	ds *deployments,
+	poller *runtime.Poller[T],
+	scope DeploymentScope,
+	deploymentName string,
+	options *DeployOptions,
+) (T, error) {
+	pollCtx := ctx
+	if options != nil && options.Timeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := pollDeploymentWithProgress(pollCtx, ds, poller, scope, deploymentName, options)
+	if err == nil {
+		return result, nil
+	}
+
+	// Only the deployment timeout elapsed, the caller's context is still usable to cancel the deployment
+	if pollCtx.Err() != nil && ctx.Err() == nil {
+		return result, ds.cancelTimedOutDeployment(ctx, scope, deploymentName, options.Timeout)
+	}
+
+	return result, deploymentContextError(err, deploymentName, time.Since(start))
+}
+
+// Cancels the deployment in Azure after the deployment timeout elapsed
+func (ds *deployments) cancelTimedOutDeployment(
+	ctx context.Context,
+	scope DeploymentScope,
+	deploymentName string,
+	timeout time.Duration,
+) error {
+	if err := ds.CancelDeployment(ctx, scope, deploymentName); err != nil {
+		return fmt.Errorf(
+			"%w: deployment '%s' did not complete within %s and canceling it failed, it may still be running in Azure: %w",
+			ErrDeploymentTimeout, deploymentName, timeout, err)
+	}
+
+	return fmt.Errorf(
+		"%w: deployment '%s' did not complete within %s and was canceled", ErrDeploymentTimeout, deploymentName, timeout)
+}
+
+// Classifies a polling error caused by the context ending, so callers can distinguish a local timeout or cancellation
+// from a deployment failure reported by ARM. Other errors are returned unchanged.
+func deploymentContextError(err error, deploymentName string, elapsed time.Duration) error {
+	elapsed = elapsed.Round(time.Second)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf(
+			"%w: stopped waiting for deployment '%s' after %s, it may still be running in Azure: %w",
+			ErrDeploymentTimeout, deploymentName, elapsed, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf(
+			"%w: stopped waiting for deployment '%s' after %s, it may still be running in Azure: %w",
+			ErrDeploymentCanceled, deploymentName, elapsed, err)
+	default:
+		return err
+	}
+}
+
+func pollDeploymentWithProgress[T any](
+	ctx context.Context,
+	ds *deployments,
+	poller *runtime.Poller[T],
+	scope DeploymentScope,
+	deploymentName string,
+	options *DeployOptions,
+) (T, error) {
+	if options == nil || options.Progress == nil {
+		return poller.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	}
+
+	frequency := options.ProgressFrequency
+	if frequency <= 0 {
+		frequency = defaultProgressFrequency
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(frequency)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				ds.reportProgress(ctx, scope, deploymentName, options.Progress)
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ds.reportProgress(ctx, scope, deploymentName, options.Progress)
+			}
+		}
+	}()
+
+	result, err := poller.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	close(done)
+	wg.Wait()
+
+	return result, err
+}
+
+// Lists the current deployment operations and invokes the progress callback, failures are only logged
+func (ds *deployments) reportProgress(
+	ctx context.Context,
+	scope DeploymentScope,
+	deploymentName string,
+	progress DeploymentProgressFn,
+) {
+	operations, err := ds.ListDeploymentOperations(ctx, scope, deploymentName)
+	if err != nil {
+		// Operations are not available until ARM has accepted the deployment
+		log.Printf("failed listing deployment operations for progress: %v", err)
+		return
+	}
+
+	if err := progress(operations); err != nil {
+		log.Printf("deployment progress callback failed: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// The maximum number of deployments deleted concurrently when pruning deployments
+const pruneDeploymentsConcurrency = 10
+
+// PruneSubscriptionDeployments deletes the old deployments of the subscription so that its deployment history stays
+// under the ARM limit of 800 deployments. Deployments are ordered from the newest to the oldest, and the
+// deployments beyond the keep count or older than the cutoff are deleted. A keep count or a duration of zero disables
+// the respective rule. Deployments that are still running are never deleted.
+//
+// Returns the names of the deleted deployments. Deletion continues when a deployment fails to delete, all the
+// failures are returned together. The age of deployments is measured with the clock of the deployments service.
+func (ds *deployments) PruneSubscriptionDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	keepCount int,
+	olderThan time.Duration,
+) ([]string, error) {
+	deployments, err := ds.ListSubscriptionDeployments(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+
+	prune := deploymentsToPrune(deployments, keepCount, olderThan, ds.clock.Now())
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	deleted := []string{}
+	deleteErrors := []error{}
+	semaphore := make(chan struct{}, pruneDeploymentsConcurrency)
+
+	for _, deploymentName := range prune {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		// No more deletes are started once canceled, the deletes already started are waited for
+		if ctx.Err() != nil {
+			mu.Lock()
+			deleteErrors = append(deleteErrors, ctx.Err())
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func(deploymentName string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			err := ds.DeleteSubscriptionDeployment(ctx, subscriptionId, deploymentName)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				deleteErrors = append(deleteErrors, fmt.Errorf("deployment '%s': %w", deploymentName, err))
+				return
+			}
+
+			deleted = append(deleted, deploymentName)
+		}(deploymentName)
+	}
+
+	wg.Wait()
+	sort.Strings(deleted)
+
+	if len(deleteErrors) > 0 {
+		return deleted, fmt.Errorf("deleting deployments: %w", errors.Join(deleteErrors...))
+	}
+
+	return deleted, nil
+}
+
+// Gets the names of the deployments beyond the keep count or older than the cutoff, skipping running deployments
+func deploymentsToPrune(
+	deployments []*armresources.DeploymentExtended,
+	keepCount int,
+	olderThan time.Duration,
+	now time.Time,
+) []string {
+	sorted := make([]*armresources.DeploymentExtended, 0, len(deployments))
+	for _, deployment := range deployments {
+		if deployment.Name != nil {
+			sorted = append(sorted, deployment)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return deploymentTimestamp(sorted[i]).After(deploymentTimestamp(sorted[j]))
+	})
+
+	cutoff := now.Add(-olderThan)
+	prune := []string{}
+	for i, deployment := range sorted {
+		beyondKeepCount := keepCount > 0 && i >= keepCount
+		olderThanCutoff := olderThan > 0 && deploymentTimestamp(deployment).Before(cutoff)
+		if !beyondKeepCount && !olderThanCutoff {
+			continue
+		}
+
+		if deployment.Properties != nil && deployment.Properties.ProvisioningState != nil {
+			switch *deployment.Properties.ProvisioningState {
+			case armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateAccepted,
+				armresources.ProvisioningStateCreating,
+				armresources.ProvisioningStateUpdating,
+				armresources.ProvisioningStateDeleting:
+				continue
+			}
+		}
+
+		prune = append(prune, *deployment.Name)
+	}
+
+	return prune
+}
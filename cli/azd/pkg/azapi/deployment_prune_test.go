@@ -0,0 +1,170 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeploymentsToPrune(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	deployment := func(name string, age time.Duration, state armresources.ProvisioningState) *armresources.DeploymentExtended {
+		return &armresources.DeploymentExtended{
+			Name: to.Ptr(name),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(state),
+				Timestamp:         to.Ptr(now.Add(-age)),
+			},
+		}
+	}
+
+	day := 24 * time.Hour
+	deployments := []*armresources.DeploymentExtended{
+		deployment("dev-3", 3*day, armresources.ProvisioningStateFailed),
+		deployment("dev-1", day, armresources.ProvisioningStateSucceeded),
+		deployment("dev-5", 5*day, armresources.ProvisioningStateRunning),
+		deployment("dev-2", 2*day, armresources.ProvisioningStateSucceeded),
+		deployment("dev-4", 4*day, armresources.ProvisioningStateSucceeded),
+	}
+
+	tests := map[string]struct {
+		keepCount int
+		olderThan time.Duration
+		expected  []string
+	}{
+		"KeepCount":          {keepCount: 2, expected: []string{"dev-3", "dev-4"}},
+		"OlderThan":          {olderThan: 2*day + time.Hour, expected: []string{"dev-3", "dev-4"}},
+		"KeepCountOrOlder":   {keepCount: 3, olderThan: 1*day + time.Hour, expected: []string{"dev-2", "dev-3", "dev-4"}},
+		"KeepAll":            {keepCount: 10, expected: []string{}},
+		"NoRules":            {expected: []string{}},
+		"RunningNeverPruned": {keepCount: 1, expected: []string{"dev-2", "dev-3", "dev-4"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.expected, deploymentsToPrune(deployments, test.keepCount, test.olderThan, now))
+		})
+	}
+}
+
+func Test_PruneSubscriptionDeployments(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.AdvanceTime(365 * 24 * time.Hour)
+	now := mockContext.Clock.Now()
+	mockListSubscriptionDeployments(mockContext, []*armresources.DeploymentExtended{
+		{
+			Name: to.Ptr("dev-1"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Timestamp:         to.Ptr(now.Add(-time.Hour)),
+			},
+		},
+		{
+			Name: to.Ptr("dev-2"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Timestamp:         to.Ptr(now.Add(-2 * time.Hour)),
+			},
+		},
+		{
+			Name: to.Ptr("dev-3"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateFailed),
+				Timestamp:         to.Ptr(now.Add(-3 * time.Hour)),
+			},
+		},
+	})
+
+	var mu sync.Mutex
+	deleteRequests := []string{}
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodDelete &&
+			strings.Contains(request.URL.Path, "/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		deploymentName := path.Base(request.URL.Path)
+
+		mu.Lock()
+		deleteRequests = append(deleteRequests, deploymentName)
+		mu.Unlock()
+
+		if deploymentName == "dev-3" {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusConflict, map[string]any{
+				"error": map[string]any{"code": "Conflict", "message": "The deployment is locked."},
+			})
+		}
+
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+	})
+
+	ds := newTestDeployments(mockContext)
+	ds.clock = mockContext.Clock
+	deleted, err := ds.PruneSubscriptionDeployments(*mockContext.Context, "SUBSCRIPTION_ID", 1, 0)
+	require.ErrorContains(t, err, "deployment 'dev-3'")
+	require.ErrorContains(t, err, "The deployment is locked.")
+	require.Equal(t, []string{"dev-2"}, deleted)
+	require.ElementsMatch(t, []string{"dev-2", "dev-3"}, deleteRequests)
+
+	// The cutoff is relative to the clock of the deployments service
+	deleteRequests = []string{}
+	deleted, err = ds.PruneSubscriptionDeployments(
+		*mockContext.Context, "SUBSCRIPTION_ID", 0, 90*time.Minute)
+	require.ErrorContains(t, err, "deployment 'dev-3'")
+	require.Equal(t, []string{"dev-2"}, deleted)
+	require.ElementsMatch(t, []string{"dev-2", "dev-3"}, deleteRequests)
+}
+
+func Test_PruneSubscriptionDeployments_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockContext := mocks.NewMockContext(ctx)
+	deployments := []*armresources.DeploymentExtended{}
+	for i := 0; i < 3*pruneDeploymentsConcurrency; i++ {
+		deployments = append(deployments, &armresources.DeploymentExtended{
+			Name: to.Ptr(fmt.Sprintf("dev-%d", i)),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Timestamp:         to.Ptr(time.Now().Add(-time.Duration(i) * time.Hour)),
+			},
+		})
+	}
+	mockListSubscriptionDeployments(mockContext, deployments)
+
+	// The first deletes are canceled while in progress, so no slot is released for the remaining deletes
+	var deletes atomic.Int32
+	release := make(chan struct{})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodDelete
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		if deletes.Add(1) == pruneDeploymentsConcurrency {
+			cancel()
+		}
+
+		<-release
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+	})
+
+	ds := newTestDeployments(mockContext)
+	go func() {
+		<-ctx.Done()
+		close(release)
+	}()
+
+	_, err := ds.PruneSubscriptionDeployments(*mockContext.Context, "SUBSCRIPTION_ID", 1, 0)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int32(pruneDeploymentsConcurrency), deletes.Load())
+}
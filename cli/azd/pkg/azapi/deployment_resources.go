@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// ResourceGroupsFromDeployment returns the names of the resource groups created by a subscription-scoped deployment,
+// in the order they appear within the deployment output resources.
+func ResourceGroupsFromDeployment(d *armresources.DeploymentExtended) []string {
+	resourceGroups := []string{}
+	if d == nil || d.Properties == nil {
+		return resourceGroups
+	}
+
+	seen := map[string]struct{}{}
+	for _, resource := range d.Properties.OutputResources {
+		if resource == nil || resource.ID == nil {
+			continue
+		}
+
+		resourceId, err := arm.ParseResourceID(*resource.ID)
+		if err != nil {
+			continue
+		}
+
+		if !strings.EqualFold(resourceId.ResourceType.String(), arm.ResourceGroupResourceType.String()) {
+			continue
+		}
+
+		key := strings.ToLower(resourceId.Name)
+		if _, has := seen[key]; has {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		resourceGroups = append(resourceGroups, resourceId.Name)
+	}
+
+	return resourceGroups
+}
+
+// ParseOutputResources parses the IDs of the resources created by a deployment, so callers can find a resource by its
+// subscription, resource group, type or name without splitting IDs by hand. Child resources, ex. a database of a SQL
+// server, have a nested resource type like 'Microsoft.Sql/servers/databases' and reference their parent resource.
+// IDs that can't be parsed are skipped.
+func ParseOutputResources(props AzCliDeploymentProperties) []*arm.ResourceID {
+	resources := make([]*arm.ResourceID, 0, len(props.OutputResources))
+	for _, resource := range props.OutputResources {
+		resourceId, err := arm.ParseResourceID(resource.Id)
+		if err != nil {
+			continue
+		}
+
+		resources = append(resources, resourceId)
+	}
+
+	return resources
+}
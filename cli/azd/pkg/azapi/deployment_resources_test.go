@@ -0,0 +1,57 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResourceGroupsFromDeployment(t *testing.T) {
+	t.Run("MultipleResourceGroups", func(t *testing.T) {
+		deployment := &armresources.DeploymentExtended{
+			Properties: &armresources.DeploymentPropertiesExtended{
+				OutputResources: []*armresources.ResourceReference{
+					{ID: to.Ptr("/subscriptions/SUBSCRIPTION_ID/resourceGroups/rg-app")},
+					{ID: to.Ptr("/subscriptions/SUBSCRIPTION_ID/resourceGroups/rg-app/providers/Microsoft.Web/sites/web")},
+					{ID: to.Ptr("/subscriptions/SUBSCRIPTION_ID/resourceGroups/rg-data")},
+					{ID: to.Ptr("/subscriptions/SUBSCRIPTION_ID/resourceGroups/RG-APP")},
+					{ID: to.Ptr("/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Authorization/roleAssignments/ra")},
+					{ID: nil},
+				},
+			},
+		}
+
+		require.Equal(t, []string{"rg-app", "rg-data"}, ResourceGroupsFromDeployment(deployment))
+	})
+
+	t.Run("NoProperties", func(t *testing.T) {
+		require.Empty(t, ResourceGroupsFromDeployment(nil))
+		require.Empty(t, ResourceGroupsFromDeployment(&armresources.DeploymentExtended{}))
+	})
+}
+
+func Test_ParseOutputResources(t *testing.T) {
+	resources := ParseOutputResources(AzCliDeploymentProperties{
+		OutputResources: []AzCliDeploymentResourceReference{
+			{Id: "/subscriptions/SUBSCRIPTION_ID/resourceGroups/rg-app/providers/Microsoft.Web/sites/web"},
+			{Id: "/subscriptions/SUBSCRIPTION_ID/resourceGroups/rg-app/providers/Microsoft.Sql/servers/sql/databases/todo"},
+			{Id: "not-a-resource-id"},
+		},
+	})
+	require.Len(t, resources, 2)
+
+	require.Equal(t, "SUBSCRIPTION_ID", resources[0].SubscriptionID)
+	require.Equal(t, "rg-app", resources[0].ResourceGroupName)
+	require.Equal(t, "Microsoft.Web/sites", resources[0].ResourceType.String())
+	require.Equal(t, "web", resources[0].Name)
+
+	require.Equal(t, "Microsoft.Sql/servers/databases", resources[1].ResourceType.String())
+	require.Equal(t, "todo", resources[1].Name)
+	require.Equal(t, "sql", resources[1].Parent.Name)
+	require.Equal(t, "Microsoft.Sql/servers", resources[1].Parent.ResourceType.String())
+}
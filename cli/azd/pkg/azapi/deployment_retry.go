@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// Retry settings used when starting deployments and deployment previews, when ARM is throttling requests or fails
+// with a transient server error. The settings configure the retries of the client pipeline, replacing its defaults.
+type DeploymentRetryOptions struct {
+	// The maximum number of retries after the first attempt, zero disables retries.
+	MaxRetries int
+	// The delay before the first retry, increased exponentially for every following retry.
+	// A Retry-After header returned by ARM takes precedence over this delay.
+	BaseDelay time.Duration
+}
+
+var defaultDeploymentRetryOptions = DeploymentRetryOptions{
+	MaxRetries: 3,
+	BaseDelay:  5 * time.Second,
+}
+
+// Status codes returned by ARM for throttled or transient failures.
+// Any other status, for example a 400 template validation error, fails the same way when retried.
+var retryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Gets the retry options of the client pipeline for the deployment retry options, keeping the other settings of the
+// base options. The pipeline honors a Retry-After header returned by ARM instead of the backoff delay.
+func (o DeploymentRetryOptions) pipelineRetryOptions(base policy.RetryOptions) policy.RetryOptions {
+	base.StatusCodes = retryableStatusCodes
+
+	// The pipeline replaces zero values with its defaults, disabled retries or delays are negative instead
+	base.MaxRetries = int32(o.MaxRetries)
+	if o.MaxRetries <= 0 {
+		base.MaxRetries = -1
+	}
+
+	base.RetryDelay = o.BaseDelay
+	if o.BaseDelay <= 0 {
+		base.RetryDelay = -1
+	}
+
+	return base
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeployToSubscription_Retry(t *testing.T) {
+	tests := map[string]struct {
+		statusCodes      []int
+		maxRetries       int
+		expectedAttempts int
+		expectError      bool
+	}{
+		"RetriesThrottling": {
+			statusCodes:      []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+			maxRetries:       3,
+			expectedAttempts: 3,
+		},
+		"ExhaustsRetries": {
+			statusCodes: []int{
+				http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests,
+			},
+			maxRetries:       2,
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+		"NoRetryOnValidationError": {
+			statusCodes:      []int{http.StatusBadRequest},
+			maxRetries:       3,
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+		"RetriesDisabled": {
+			statusCodes:      []int{http.StatusServiceUnavailable},
+			maxRetries:       0,
+			expectedAttempts: 1,
+			expectError:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			attempts := 0
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodPut &&
+					strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts <= len(test.statusCodes) {
+					response, err := mocks.CreateEmptyHttpResponse(request, test.statusCodes[attempts-1])
+					response.Header.Set("Retry-After", "0")
+					return response, err
+				}
+
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+					Name: to.Ptr("DEPLOYMENT_NAME"),
+					Properties: &armresources.DeploymentPropertiesExtended{
+						ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+					},
+				})
+			})
+
+			// The deployment retries replace the retries of the SDK pipeline, so requests are never retried twice
+			armOptions := mockContext.ArmClientOptions
+			ds := NewDeploymentsWithOptions(mockContext.SubscriptionCredentialProvider, armOptions, &DeploymentsOptions{
+				PollFrequency: time.Millisecond,
+				Retry: &DeploymentRetryOptions{
+					MaxRetries: test.maxRetries,
+					BaseDelay:  time.Millisecond,
+				},
+			})
+
+			_, err := ds.DeployToSubscription(
+				*mockContext.Context, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+			if test.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectedAttempts, attempts)
+		})
+	}
+}
+
+func Test_PipelineRetryOptions(t *testing.T) {
+	base := policy.RetryOptions{MaxRetryDelay: time.Minute, TryTimeout: time.Hour}
+
+	retryOptions := DeploymentRetryOptions{MaxRetries: 2, BaseDelay: time.Second}.pipelineRetryOptions(base)
+	require.Equal(t, policy.RetryOptions{
+		MaxRetries:    2,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: time.Minute,
+		TryTimeout:    time.Hour,
+		StatusCodes:   retryableStatusCodes,
+	}, retryOptions)
+
+	disabled := DeploymentRetryOptions{}.pipelineRetryOptions(base)
+	require.Equal(t, int32(-1), disabled.MaxRetries)
+	require.Equal(t, time.Duration(-1), disabled.RetryDelay)
+}
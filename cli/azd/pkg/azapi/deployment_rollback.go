@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// Returned when redeploying the last successful deployment and no deployment of the scope has succeeded
+var ErrNoSuccessfulDeployment = errors.New("no successful deployment found")
+
+// RedeployLastSuccessful redeploys the template and parameters of the most recent succeeded deployment of the
+// subscription or resource group, ex. to roll back a failed provision. Deployments in any other state, including the
+// currently failed deployment, are skipped. The optional predicate restricts the candidate deployments, ex. to the
+// deployments of a single environment, since a subscription holds the deployments of every environment.
+//
+// The deployment is redeployed with the same location and tags under a new name, ex. 'dev-1683303710-rollback-1683307310',
+// so the record of the succeeded deployment is kept in the deployment history instead of being replaced. ARM never
+// returns the value of secure parameters, so deployments with secure parameters can't be redeployed and return an error.
+func (ds *deployments) RedeployLastSuccessful(
+	ctx context.Context,
+	scope DeploymentScope,
+	predicate func(deployment *armresources.DeploymentExtended) bool,
+	options *DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	var deployments []*armresources.DeploymentExtended
+	var err error
+	if scope.ResourceGroupName == "" {
+		deployments, err = ds.ListSubscriptionDeployments(ctx, scope.SubscriptionId)
+	} else {
+		deployments, err = ds.ListResourceGroupDeployments(ctx, scope.SubscriptionId, scope.ResourceGroupName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+
+	lastSuccessful := lastSuccessfulDeployment(deployments, predicate)
+	if lastSuccessful == nil {
+		return nil, ErrNoSuccessfulDeployment
+	}
+
+	deploymentName := *lastSuccessful.Name
+
+	parameters, err := deploymentParameters(lastSuccessful.Properties.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("reading parameters of deployment '%s': %w", deploymentName, err)
+	}
+
+	secureParameters := []string{}
+	for name, parameter := range parameters {
+		if parameter.Value == nil {
+			secureParameters = append(secureParameters, name)
+		}
+	}
+	if len(secureParameters) > 0 {
+		sort.Strings(secureParameters)
+		return nil, fmt.Errorf(
+			"deployment '%s' can't be redeployed because the values of its secure parameters can't be recovered: %s",
+			deploymentName,
+			strings.Join(secureParameters, ", "),
+		)
+	}
+
+	template, err := ds.ExportDeploymentTemplate(ctx, scope, deploymentName)
+	if err != nil {
+		return nil, fmt.Errorf("exporting template of deployment '%s': %w", deploymentName, err)
+	}
+
+	// The idempotency key would match the deployment being redeployed, which would be returned without redeploying
+	tags := map[string]*string{}
+	for key, value := range lastSuccessful.Tags {
+		if key != IdempotencyKeyTagName {
+			tags[key] = value
+		}
+	}
+
+	rollbackName := GenerateDeploymentName(deploymentName+"-rollback", ds.clock.Now())
+
+	if scope.ResourceGroupName == "" {
+		location := ""
+		if lastSuccessful.Location != nil {
+			location = *lastSuccessful.Location
+		}
+
+		return ds.DeployToSubscription(
+			ctx, scope.SubscriptionId, location, rollbackName, template, parameters, tags, options)
+	}
+
+	return ds.DeployToResourceGroup(
+		ctx, scope.SubscriptionId, scope.ResourceGroupName, rollbackName, template, parameters, tags, options)
+}
+
+// Finds the succeeded deployment with the latest timestamp that matches the optional predicate
+func lastSuccessfulDeployment(
+	deployments []*armresources.DeploymentExtended,
+	predicate func(deployment *armresources.DeploymentExtended) bool,
+) *armresources.DeploymentExtended {
+	var lastSuccessful *armresources.DeploymentExtended
+	for _, deployment := range deployments {
+		if deployment.Name == nil || deployment.Properties == nil ||
+			deployment.Properties.ProvisioningState == nil ||
+			*deployment.Properties.ProvisioningState != armresources.ProvisioningStateSucceeded {
+			continue
+		}
+
+		if predicate != nil && !predicate(deployment) {
+			continue
+		}
+
+		if lastSuccessful == nil || deploymentTimestamp(deployment).After(deploymentTimestamp(lastSuccessful)) {
+			lastSuccessful = deployment
+		}
+	}
+
+	return lastSuccessful
+}
+
+// Gets the timestamp of the deployment, or the zero time when it isn't set
+func deploymentTimestamp(deployment *armresources.DeploymentExtended) time.Time {
+	if deployment.Properties == nil || deployment.Properties.Timestamp == nil {
+		return time.Time{}
+	}
+
+	return *deployment.Properties.Timestamp
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RedeployLastSuccessful(t *testing.T) {
+	deploymentsPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP/providers/Microsoft.Resources/deployments/"
+	scope := DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rollbackDeployment := func(
+		name string,
+		state armresources.ProvisioningState,
+		timestamp time.Time,
+	) *armresources.DeploymentExtended {
+		return &armresources.DeploymentExtended{
+			Name: to.Ptr(name),
+			Tags: map[string]*string{
+				"azd-env-name":        to.Ptr("dev"),
+				IdempotencyKeyTagName: to.Ptr("key-" + name),
+			},
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(state),
+				Timestamp:         to.Ptr(timestamp),
+				Parameters:        map[string]any{"location": map[string]any{"type": "String", "value": "eastus2"}},
+			},
+		}
+	}
+
+	mockListDeployments := func(mockContext *mocks.MockContext, deployments ...*armresources.DeploymentExtended) {
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentsPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{
+				Value: deployments,
+			})
+		})
+	}
+
+	t.Run("RedeploysLastSucceeded", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockListDeployments(
+			mockContext,
+			rollbackDeployment("dev-1", armresources.ProvisioningStateSucceeded, start),
+			rollbackDeployment("dev-3", armresources.ProvisioningStateFailed, start.Add(2*time.Hour)),
+			rollbackDeployment("dev-2", armresources.ProvisioningStateSucceeded, start.Add(time.Hour)),
+		)
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentsPath+"dev-2/exportTemplate")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExportResult{
+				Template: map[string]any{"contentVersion": "1.0.0.0", "resources": []any{}},
+			})
+		})
+
+		// The succeeded deployment is redeployed under a new name, so its record isn't replaced
+		var deployedPaths []string
+		var deployed armresources.Deployment
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPut && strings.Contains(request.URL.Path, deploymentsPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			deployedPaths = append(deployedPaths, request.URL.Path)
+			body, err := io.ReadAll(request.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &deployed))
+
+			name := request.URL.Path[strings.LastIndex(request.URL.Path, "/")+1:]
+			return mocks.CreateHttpResponseWithBody(
+				request, http.StatusOK, rollbackDeployment(name, armresources.ProvisioningStateSucceeded, time.Now()))
+		})
+
+		mockContext.AdvanceTime(time.Hour)
+		ds := newTestDeployments(mockContext)
+		ds.clock = mockContext.Clock
+		result, err := ds.RedeployLastSuccessful(*mockContext.Context, scope, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "dev-2-rollback-3600", *result.Name)
+		require.Len(t, deployedPaths, 1)
+		require.True(t, strings.HasSuffix(deployedPaths[0], deploymentsPath+"dev-2-rollback-3600"))
+
+		require.Equal(t, map[string]any{"contentVersion": "1.0.0.0", "resources": []any{}}, deployed.Properties.Template)
+		require.Equal(t, map[string]any{"location": map[string]any{"value": "eastus2"}}, deployed.Properties.Parameters)
+		// The idempotency key isn't kept, otherwise the succeeded deployment would be returned without redeploying
+		require.Equal(t, map[string]*string{"azd-env-name": to.Ptr("dev")}, deployed.Tags)
+	})
+
+	t.Run("Predicate", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockListDeployments(
+			mockContext,
+			rollbackDeployment("dev-1", armresources.ProvisioningStateSucceeded, start),
+		)
+
+		ds := newTestDeployments(mockContext)
+		_, err := ds.RedeployLastSuccessful(
+			*mockContext.Context,
+			scope,
+			func(deployment *armresources.DeploymentExtended) bool {
+				return deployment.Tags["azd-env-name"] != nil && *deployment.Tags["azd-env-name"] == "prod"
+			},
+			nil,
+		)
+		require.ErrorIs(t, err, ErrNoSuccessfulDeployment)
+	})
+
+	t.Run("NoSuccessfulDeployment", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockListDeployments(
+			mockContext,
+			rollbackDeployment("dev-1", armresources.ProvisioningStateFailed, start),
+			rollbackDeployment("dev-2", armresources.ProvisioningStateCanceled, start.Add(time.Hour)),
+		)
+
+		ds := newTestDeployments(mockContext)
+		_, err := ds.RedeployLastSuccessful(*mockContext.Context, scope, nil, nil)
+		require.ErrorIs(t, err, ErrNoSuccessfulDeployment)
+	})
+
+	t.Run("SecureParameters", func(t *testing.T) {
+		deployment := rollbackDeployment("dev-1", armresources.ProvisioningStateSucceeded, start)
+		deployment.Properties.Parameters = map[string]any{
+			"location":      map[string]any{"type": "String", "value": "eastus2"},
+			"adminPassword": map[string]any{"type": "SecureString"},
+		}
+
+		mockContext := mocks.NewMockContext(context.Background())
+		mockListDeployments(mockContext, deployment)
+
+		ds := newTestDeployments(mockContext)
+		_, err := ds.RedeployLastSuccessful(*mockContext.Context, scope, nil, nil)
+		require.ErrorContains(t, err, "secure parameters can't be recovered: adminPassword")
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+var ErrIllegalStateTransition = errors.New("illegal deployment state transition")
+
+// DeploymentStateChange is emitted each time the provisioning state of a watched deployment changes
+type DeploymentStateChange struct {
+	// The state the deployment transitioned from, empty for the first observed state
+	Previous armresources.ProvisioningState
+	// The new state of the deployment
+	State armresources.ProvisioningState
+	// The time at which the new state was observed
+	Timestamp time.Time
+}
+
+// WatchDeploymentStates polls the deployment returned by getDeployment at the specified frequency and invokes onChange
+// each time its provisioning state changes, until the deployment reaches a terminal state or the context is canceled.
+// The last polled deployment is returned.
+//
+// Provisioning states only move forward (accepted -> running -> terminal). When the polled responses contain an
+// impossible transition, for example Succeeded -> Running, an error wrapping ErrIllegalStateTransition is returned
+// since this can only happen because of a bug or a stale response.
+func (ds *deployments) WatchDeploymentStates(
+	ctx context.Context,
+	getDeployment func(ctx context.Context) (*armresources.DeploymentExtended, error),
+	frequency time.Duration,
+	onChange func(change DeploymentStateChange),
+) (*armresources.DeploymentExtended, error) {
+	var current armresources.ProvisioningState
+
+	for {
+		deployment, err := getDeployment(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting deployment state: %w", err)
+		}
+
+		var state armresources.ProvisioningState
+		if deployment.Properties != nil && deployment.Properties.ProvisioningState != nil {
+			state = *deployment.Properties.ProvisioningState
+		}
+
+		if state != current {
+			if err := validateStateTransition(current, state); err != nil {
+				return deployment, err
+			}
+
+			if onChange != nil {
+				onChange(DeploymentStateChange{
+					Previous:  current,
+					State:     state,
+					Timestamp: ds.clock.Now(),
+				})
+			}
+
+			current = state
+		}
+
+		if isTerminalProvisioningState(state) {
+			return deployment, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return deployment, ctx.Err()
+		case <-ds.clock.After(frequency):
+		}
+	}
+}
+
+// Gets the relative order of a provisioning state within the lifecycle of a deployment
+func provisioningStateRank(state armresources.ProvisioningState) int {
+	switch state {
+	case "":
+		return -1
+	case armresources.ProvisioningStateNotSpecified,
+		armresources.ProvisioningStateAccepted,
+		armresources.ProvisioningStateCreated:
+		return 0
+	case armresources.ProvisioningStateSucceeded,
+		armresources.ProvisioningStateFailed,
+		armresources.ProvisioningStateCanceled,
+		armresources.ProvisioningStateDeleted:
+		return 2
+	default:
+		// Running, Creating, Updating, Deleting, Ready, ...
+		return 1
+	}
+}
+
+func isTerminalProvisioningState(state armresources.ProvisioningState) bool {
+	return provisioningStateRank(state) == 2
+}
+
+// Validates that a deployment can move from one provisioning state to another.
+// States can't move backwards in the lifecycle and a terminal state is never left.
+func validateStateTransition(from armresources.ProvisioningState, to armresources.ProvisioningState) error {
+	if from == to {
+		return nil
+	}
+
+	if to == "" || isTerminalProvisioningState(from) || provisioningStateRank(to) < provisioningStateRank(from) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalStateTransition, from, to)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WatchDeploymentStates(t *testing.T) {
+	tests := map[string]struct {
+		states      []armresources.ProvisioningState
+		expected    []armresources.ProvisioningState
+		expectError bool
+	}{
+		"Succeeded": {
+			states: []armresources.ProvisioningState{
+				armresources.ProvisioningStateAccepted,
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateSucceeded,
+			},
+			expected: []armresources.ProvisioningState{
+				armresources.ProvisioningStateAccepted,
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateSucceeded,
+			},
+		},
+		"Failed": {
+			states: []armresources.ProvisioningState{
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateFailed,
+			},
+			expected: []armresources.ProvisioningState{
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateFailed,
+			},
+		},
+		"RunningToAccepted": {
+			states: []armresources.ProvisioningState{
+				armresources.ProvisioningStateRunning,
+				armresources.ProvisioningStateAccepted,
+			},
+			expected: []armresources.ProvisioningState{
+				armresources.ProvisioningStateRunning,
+			},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			polls := 0
+			getDeployment := func(ctx context.Context) (*armresources.DeploymentExtended, error) {
+				state := test.states[polls]
+				polls++
+
+				return &armresources.DeploymentExtended{
+					Properties: &armresources.DeploymentPropertiesExtended{
+						ProvisioningState: to.Ptr(state),
+					},
+				}, nil
+			}
+
+			mockContext := mocks.NewMockContext(context.Background())
+			ds := newTestDeployments(mockContext)
+			ds.clock = mockContext.Clock
+
+			// Polls wait on the mock clock, which is only advanced once the first state is observed
+			started := make(chan struct{})
+			done := make(chan struct{})
+			var advancing sync.WaitGroup
+			advancing.Add(1)
+			go func() {
+				defer advancing.Done()
+				<-started
+				for {
+					select {
+					case <-done:
+						return
+					case <-time.After(time.Millisecond):
+						mockContext.AdvanceTime(time.Minute)
+					}
+				}
+			}()
+
+			changes := []armresources.ProvisioningState{}
+			timestamps := []time.Time{}
+			_, err := ds.WatchDeploymentStates(
+				*mockContext.Context,
+				getDeployment,
+				time.Minute,
+				func(change DeploymentStateChange) {
+					changes = append(changes, change.State)
+					timestamps = append(timestamps, change.Timestamp)
+					if len(timestamps) == 1 {
+						close(started)
+					}
+				},
+			)
+			close(done)
+			advancing.Wait()
+
+			if test.expectError {
+				require.ErrorIs(t, err, ErrIllegalStateTransition)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expected, changes)
+
+			// Changes are timestamped with the mock clock, at least one poll interval apart
+			require.Equal(t, time.Unix(0, 0), timestamps[0])
+			for i := 1; i < len(timestamps); i++ {
+				require.GreaterOrEqual(t, timestamps[i].Sub(timestamps[i-1]), time.Minute)
+			}
+		})
+	}
+}
+
+func Test_ValidateStateTransition(t *testing.T) {
+	err := validateStateTransition(armresources.ProvisioningStateSucceeded, armresources.ProvisioningStateRunning)
+	require.ErrorIs(t, err, ErrIllegalStateTransition)
+	require.Contains(t, err.Error(), "Succeeded -> Running")
+
+	err = validateStateTransition(armresources.ProvisioningStateSucceeded, armresources.ProvisioningStateFailed)
+	require.ErrorIs(t, err, ErrIllegalStateTransition)
+
+	require.NoError(t, validateStateTransition("", armresources.ProvisioningStateSucceeded))
+	require.NoError(t, validateStateTransition(armresources.ProvisioningStateAccepted, armresources.ProvisioningStateRunning))
+}
@@ -0,0 +1,42 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// Merges the tags with the tags of the existing deployment when DeployOptions.MergeTags is set, the specified tags win
+// on conflict. Returns the tags unchanged when merging is disabled or the deployment doesn't exist yet.
+func mergeDeploymentTags(
+	tags map[string]*string,
+	options *DeployOptions,
+	getDeployment func() (*armresources.DeploymentExtended, error),
+) (map[string]*string, error) {
+	if options == nil || !options.MergeTags {
+		return tags, nil
+	}
+
+	deployment, err := getDeployment()
+	if errors.Is(err, ErrDeploymentNotFound) {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting existing deployment tags: %w", err)
+	}
+
+	merged := map[string]*string{}
+	for key, value := range deployment.Tags {
+		merged[key] = value
+	}
+
+	for key, value := range tags {
+		merged[key] = value
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+)
+
+// WhatIfSummary groups the resource IDs of a what-if result by change type.
+// The number of changes of each type is the length of the matching list.
+type WhatIfSummary struct {
+	Create []string
+	// Includes resources that will be redeployed without ARM being able to tell whether their properties change
+	Modify []string
+	Delete []string
+	// Includes resources whose changes are unsupported by what-if
+	Ignore   []string
+	NoChange []string
+}
+
+// SummarizeWhatIf groups the changes of a what-if result by change type, ex. to render a summary before asking for
+// confirmation. Returns an empty summary when the result has no changes.
+func SummarizeWhatIf(result *armresources.WhatIfOperationResult) WhatIfSummary {
+	summary := WhatIfSummary{
+		Create:   []string{},
+		Modify:   []string{},
+		Delete:   []string{},
+		Ignore:   []string{},
+		NoChange: []string{},
+	}
+
+	if result == nil || result.Properties == nil {
+		return summary
+	}
+
+	for _, change := range result.Properties.Changes {
+		if change == nil || change.ResourceID == nil || change.ChangeType == nil {
+			continue
+		}
+
+		resourceId := *change.ResourceID
+		switch *change.ChangeType {
+		case armresources.ChangeTypeCreate:
+			summary.Create = append(summary.Create, resourceId)
+		case armresources.ChangeTypeModify, armresources.ChangeTypeDeploy:
+			summary.Modify = append(summary.Modify, resourceId)
+		case armresources.ChangeTypeDelete:
+			summary.Delete = append(summary.Delete, resourceId)
+		case armresources.ChangeTypeIgnore, armresources.ChangeTypeUnsupported:
+			summary.Ignore = append(summary.Ignore, resourceId)
+		case armresources.ChangeTypeNoChange:
+			summary.NoChange = append(summary.NoChange, resourceId)
+		}
+	}
+
+	return summary
+}
+
+// HasChanges returns true when any resource will be created, modified or deleted
+func (s WhatIfSummary) HasChanges() bool {
+	return len(s.Create) > 0 || len(s.Modify) > 0 || len(s.Delete) > 0
+}
+
+// String formats the summary as the number of resources to create, modify and delete, ex. '3 to create, 1 to delete'
+func (s WhatIfSummary) String() string {
+	if !s.HasChanges() {
+		return "no changes"
+	}
+
+	parts := []string{}
+	for _, bucket := range []struct {
+		action string
+		ids    []string
+	}{
+		{"create", s.Create},
+		{"modify", s.Modify},
+		{"delete", s.Delete},
+	} {
+		if len(bucket.ids) > 0 {
+			parts = append(parts, fmt.Sprintf("%d to %s", len(bucket.ids), bucket.action))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// FilterWhatIfChanges returns a copy of the what-if result with only the changes of the included resource types that
+// aren't excluded, ex. to preview the changes of the 'Microsoft.Web/sites' of a single service. An empty include list
+// includes all resource types. Resource types are compared case-insensitively and a type also matches its child types,
+// so 'Microsoft.Web' matches every resource type of the provider namespace and 'Microsoft.Web/sites' matches
+// 'Microsoft.Web/sites/slots'.
+func FilterWhatIfChanges(
+	result *armresources.WhatIfOperationResult,
+	include []string,
+	exclude []string,
+) *armresources.WhatIfOperationResult {
+	if result == nil || result.Properties == nil {
+		return result
+	}
+
+	changes := []*armresources.WhatIfChange{}
+	for _, change := range result.Properties.Changes {
+		if change == nil {
+			continue
+		}
+
+		resourceType := ""
+		if change.ResourceID != nil {
+			if resourceId, err := arm.ParseResourceID(*change.ResourceID); err == nil {
+				resourceType = resourceId.ResourceType.String()
+			}
+		}
+
+		if len(include) > 0 && !matchesResourceType(resourceType, include) {
+			continue
+		}
+
+		if matchesResourceType(resourceType, exclude) {
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	filtered := *result
+	properties := *result.Properties
+	properties.Changes = changes
+	filtered.Properties = &properties
+
+	return &filtered
+}
+
+// Whether the resource type equals, or is a child type of, any of the resource types
+func matchesResourceType(resourceType string, resourceTypes []string) bool {
+	if resourceType == "" {
+		return false
+	}
+
+	for _, candidate := range resourceTypes {
+		candidate = strings.TrimSuffix(candidate, "/")
+		if strings.EqualFold(resourceType, candidate) ||
+			(len(resourceType) > len(candidate) &&
+				strings.EqualFold(resourceType[:len(candidate)], candidate) &&
+				resourceType[len(candidate)] == '/') {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SummarizeWhatIf(t *testing.T) {
+	t.Run("Changes", func(t *testing.T) {
+		change := func(changeType armresources.ChangeType, id string) *armresources.WhatIfChange {
+			return &armresources.WhatIfChange{ChangeType: to.Ptr(changeType), ResourceID: to.Ptr(id)}
+		}
+
+		result := &armresources.WhatIfOperationResult{
+			Properties: &armresources.WhatIfOperationProperties{
+				Changes: []*armresources.WhatIfChange{
+					change(armresources.ChangeTypeCreate, "/rg/web"),
+					change(armresources.ChangeTypeCreate, "/rg/api"),
+					change(armresources.ChangeTypeCreate, "/rg/db"),
+					change(armresources.ChangeTypeModify, "/rg/plan"),
+					change(armresources.ChangeTypeDeploy, "/rg/vault"),
+					change(armresources.ChangeTypeDelete, "/rg/old"),
+					change(armresources.ChangeTypeIgnore, "/rg/other"),
+					change(armresources.ChangeTypeUnsupported, "/rg/unsupported"),
+					change(armresources.ChangeTypeNoChange, "/rg/logs"),
+					nil,
+				},
+			},
+		}
+
+		summary := SummarizeWhatIf(result)
+		require.Equal(t, []string{"/rg/web", "/rg/api", "/rg/db"}, summary.Create)
+		require.Equal(t, []string{"/rg/plan", "/rg/vault"}, summary.Modify)
+		require.Equal(t, []string{"/rg/old"}, summary.Delete)
+		require.Equal(t, []string{"/rg/other", "/rg/unsupported"}, summary.Ignore)
+		require.Equal(t, []string{"/rg/logs"}, summary.NoChange)
+		require.True(t, summary.HasChanges())
+		require.Equal(t, "3 to create, 2 to modify, 1 to delete", summary.String())
+	})
+
+	t.Run("NilChanges", func(t *testing.T) {
+		for _, result := range []*armresources.WhatIfOperationResult{
+			nil,
+			{},
+			{Properties: &armresources.WhatIfOperationProperties{}},
+		} {
+			summary := SummarizeWhatIf(result)
+			require.Empty(t, summary.Create)
+			require.Empty(t, summary.NoChange)
+			require.False(t, summary.HasChanges())
+			require.Equal(t, "no changes", summary.String())
+		}
+	})
+}
+
+func Test_FilterWhatIfChanges(t *testing.T) {
+	rg := "/subscriptions/SUBSCRIPTION_ID/resourceGroups/RESOURCE_GROUP/providers/"
+	ids := []string{
+		rg + "Microsoft.Web/sites/web",
+		rg + "Microsoft.Web/sites/web/slots/staging",
+		rg + "Microsoft.Web/serverfarms/plan",
+		rg + "microsoft.keyvault/vaults/vault",
+		rg + "Microsoft.Insights/components/insights",
+	}
+
+	changes := []*armresources.WhatIfChange{}
+	for _, id := range ids {
+		changes = append(changes, &armresources.WhatIfChange{
+			ChangeType: to.Ptr(armresources.ChangeTypeCreate),
+			ResourceID: to.Ptr(id),
+		})
+	}
+
+	result := &armresources.WhatIfOperationResult{
+		Status:     to.Ptr("Succeeded"),
+		Properties: &armresources.WhatIfOperationProperties{Changes: changes},
+	}
+
+	tests := map[string]struct {
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		"ResourceType": {
+			include:  []string{"microsoft.web/sites"},
+			expected: []string{ids[0], ids[1]},
+		},
+		"ProviderNamespace": {
+			include:  []string{"Microsoft.Web"},
+			expected: []string{ids[0], ids[1], ids[2]},
+		},
+		"Exclude": {
+			exclude:  []string{"Microsoft.Web/sites", "Microsoft.Insights"},
+			expected: []string{ids[2], ids[3]},
+		},
+		"IncludeAndExclude": {
+			include:  []string{"Microsoft.Web", "Microsoft.KeyVault/vaults"},
+			exclude:  []string{"Microsoft.Web/sites/slots"},
+			expected: []string{ids[0], ids[2], ids[3]},
+		},
+		"NotAPrefixOfTheName": {
+			include:  []string{"Microsoft.Web/site"},
+			expected: []string{},
+		},
+		"NoFilters": {
+			expected: ids,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			filtered := FilterWhatIfChanges(result, test.include, test.exclude)
+			require.Equal(t, "Succeeded", *filtered.Status)
+
+			filteredIds := []string{}
+			for _, change := range filtered.Properties.Changes {
+				filteredIds = append(filteredIds, *change.ResourceID)
+			}
+			require.Equal(t, test.expected, filteredIds)
+		})
+	}
+
+	// The original result isn't modified
+	require.Len(t, result.Properties.Changes, len(ids))
+	require.Nil(t, FilterWhatIfChanges(nil, []string{"Microsoft.Web"}, nil))
+}
@@ -5,17 +5,30 @@ package azapi
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/azure/azure-dev/cli/azd/pkg/account"
+	"github.com/azure/azure-dev/cli/azd/pkg/azsdk"
 	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+	"github.com/azure/azure-dev/cli/azd/pkg/convert"
+	"github.com/benbjohnson/clock"
 )
 
 type Deployments interface {
@@ -23,6 +36,11 @@ type Deployments interface {
 		ctx context.Context,
 		subscriptionId string,
 	) ([]*armresources.DeploymentExtended, error)
+	ListSubscriptionDeploymentsWithOptions(
+		ctx context.Context,
+		subscriptionId string,
+		options *ListDeploymentsOptions,
+	) ([]*armresources.DeploymentExtended, error)
 	GetSubscriptionDeployment(
 		ctx context.Context,
 		subscriptionId string,
@@ -47,6 +65,7 @@ type Deployments interface {
 		armTemplate azure.RawArmTemplate,
 		parameters azure.ArmParameters,
 		tags map[string]*string,
+		options *DeployOptions,
 	) (*armresources.DeploymentExtended, error)
 	DeployToResourceGroup(
 		ctx context.Context,
@@ -56,6 +75,7 @@ type Deployments interface {
 		armTemplate azure.RawArmTemplate,
 		parameters azure.ArmParameters,
 		tags map[string]*string,
+		options *DeployOptions,
 	) (*armresources.DeploymentExtended, error)
 	WhatIfDeployToSubscription(
 		ctx context.Context,
@@ -64,6 +84,7 @@ type Deployments interface {
 		deploymentName string,
 		armTemplate azure.RawArmTemplate,
 		parameters azure.ArmParameters,
+		options *DeployOptions,
 	) (*armresources.WhatIfOperationResult, error)
 	WhatIfDeployToResourceGroup(
 		ctx context.Context,
@@ -72,64 +93,580 @@ type Deployments interface {
 		deploymentName string,
 		armTemplate azure.RawArmTemplate,
 		parameters azure.ArmParameters,
+		options *DeployOptions,
 	) (*armresources.WhatIfOperationResult, error)
+	ListManagementGroupDeployments(
+		ctx context.Context,
+		subscriptionId string,
+		managementGroupId string,
+	) ([]*armresources.DeploymentExtended, error)
+	GetManagementGroupDeployment(
+		ctx context.Context,
+		subscriptionId string,
+		managementGroupId string,
+		deploymentName string,
+	) (*armresources.DeploymentExtended, error)
+	DeployToManagementGroup(
+		ctx context.Context,
+		subscriptionId string,
+		managementGroupId string,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		tags map[string]*string,
+		options *DeployOptions,
+	) (*armresources.DeploymentExtended, error)
+	WhatIfDeployToManagementGroup(
+		ctx context.Context,
+		subscriptionId string,
+		managementGroupId string,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		options *DeployOptions,
+	) (*armresources.WhatIfOperationResult, error)
+	DeployToTenant(
+		ctx context.Context,
+		subscriptionId string,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		tags map[string]*string,
+		options *DeployOptions,
+	) (*armresources.DeploymentExtended, error)
+	WhatIfDeployToTenant(
+		ctx context.Context,
+		subscriptionId string,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		options *DeployOptions,
+	) (*armresources.WhatIfOperationResult, error)
+	ValidateDeployToSubscription(
+		ctx context.Context,
+		subscriptionId string,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		tags map[string]*string,
+		options *DeployOptions,
+	) (*armresources.DeploymentValidateResult, error)
+	ValidateDeployToResourceGroup(
+		ctx context.Context,
+		subscriptionId,
+		resourceGroup,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		tags map[string]*string,
+		options *DeployOptions,
+	) (*armresources.DeploymentValidateResult, error)
+	PreflightDeploy(
+		ctx context.Context,
+		scope DeploymentScope,
+		location string,
+		deploymentName string,
+		armTemplate azure.RawArmTemplate,
+		parameters azure.ArmParameters,
+		options *DeployOptions,
+	) (*PreflightResult, error)
+	ExportDeploymentTemplate(
+		ctx context.Context,
+		scope DeploymentScope,
+		deploymentName string,
+	) (azure.RawArmTemplate, error)
+	ExportDeploymentBundle(
+		ctx context.Context,
+		subscriptionId string,
+		resourceGroupName string,
+		deploymentName string,
+	) (azure.RawArmTemplate, azure.ArmParameters, error)
+	ListDeploymentOperations(
+		ctx context.Context,
+		scope DeploymentScope,
+		deploymentName string,
+	) ([]*armresources.DeploymentOperation, error)
+	GetDeploymentErrorReport(ctx context.Context, scope DeploymentScope, deploymentName string) (string, error)
+	CancelDeployment(ctx context.Context, scope DeploymentScope, deploymentName string) error
+	WatchDeploymentStates(
+		ctx context.Context,
+		getDeployment func(ctx context.Context) (*armresources.DeploymentExtended, error),
+		frequency time.Duration,
+		onChange func(change DeploymentStateChange),
+	) (*armresources.DeploymentExtended, error)
+	RedeployLastSuccessful(
+		ctx context.Context,
+		scope DeploymentScope,
+		predicate func(deployment *armresources.DeploymentExtended) bool,
+		options *DeployOptions,
+	) (*armresources.DeploymentExtended, error)
 	DeleteSubscriptionDeployment(ctx context.Context, subscriptionId string, deploymentName string) error
+	PruneSubscriptionDeployments(
+		ctx context.Context,
+		subscriptionId string,
+		keepCount int,
+		olderThan time.Duration,
+	) ([]string, error)
+	DeleteResourceGroupDeployment(
+		ctx context.Context,
+		subscriptionId string,
+		resourceGroupName string,
+		deploymentName string,
+	) error
 	CalculateTemplateHash(
 		ctx context.Context,
 		subscriptionId string,
 		template azure.RawArmTemplate) (armresources.DeploymentsClientCalculateTemplateHashResponse, error)
+	DeployedTemplateHash(
+		ctx context.Context,
+		subscriptionId string,
+		resourceGroupName string,
+		deploymentName string,
+	) (string, error)
 }
 
 var (
-	ErrDeploymentNotFound = errors.New("deployment not found")
+	ErrDeploymentNotFound       = errors.New("deployment not found")
+	ErrDeploymentNotCancellable = errors.New("deployment cannot be cancelled")
+	ErrInvalidTemplateSource    = errors.New("invalid deployment template source")
+	ErrLocationRequired         = errors.New("a location is required for deployments above resource group scope")
+	// Returned when complete mode is requested for a deployment above resource group scope
+	ErrCompleteModeNotSupported = errors.New("complete mode is only supported for resource group deployments")
+	// Returned when the context deadline is exceeded while waiting for a deployment, which may still be running in Azure
+	ErrDeploymentTimeout = errors.New("deployment timed out")
+	// Returned when the context is canceled while waiting for a deployment, which may still be running in Azure
+	ErrDeploymentCanceled = errors.New("deployment canceled")
 )
 
+// DeploymentScope identifies where a deployment is located.
+// When ResourceGroupName is empty the deployment is scoped to the subscription.
+type DeploymentScope struct {
+	SubscriptionId    string
+	ResourceGroupName string
+
+	// Only set internally to poll management group and tenant deployments, the subscription id then only resolves the
+	// credential
+	managementGroupId string
+	tenant            bool
+}
+
+const (
+	// The default base frequency used when polling long-running deployment operations
+	defaultPollFrequency = 30 * time.Second
+	// The default fraction of the poll frequency used to randomize poll intervals
+	defaultPollJitter = 0.2
+)
+
+// Optional settings for the deployments service
+type DeploymentsOptions struct {
+	// The base frequency used when polling long-running deployment operations, defaults to 30 seconds.
+	PollFrequency time.Duration
+	// The fraction (between 0 and 1) of the poll frequency used to randomize the poll interval of each operation.
+	// The interval is chosen within [PollFrequency*(1-PollJitter), PollFrequency*(1+PollJitter)] so that many
+	// concurrent deployments don't poll ARM in lockstep and get throttled. Defaults to 0.2, a negative value disables
+	// jitter.
+	PollJitter float64
+	// The client ID of a user-assigned managed identity used to authenticate all deployment requests instead of the
+	// credential of the subscription.
+	ManagedIdentityClientId string
+	// Creates the credential for a managed identity client ID, defaults to an azidentity managed identity credential.
+	ManagedIdentityCredential ManagedIdentityCredentialFactory
+	// Retries starting deployments that fail because of throttling or transient errors, defaults to 3 retries with an
+	// exponential backoff starting at 5 seconds.
+	Retry *DeploymentRetryOptions
+	// A suffix appended to the User-Agent header of all deployment requests, ex. to attribute requests to a partner.
+	UserAgent string
+	// Disables caching the results of CalculateTemplateHash, ex. for tests that count hash requests.
+	// By default results are cached by template content for the lifetime of the deployments service.
+	DisableTemplateHashCache bool
+	// The clock used to get the current time, ex. for the age of pruned deployments, defaults to the system clock.
+	Clock clock.Clock
+}
+
+// ManagedIdentityCredentialFactory creates a credential bound to the user-assigned managed identity with the client ID
+type ManagedIdentityCredentialFactory func(clientId string) (azcore.TokenCredential, error)
+
+func newManagedIdentityCredential(clientId string) (azcore.TokenCredential, error) {
+	return azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+		ID: azidentity.ClientID(clientId),
+	})
+}
+
+// Optional settings for a single deployment or deployment preview
+type DeployOptions struct {
+	// The deployment mode, defaults to incremental. In complete mode, resources of the resource group that are not
+	// defined in the template are deleted. ARM only supports complete mode for resource group deployments, deployments
+	// and previews at other scopes return ErrCompleteModeNotSupported.
+	Mode armresources.DeploymentMode
+	// When set, invoked with the current deployment operations while the deployment is in progress, ex. to render the
+	// provisioning status of each resource. Only used when deploying, not when previewing or validating.
+	Progress DeploymentProgressFn
+	// The frequency at which the deployment operations are reported to the progress callback, defaults to 10 seconds.
+	ProgressFrequency time.Duration
+	// The frequency at which the deployment is polled until it completes, defaults to the poll frequency of the
+	// deployments service. The minimum allowed frequency is one second.
+	PollingFrequency time.Duration
+	// The client ID of a user-assigned managed identity used to run this deployment, overrides the managed identity
+	// configured for the deployments service.
+	ManagedIdentityClientId string
+	// Deploys the template at the specified URI or template spec resource ID instead of an inline template.
+	// Can't be combined with an inline template.
+	TemplateLink *armresources.TemplateLink
+	// Uses the parameters file at the specified URI instead of inline parameters.
+	// Can't be combined with inline parameters.
+	ParametersLink *armresources.ParametersLink
+	// The format of the resource changes returned when previewing a deployment, defaults to full resource payloads.
+	// Only used when previewing, see WhatIfDeployToSubscription.
+	WhatIfResultFormat armresources.WhatIfResultFormat
+	// Merges the tags with the tags of the existing deployment with the same name instead of replacing them, so that
+	// tags applied outside of azd are kept. The specified tags win on conflict. Only used when deploying.
+	MergeTags bool
+	// The maximum time to wait for the deployment to complete. When it elapses, the deployment is canceled in Azure so
+	// that it doesn't keep running unattended, and ErrDeploymentTimeout is returned. Zero waits until the context ends.
+	// Only used when deploying.
+	Timeout time.Duration
+	// When set, invoked each time the provisioning state of the deployment changes, ex. from Accepted to Running, rather
+	// than on every poll. Only used when deploying.
+	StateChanged DeploymentStateChangedFn
+}
+
+// Validates that the template and parameters are either inlined or linked, but not both.
+func (o *DeployOptions) validateTemplateSource(armTemplate azure.RawArmTemplate, parameters azure.ArmParameters) error {
+	if o == nil {
+		return nil
+	}
+
+	if o.TemplateLink != nil && len(armTemplate) > 0 {
+		return fmt.Errorf("%w: a template link and an inline template cannot both be specified", ErrInvalidTemplateSource)
+	}
+
+	if o.ParametersLink != nil && len(parameters) > 0 {
+		return fmt.Errorf(
+			"%w: a parameters link and inline parameters cannot both be specified", ErrInvalidTemplateSource)
+	}
+
+	return nil
+}
+
+func (o *DeployOptions) templateLink() *armresources.TemplateLink {
+	if o == nil {
+		return nil
+	}
+
+	return o.TemplateLink
+}
+
+func (o *DeployOptions) parametersLink() *armresources.ParametersLink {
+	if o == nil {
+		return nil
+	}
+
+	return o.ParametersLink
+}
+
+// Inline templates and parameters are omitted from the request when empty, since ARM rejects null values next to
+// template and parameters links.
+func inlineTemplate(armTemplate azure.RawArmTemplate) any {
+	if len(armTemplate) == 0 {
+		return nil
+	}
+
+	return armTemplate
+}
+
+func inlineParameters(parameters azure.ArmParameters) any {
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	return parameters
+}
+
+// Returns the what-if settings to use, defaulting to full resource payloads when no result format is configured.
+func (o *DeployOptions) whatIfSettings() *armresources.DeploymentWhatIfSettings {
+	if o == nil || o.WhatIfResultFormat == "" {
+		return &armresources.DeploymentWhatIfSettings{
+			ResultFormat: to.Ptr(armresources.WhatIfResultFormatFullResourcePayloads),
+		}
+	}
+
+	return &armresources.DeploymentWhatIfSettings{ResultFormat: to.Ptr(o.WhatIfResultFormat)}
+}
+
+// Returns the deployment mode to use, defaulting to incremental when no mode is configured.
+// Validates the deployment mode of a deployment above resource group scope, where ARM rejects complete mode
+func (o *DeployOptions) validateModeAboveResourceGroup() error {
+	if o != nil && o.Mode == armresources.DeploymentModeComplete {
+		return ErrCompleteModeNotSupported
+	}
+
+	return nil
+}
+
+func (o *DeployOptions) deploymentMode() *armresources.DeploymentMode {
+	if o == nil || o.Mode == "" {
+		return to.Ptr(armresources.DeploymentModeIncremental)
+	}
+
+	return to.Ptr(o.Mode)
+}
+
+func (o *DeployOptions) managedIdentityClientId() string {
+	if o == nil {
+		return ""
+	}
+
+	return o.ManagedIdentityClientId
+}
+
 type deployments struct {
 	credentialProvider account.SubscriptionCredentialProvider
 	armClientOptions   *arm.ClientOptions
+	pollFrequency      time.Duration
+	pollJitter         float64
+	// The default managed identity used to authenticate requests, empty to use the subscription credential
+	managedIdentityClientId   string
+	managedIdentityCredential ManagedIdentityCredentialFactory
+	retryOptions              DeploymentRetryOptions
+	userAgent                 string
+	// The template hashes of deployments, keyed by deployment scope and name
+	deployedTemplateHashes     map[string]deployedTemplateHash
+	deployedTemplateHashesLock sync.Mutex
+	// The results of CalculateTemplateHash keyed by the SHA-256 of the template, nil when caching is disabled
+	templateHashes     map[string]armresources.DeploymentsClientCalculateTemplateHashResponse
+	templateHashesLock sync.Mutex
+	// Returns a pseudo-random number in [0.0,1.0), replaceable for deterministic tests
+	randFloat func() float64
+	clock     clock.Clock
 }
 
+// Creates a new deployments service. Each call creates its ARM client with the credential of the subscription it
+// targets, resolved from the credential provider, so a single service deploys across multiple subscriptions and tenants.
 func NewDeployments(
 	credentialProvider account.SubscriptionCredentialProvider,
 	armClientOptions *arm.ClientOptions,
 ) Deployments {
+	return NewDeploymentsWithOptions(credentialProvider, armClientOptions, nil)
+}
+
+// Creates a new deployments service with the specified options. When options is nil the defaults are used.
+func NewDeploymentsWithOptions(
+	credentialProvider account.SubscriptionCredentialProvider,
+	armClientOptions *arm.ClientOptions,
+	options *DeploymentsOptions,
+) Deployments {
+	if options == nil {
+		options = &DeploymentsOptions{}
+	}
+
+	pollFrequency := options.PollFrequency
+	if pollFrequency == 0 {
+		pollFrequency = defaultPollFrequency
+	}
+
+	pollJitter := options.PollJitter
+	if pollJitter == 0 {
+		pollJitter = defaultPollJitter
+	}
+
+	managedIdentityCredential := options.ManagedIdentityCredential
+	if managedIdentityCredential == nil {
+		managedIdentityCredential = newManagedIdentityCredential
+	}
+
+	retryOptions := defaultDeploymentRetryOptions
+	if options.Retry != nil {
+		retryOptions = *options.Retry
+	}
+
+	deploymentsClock := options.Clock
+	if deploymentsClock == nil {
+		deploymentsClock = clock.New()
+	}
+
+	var templateHashes map[string]armresources.DeploymentsClientCalculateTemplateHashResponse
+	if !options.DisableTemplateHashCache {
+		templateHashes = map[string]armresources.DeploymentsClientCalculateTemplateHashResponse{}
+	}
+
 	return &deployments{
-		credentialProvider: credentialProvider,
-		armClientOptions:   armClientOptions,
+		credentialProvider:        credentialProvider,
+		armClientOptions:          armClientOptions,
+		pollFrequency:             pollFrequency,
+		pollJitter:                min(max(pollJitter, 0), 1),
+		randFloat:                 rand.Float64,
+		managedIdentityClientId:   options.ManagedIdentityClientId,
+		managedIdentityCredential: managedIdentityCredential,
+		retryOptions:              retryOptions,
+		userAgent:                 options.UserAgent,
+		deployedTemplateHashes:    map[string]deployedTemplateHash{},
+		templateHashes:            templateHashes,
+		clock:                     deploymentsClock,
 	}
 }
 
+// CalculateTemplateHash calculates the hash of the template with ARM. The hash only depends on the template
+// content, so results are cached by the SHA-256 of the template unless disabled with
+// DeploymentsOptions.DisableTemplateHashCache.
 func (ds *deployments) CalculateTemplateHash(
 	ctx context.Context,
 	subscriptionId string,
 	template azure.RawArmTemplate) (result armresources.DeploymentsClientCalculateTemplateHashResponse, err error) {
+	var key string
+	if ds.templateHashes != nil {
+		sum := sha256.Sum256(template)
+		key = hex.EncodeToString(sum[:])
+
+		ds.templateHashesLock.Lock()
+		cached, has := ds.templateHashes[key]
+		ds.templateHashesLock.Unlock()
+		if has {
+			return cached, nil
+		}
+	}
+
 	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
 	if err != nil {
 		return result, fmt.Errorf("creating deployments client: %w", err)
 	}
 
-	return deploymentClient.CalculateTemplateHash(ctx, template, nil)
+	result, err = deploymentClient.CalculateTemplateHash(ctx, template, nil)
+	if err != nil {
+		return result, err
+	}
+
+	if ds.templateHashes != nil {
+		ds.templateHashesLock.Lock()
+		ds.templateHashes[key] = result
+		ds.templateHashesLock.Unlock()
+	}
+
+	return result, nil
+}
+
+// The cached template hash of a deployment and the timestamp of the deployment it was calculated for
+type deployedTemplateHash struct {
+	timestamp time.Time
+	hash      string
+}
+
+// DeployedTemplateHash gets the hash of the template used by a resource group deployment, which can be compared with
+// the hash of a local template (see CalculateTemplateHash) to detect drift. Hashes are cached for the lifetime of the
+// deployments service along with the timestamp of the deployment. The deployment is fetched on every call, and the
+// template is only exported again when its timestamp changed, ex. after redeploying with the same deployment name.
+func (ds *deployments) DeployedTemplateHash(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) (string, error) {
+	deployment, err := ds.GetResourceGroupDeployment(ctx, subscriptionId, resourceGroupName, deploymentName)
+	if err != nil {
+		return "", err
+	}
+
+	key := strings.Join([]string{subscriptionId, resourceGroupName, deploymentName}, "/")
+	timestamp := deploymentTimestamp(deployment)
+
+	ds.deployedTemplateHashesLock.Lock()
+	cached, has := ds.deployedTemplateHashes[key]
+	ds.deployedTemplateHashesLock.Unlock()
+	if has && !timestamp.IsZero() && cached.timestamp.Equal(timestamp) {
+		return cached.hash, nil
+	}
+
+	template, err := ds.ExportDeploymentTemplate(ctx, DeploymentScope{
+		SubscriptionId:    subscriptionId,
+		ResourceGroupName: resourceGroupName,
+	}, deploymentName)
+	if err != nil {
+		return "", err
+	}
+
+	hashResult, err := ds.CalculateTemplateHash(ctx, subscriptionId, template)
+	if err != nil {
+		return "", fmt.Errorf("calculating deployed template hash: %w", err)
+	}
+
+	hash := convert.ToValueWithDefault(hashResult.TemplateHash, "")
+	if hash == "" {
+		return "", fmt.Errorf("calculating deployed template hash: no hash returned for deployment '%s'", deploymentName)
+	}
+
+	ds.deployedTemplateHashesLock.Lock()
+	ds.deployedTemplateHashes[key] = deployedTemplateHash{timestamp: timestamp, hash: hash}
+	ds.deployedTemplateHashesLock.Unlock()
+
+	return hash, nil
 }
 
 func (ds *deployments) ListSubscriptionDeployments(
 	ctx context.Context,
 	subscriptionId string,
 ) ([]*armresources.DeploymentExtended, error) {
+	return ds.ListSubscriptionDeploymentsWithOptions(ctx, subscriptionId, nil)
+}
+
+// Optional parameters for deployments listing
+type ListDeploymentsOptions struct {
+	// An optional filter expression passed through to ARM, ex. "provisioningState eq 'Failed'"
+	// https://learn.microsoft.com/rest/api/resources/deployments/list-at-subscription-scope#uri-parameters
+	Filter *string
+	// An optional predicate evaluated locally, only the deployments it returns true for are included
+	Predicate func(deployment *armresources.DeploymentExtended) bool
+	// The maximum number of deployments to return, in the order returned by ARM. Zero returns all deployments.
+	MaxCount int
+}
+
+// ListSubscriptionDeploymentsWithOptions lists the deployments of the subscription matching the filter and predicate.
+// Listing stops as soon as MaxCount deployments are found, so large subscriptions aren't paged through entirely.
+func (ds *deployments) ListSubscriptionDeploymentsWithOptions(
+	ctx context.Context,
+	subscriptionId string,
+	options *ListDeploymentsOptions,
+) ([]*armresources.DeploymentExtended, error) {
+	if options == nil {
+		options = &ListDeploymentsOptions{}
+	}
+
 	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
 
+	listOptions := &armresources.DeploymentsClientListAtSubscriptionScopeOptions{
+		Filter: options.Filter,
+	}
+
+	// The predicate may exclude deployments from a page, so ARM can only limit the results without one
+	if options.MaxCount > 0 && options.Predicate == nil {
+		listOptions.Top = to.Ptr(int32(options.MaxCount))
+	}
+
 	results := []*armresources.DeploymentExtended{}
 
-	pager := deploymentClient.NewListAtSubscriptionScopePager(nil)
+	pager := deploymentClient.NewListAtSubscriptionScopePager(listOptions)
 	for pager.More() {
 		page, err := pager.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, page.Value...)
+		for _, deployment := range page.Value {
+			if options.Predicate != nil && !options.Predicate(deployment) {
+				continue
+			}
+
+			results = append(results, deployment)
+			if options.MaxCount > 0 && len(results) == options.MaxCount {
+				return results, nil
+			}
+		}
 	}
 
 	return results, nil
@@ -162,21 +699,53 @@ func (ds *deployments) ListResourceGroupDeployments(
 	subscriptionId string,
 	resourceGroupName string,
 ) ([]*armresources.DeploymentExtended, error) {
+	return ds.listResourceGroupDeployments(ctx, subscriptionId, resourceGroupName, nil)
+}
+
+// Lists the deployments of the resource group, see ListSubscriptionDeploymentsWithOptions for the options
+func (ds *deployments) listResourceGroupDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	options *ListDeploymentsOptions,
+) ([]*armresources.DeploymentExtended, error) {
+	if options == nil {
+		options = &ListDeploymentsOptions{}
+	}
+
 	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
 
+	listOptions := &armresources.DeploymentsClientListByResourceGroupOptions{
+		Filter: options.Filter,
+	}
+
+	// The predicate may exclude deployments from a page, so ARM can only limit the results without one
+	if options.MaxCount > 0 && options.Predicate == nil {
+		listOptions.Top = to.Ptr(int32(options.MaxCount))
+	}
+
 	results := []*armresources.DeploymentExtended{}
 
-	pager := deploymentClient.NewListByResourceGroupPager(resourceGroupName, nil)
+	pager := deploymentClient.NewListByResourceGroupPager(resourceGroupName, listOptions)
 	for pager.More() {
 		page, err := pager.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, page.Value...)
+		for _, deployment := range page.Value {
+			if options.Predicate != nil && !options.Predicate(deployment) {
+				continue
+			}
+
+			results = append(results, deployment)
+			if options.MaxCount > 0 && len(results) == options.MaxCount {
+				return results, nil
+			}
+		}
 	}
 
 	return results, nil
@@ -205,16 +774,190 @@ func (ds *deployments) GetResourceGroupDeployment(
 	return &deployment.DeploymentExtended, nil
 }
 
-func (ds *deployments) createDeploymentsClient(
+// ExportDeploymentTemplate exports the template that was used by an existing subscription or resource group deployment.
+func (ds *deployments) ExportDeploymentTemplate(
+	ctx context.Context,
+	scope DeploymentScope,
+	deploymentName string,
+) (azure.RawArmTemplate, error) {
+	deploymentClient, err := ds.createDeploymentsClient(ctx, scope.SubscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	var template any
+	if scope.ResourceGroupName == "" {
+		var exportResult armresources.DeploymentsClientExportTemplateAtSubscriptionScopeResponse
+		exportResult, err = deploymentClient.ExportTemplateAtSubscriptionScope(ctx, deploymentName, nil)
+		template = exportResult.Template
+	} else {
+		var exportResult armresources.DeploymentsClientExportTemplateResponse
+		exportResult, err = deploymentClient.ExportTemplate(ctx, scope.ResourceGroupName, deploymentName, nil)
+		template = exportResult.Template
+	}
+	if err != nil {
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return nil, ErrDeploymentNotFound
+		}
+		return nil, fmt.Errorf("exporting deployment template: %w", err)
+	}
+
+	rawTemplate, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling deployment template: %w", err)
+	}
+
+	return rawTemplate, nil
+}
+
+// ExportDeploymentBundle exports the template and the parameters used by a past resource group deployment so that it
+// can be reproduced. ARM never returns the value of secure parameters, these are included in the returned
+// parameters with a nil value and must be supplied by the caller before redeploying.
+func (ds *deployments) ExportDeploymentBundle(
 	ctx context.Context,
 	subscriptionId string,
-) (*armresources.DeploymentsClient, error) {
-	credential, err := ds.credentialProvider.CredentialForSubscription(ctx, subscriptionId)
+	resourceGroupName string,
+	deploymentName string,
+) (azure.RawArmTemplate, azure.ArmParameters, error) {
+	template, err := ds.ExportDeploymentTemplate(ctx, DeploymentScope{
+		SubscriptionId:    subscriptionId,
+		ResourceGroupName: resourceGroupName,
+	}, deploymentName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deployment, err := ds.GetResourceGroupDeployment(ctx, subscriptionId, resourceGroupName, deploymentName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var parameters azure.ArmParameters
+	if deployment.Properties != nil {
+		parameters, err = deploymentParameters(deployment.Properties.Parameters)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading deployment parameters: %w", err)
+		}
+	}
+
+	return template, parameters, nil
+}
+
+// Converts the parameters of a deployment, in the form '{ "name": { "type": "String", "value": "..." } }', into
+// ArmParameters. Secure parameters have no value within the deployment and are returned with a nil value.
+func deploymentParameters(rawParameters any) (azure.ArmParameters, error) {
+	parameters := azure.ArmParameters{}
+	if rawParameters == nil {
+		return parameters, nil
+	}
+
+	parametersJson, err := json.Marshal(rawParameters)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := armresources.NewDeploymentsClient(subscriptionId, credential, ds.armClientOptions)
+	var deploymentParameters map[string]struct {
+		Type  string `json:"type"`
+		Value any    `json:"value"`
+	}
+	if err := json.Unmarshal(parametersJson, &deploymentParameters); err != nil {
+		return nil, err
+	}
+
+	for name, parameter := range deploymentParameters {
+		if strings.HasPrefix(strings.ToLower(parameter.Type), "secure") {
+			parameters[name] = azure.ArmParameterValue{Value: nil}
+			continue
+		}
+
+		parameters[name] = azure.ArmParameterValue{Value: parameter.Value}
+	}
+
+	return parameters, nil
+}
+
+// Creates a deployments client, any additional policies are run once per operation before the configured policies
+func (ds *deployments) createDeploymentsClient(
+	ctx context.Context,
+	subscriptionId string,
+	additionalPolicies ...policy.Policy,
+) (*armresources.DeploymentsClient, error) {
+	return ds.createDeploymentsClientForIdentity(ctx, subscriptionId, "", additionalPolicies...)
+}
+
+// Creates a deployments client authenticated as the managed identity with the client ID. When the client ID is empty,
+// the managed identity of the deployments service is used, or the subscription credential if none is configured.
+func (ds *deployments) createDeploymentsClientForIdentity(
+	ctx context.Context,
+	subscriptionId string,
+	managedIdentityClientId string,
+	additionalPolicies ...policy.Policy,
+) (*armresources.DeploymentsClient, error) {
+	return ds.newDeploymentsClient(ctx, subscriptionId, managedIdentityClientId, nil, additionalPolicies...)
+}
+
+// Creates a deployments client used to start deployments and deployment previews, see
+// createDeploymentsClientForIdentity. The retries of the client pipeline are configured with the deployment retry
+// options of the service, so throttled or transiently failing requests are retried by the pipeline only.
+func (ds *deployments) createDeploymentsClientForDeploy(
+	ctx context.Context,
+	subscriptionId string,
+	managedIdentityClientId string,
+	additionalPolicies ...policy.Policy,
+) (*armresources.DeploymentsClient, error) {
+	return ds.newDeploymentsClient(ctx, subscriptionId, managedIdentityClientId, &ds.retryOptions, additionalPolicies...)
+}
+
+// Creates a deployments client, overriding the retries of the client pipeline when retryOptions is not nil
+func (ds *deployments) newDeploymentsClient(
+	ctx context.Context,
+	subscriptionId string,
+	managedIdentityClientId string,
+	retryOptions *DeploymentRetryOptions,
+	additionalPolicies ...policy.Policy,
+) (*armresources.DeploymentsClient, error) {
+	if managedIdentityClientId == "" {
+		managedIdentityClientId = ds.managedIdentityClientId
+	}
+
+	var credential azcore.TokenCredential
+	var err error
+
+	if managedIdentityClientId != "" {
+		credential, err = ds.managedIdentityCredential(managedIdentityClientId)
+		if err != nil {
+			return nil, fmt.Errorf("creating managed identity credential: %w", err)
+		}
+	} else {
+		credential, err = ds.credentialProvider.CredentialForSubscription(ctx, subscriptionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ds.userAgent != "" {
+		additionalPolicies = append(additionalPolicies, azsdk.NewUserAgentPolicy(ds.userAgent))
+	}
+
+	clientOptions := ds.armClientOptions
+	if len(additionalPolicies) > 0 || retryOptions != nil {
+		clientOptions = &arm.ClientOptions{}
+		if ds.armClientOptions != nil {
+			*clientOptions = *ds.armClientOptions
+		}
+
+		clientOptions.PerCallPolicies = append(
+			append([]policy.Policy{}, additionalPolicies...),
+			clientOptions.PerCallPolicies...,
+		)
+	}
+
+	if retryOptions != nil {
+		clientOptions.Retry = retryOptions.pipelineRetryOptions(clientOptions.Retry)
+	}
+
+	client, err := armresources.NewDeploymentsClient(subscriptionId, credential, clientOptions)
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
@@ -222,6 +965,40 @@ func (ds *deployments) createDeploymentsClient(
 	return client, nil
 }
 
+// Gets the options used when polling long-running deployment operations. The polling frequency of the deployment
+// options takes precedence over the jittered poll frequency of the service, and is shortened when the context deadline
+// would expire before the next poll. The SDK poller waits for the Retry-After delay instead when ARM returns one.
+func (ds *deployments) pollUntilDoneOptions(ctx context.Context, options *DeployOptions) *runtime.PollUntilDoneOptions {
+	frequency := ds.jitteredPollFrequency()
+	if options != nil && options.PollingFrequency > 0 {
+		frequency = options.PollingFrequency
+	}
+
+	if deadline, has := ctx.Deadline(); has {
+		if remaining := time.Until(deadline); remaining < frequency {
+			frequency = max(remaining, min(frequency, time.Second))
+		}
+	}
+
+	return &runtime.PollUntilDoneOptions{
+		Frequency: frequency,
+	}
+}
+
+// Gets a poll frequency randomized within the jitter band around the base poll frequency
+func (ds *deployments) jitteredPollFrequency() time.Duration {
+	if ds.pollJitter == 0 {
+		return ds.pollFrequency
+	}
+
+	// Scale [0.0,1.0) to [-1.0,1.0) to spread the interval evenly below and above the base frequency
+	offset := ds.pollJitter * (2*ds.randFloat() - 1)
+	frequency := time.Duration(float64(ds.pollFrequency) * (1 + offset))
+
+	// ARM operations cannot be polled more often than once per second, unless the base frequency already is
+	return max(frequency, min(ds.pollFrequency, time.Second))
+}
+
 func (ds *deployments) DeployToSubscription(
 	ctx context.Context,
 	subscriptionId string,
@@ -230,8 +1007,43 @@ func (ds *deployments) DeployToSubscription(
 	armTemplate azure.RawArmTemplate,
 	parameters azure.ArmParameters,
 	tags map[string]*string,
+	options *DeployOptions,
 ) (*armresources.DeploymentExtended, error) {
-	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	existing, err := findIdempotentDeployment(tags,
+		func() (*armresources.DeploymentExtended, error) {
+			return ds.GetSubscriptionDeployment(ctx, subscriptionId, deploymentName)
+		},
+		func(listOptions *ListDeploymentsOptions) ([]*armresources.DeploymentExtended, error) {
+			return ds.ListSubscriptionDeploymentsWithOptions(ctx, subscriptionId, listOptions)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	tags, err = mergeDeploymentTags(tags, options, func() (*armresources.DeploymentExtended, error) {
+		return ds.GetSubscriptionDeployment(ctx, subscriptionId, deploymentName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(
+		ctx, subscriptionId, options.managedIdentityClientId(), options.stateChangedPolicies(ds.clock)...)
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
@@ -240,9 +1052,11 @@ func (ds *deployments) DeployToSubscription(
 		ctx, deploymentName,
 		armresources.Deployment{
 			Properties: &armresources.DeploymentProperties{
-				Template:   armTemplate,
-				Parameters: parameters,
-				Mode:       to.Ptr(armresources.DeploymentModeIncremental),
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
 			},
 			Location: to.Ptr(location),
 			Tags:     tags,
@@ -252,7 +1066,8 @@ func (ds *deployments) DeployToSubscription(
 	}
 
 	// wait for deployment creation
-	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, nil)
+	deployResult, err := pollDeploymentUntilDone(
+		ctx, ds, createFromTemplateOperation, DeploymentScope{SubscriptionId: subscriptionId}, deploymentName, options)
 	if err != nil {
 		deploymentError := createDeploymentError(err)
 		return nil, fmt.Errorf(
@@ -270,8 +1085,35 @@ func (ds *deployments) DeployToResourceGroup(
 	armTemplate azure.RawArmTemplate,
 	parameters azure.ArmParameters,
 	tags map[string]*string,
+	options *DeployOptions,
 ) (*armresources.DeploymentExtended, error) {
-	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	existing, err := findIdempotentDeployment(tags,
+		func() (*armresources.DeploymentExtended, error) {
+			return ds.GetResourceGroupDeployment(ctx, subscriptionId, resourceGroup, deploymentName)
+		},
+		func(listOptions *ListDeploymentsOptions) ([]*armresources.DeploymentExtended, error) {
+			return ds.listResourceGroupDeployments(ctx, subscriptionId, resourceGroup, listOptions)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	tags, err = mergeDeploymentTags(tags, options, func() (*armresources.DeploymentExtended, error) {
+		return ds.GetResourceGroupDeployment(ctx, subscriptionId, resourceGroup, deploymentName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(
+		ctx, subscriptionId, options.managedIdentityClientId(), options.stateChangedPolicies(ds.clock)...)
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
@@ -280,9 +1122,11 @@ func (ds *deployments) DeployToResourceGroup(
 		ctx, resourceGroup, deploymentName,
 		armresources.Deployment{
 			Properties: &armresources.DeploymentProperties{
-				Template:   armTemplate,
-				Parameters: parameters,
-				Mode:       to.Ptr(armresources.DeploymentModeIncremental),
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
 			},
 			Tags: tags,
 		}, nil)
@@ -291,7 +1135,8 @@ func (ds *deployments) DeployToResourceGroup(
 	}
 
 	// wait for deployment creation
-	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, nil)
+	scope := DeploymentScope{SubscriptionId: subscriptionId, ResourceGroupName: resourceGroup}
+	deployResult, err := pollDeploymentUntilDone(ctx, ds, createFromTemplateOperation, scope, deploymentName, options)
 	if err != nil {
 		deploymentError := createDeploymentError(err)
 		return nil, fmt.Errorf(
@@ -303,6 +1148,11 @@ func (ds *deployments) DeployToResourceGroup(
 	return &deployResult.DeploymentExtended, nil
 }
 
+// WhatIfDeployToSubscription previews the changes of a subscription deployment.
+//
+// The result format is selected with DeployOptions.WhatIfResultFormat. Full resource payloads include the before and
+// after state of each resource, which allows rendering property diffs but can make the response several megabytes
+// for large deployments. Resource ID only results are much smaller but only report which resources change.
 func (ds *deployments) WhatIfDeployToSubscription(
 	ctx context.Context,
 	subscriptionId string,
@@ -310,8 +1160,21 @@ func (ds *deployments) WhatIfDeployToSubscription(
 	deploymentName string,
 	armTemplate azure.RawArmTemplate,
 	parameters azure.ArmParameters,
+	options *DeployOptions,
 ) (*armresources.WhatIfOperationResult, error) {
-	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(ctx, subscriptionId, options.managedIdentityClientId())
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
@@ -320,10 +1183,12 @@ func (ds *deployments) WhatIfDeployToSubscription(
 		ctx, deploymentName,
 		armresources.DeploymentWhatIf{
 			Properties: &armresources.DeploymentWhatIfProperties{
-				Template:       armTemplate,
-				Parameters:     parameters,
-				Mode:           to.Ptr(armresources.DeploymentModeIncremental),
-				WhatIfSettings: &armresources.DeploymentWhatIfSettings{},
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+				WhatIfSettings: options.whatIfSettings(),
 			},
 			Location: to.Ptr(location),
 		}, nil)
@@ -332,7 +1197,7 @@ func (ds *deployments) WhatIfDeployToSubscription(
 	}
 
 	// wait for deployment creation
-	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, nil)
+	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
 	if err != nil {
 		deploymentError := createDeploymentError(err)
 		return nil, fmt.Errorf(
@@ -344,13 +1209,20 @@ func (ds *deployments) WhatIfDeployToSubscription(
 	return &deployResult.WhatIfOperationResult, nil
 }
 
+// WhatIfDeployToResourceGroup previews the changes of a resource group deployment, see WhatIfDeployToSubscription for
+// the tradeoffs of the result formats.
 func (ds *deployments) WhatIfDeployToResourceGroup(
 	ctx context.Context,
 	subscriptionId, resourceGroup, deploymentName string,
 	armTemplate azure.RawArmTemplate,
 	parameters azure.ArmParameters,
+	options *DeployOptions,
 ) (*armresources.WhatIfOperationResult, error) {
-	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(ctx, subscriptionId, options.managedIdentityClientId())
 	if err != nil {
 		return nil, fmt.Errorf("creating deployments client: %w", err)
 	}
@@ -359,9 +1231,12 @@ func (ds *deployments) WhatIfDeployToResourceGroup(
 		ctx, resourceGroup, deploymentName,
 		armresources.DeploymentWhatIf{
 			Properties: &armresources.DeploymentWhatIfProperties{
-				Template:   armTemplate,
-				Parameters: parameters,
-				Mode:       to.Ptr(armresources.DeploymentModeIncremental),
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+				WhatIfSettings: options.whatIfSettings(),
 			},
 		}, nil)
 	if err != nil {
@@ -369,7 +1244,7 @@ func (ds *deployments) WhatIfDeployToResourceGroup(
 	}
 
 	// wait for deployment creation
-	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, nil)
+	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
 	if err != nil {
 		deploymentError := createDeploymentError(err)
 		return nil, fmt.Errorf(
@@ -381,6 +1256,212 @@ func (ds *deployments) WhatIfDeployToResourceGroup(
 	return &deployResult.WhatIfOperationResult, nil
 }
 
+// ValidateDeployToSubscription validates the template and parameters of a subscription deployment with ARM without
+// deploying any resources. Validation failures are returned as an AzureDeploymentError.
+func (ds *deployments) ValidateDeployToSubscription(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *DeployOptions,
+) (*armresources.DeploymentValidateResult, error) {
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForIdentity(
+		ctx, subscriptionId, options.managedIdentityClientId(), validationErrorPolicy{})
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	validateOperation, err := deploymentClient.BeginValidateAtSubscriptionScope(
+		ctx, deploymentName,
+		armresources.Deployment{
+			Properties: &armresources.DeploymentProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+			},
+			Location: to.Ptr(location),
+			Tags:     tags,
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"validating deployment to subscription:\n\nDeployment Error Details:\n%w",
+			createDeploymentError(err),
+		)
+	}
+
+	validateResult, err := validateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"validating deployment to subscription:\n\nDeployment Error Details:\n%w",
+			createDeploymentError(err),
+		)
+	}
+
+	return &validateResult.DeploymentValidateResult, nil
+}
+
+// ValidateDeployToResourceGroup validates the template and parameters of a resource group deployment with ARM
+// without deploying any resources. Validation failures are returned as an AzureDeploymentError.
+func (ds *deployments) ValidateDeployToResourceGroup(
+	ctx context.Context,
+	subscriptionId, resourceGroup, deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *DeployOptions,
+) (*armresources.DeploymentValidateResult, error) {
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForIdentity(
+		ctx, subscriptionId, options.managedIdentityClientId(), validationErrorPolicy{})
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	validateOperation, err := deploymentClient.BeginValidate(
+		ctx, resourceGroup, deploymentName,
+		armresources.Deployment{
+			Properties: &armresources.DeploymentProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+			},
+			Tags: tags,
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"validating deployment to resource group:\n\nDeployment Error Details:\n%w",
+			createDeploymentError(err),
+		)
+	}
+
+	validateResult, err := validateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"validating deployment to resource group:\n\nDeployment Error Details:\n%w",
+			createDeploymentError(err),
+		)
+	}
+
+	return &validateResult.DeploymentValidateResult, nil
+}
+
+// The validate operations return a 400 status with the validation error in the body, which the SDK accepts as a valid
+// response and then discards when creating the poller. This policy surfaces these responses as errors instead.
+type validationErrorPolicy struct{}
+
+func (p validationErrorPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if err == nil && resp.StatusCode == http.StatusBadRequest {
+		return nil, runtime.NewResponseError(resp)
+	}
+
+	return resp, err
+}
+
+// ListDeploymentOperations lists all the operations of the deployment, one for each resource deployed by the template.
+// The error reported by failed operations is available on the status message of the operation properties,
+// see FailedDeploymentOperations.
+func (ds *deployments) ListDeploymentOperations(
+	ctx context.Context,
+	scope DeploymentScope,
+	deploymentName string,
+) ([]*armresources.DeploymentOperation, error) {
+	if scope.tenant {
+		return ds.listTenantDeploymentOperations(ctx, scope.SubscriptionId, deploymentName)
+	}
+
+	if scope.managementGroupId != "" {
+		return ds.listManagementGroupDeploymentOperations(
+			ctx, scope.SubscriptionId, scope.managementGroupId, deploymentName)
+	}
+
+	deploymentOperations := NewDeploymentOperations(ds.credentialProvider, ds.armClientOptions)
+
+	if scope.ResourceGroupName == "" {
+		return deploymentOperations.ListSubscriptionDeploymentOperations(ctx, scope.SubscriptionId, deploymentName)
+	}
+
+	return deploymentOperations.ListResourceGroupDeploymentOperations(
+		ctx, scope.SubscriptionId, scope.ResourceGroupName, deploymentName)
+}
+
+// CancelDeployment cancels a running deployment. Cancelling a deployment that already reached a terminal state is a
+// no-op. When ARM rejects the cancellation because of the current stage of the deployment an error wrapping
+// ErrDeploymentNotCancellable is returned.
+func (ds *deployments) CancelDeployment(ctx context.Context, scope DeploymentScope, deploymentName string) error {
+	var deployment *armresources.DeploymentExtended
+	var err error
+
+	switch {
+	case scope.tenant:
+		deployment, err = ds.getTenantDeployment(ctx, scope.SubscriptionId, deploymentName)
+	case scope.managementGroupId != "":
+		deployment, err = ds.GetManagementGroupDeployment(
+			ctx, scope.SubscriptionId, scope.managementGroupId, deploymentName)
+	case scope.ResourceGroupName == "":
+		deployment, err = ds.GetSubscriptionDeployment(ctx, scope.SubscriptionId, deploymentName)
+	default:
+		deployment, err = ds.GetResourceGroupDeployment(ctx, scope.SubscriptionId, scope.ResourceGroupName, deploymentName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if deployment.Properties != nil && deployment.Properties.ProvisioningState != nil &&
+		isTerminalProvisioningState(*deployment.Properties.ProvisioningState) {
+		return nil
+	}
+
+	deploymentClient, err := ds.createDeploymentsClient(ctx, scope.SubscriptionId)
+	if err != nil {
+		return fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	switch {
+	case scope.tenant:
+		_, err = deploymentClient.CancelAtTenantScope(ctx, deploymentName, nil)
+	case scope.managementGroupId != "":
+		_, err = deploymentClient.CancelAtManagementGroupScope(ctx, scope.managementGroupId, deploymentName, nil)
+	case scope.ResourceGroupName == "":
+		_, err = deploymentClient.CancelAtSubscriptionScope(ctx, deploymentName, nil)
+	default:
+		_, err = deploymentClient.Cancel(ctx, scope.ResourceGroupName, deploymentName, nil)
+	}
+
+	if err != nil {
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == http.StatusConflict {
+			return fmt.Errorf("cancelling deployment '%s': %w: %w", deploymentName, ErrDeploymentNotCancellable, err)
+		}
+
+		return fmt.Errorf("cancelling deployment '%s': %w", deploymentName, err)
+	}
+
+	return nil
+}
+
 func (ds *deployments) DeleteSubscriptionDeployment(
 	ctx context.Context, subscriptionId string, deploymentName string) error {
 	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
@@ -394,7 +1475,36 @@ func (ds *deployments) DeleteSubscriptionDeployment(
 	}
 
 	// wait for the operation to complete
-	_, err = deleteDeploymentOperation.PollUntilDone(ctx, nil)
+	_, err = deleteDeploymentOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, nil))
+	if err != nil {
+		return fmt.Errorf("deleting deployment operation: %w", err)
+	}
+
+	return nil
+}
+
+func (ds *deployments) DeleteResourceGroupDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) error {
+	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err != nil {
+		return fmt.Errorf("deleting deployment: %w", err)
+	}
+
+	deleteDeploymentOperation, err := deploymentClient.BeginDelete(ctx, resourceGroupName, deploymentName, nil)
+	if err != nil {
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return ErrDeploymentNotFound
+		}
+		return fmt.Errorf("starting to delete deployment: %w", err)
+	}
+
+	// wait for the operation to complete
+	_, err = deleteDeploymentOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, nil))
 	if err != nil {
 		return fmt.Errorf("deleting deployment operation: %w", err)
 	}
@@ -517,7 +1627,13 @@ func createDeploymentError(err error) error {
 		} else {
 			errorText = string(rawBody)
 		}
-		return NewAzureDeploymentError(errorText)
+
+		deploymentErr := NewAzureDeploymentError(errorText)
+		if deploymentErr.correlationId == "" {
+			deploymentErr.correlationId = responseErr.RawResponse.Header.Get(correlationIdHeaderName)
+		}
+
+		return classifyQuotaError(deploymentErr)
 	}
 
 	return err
@@ -0,0 +1,223 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+)
+
+// Management group deployments are not bound to a subscription, the subscription id passed to these methods is only
+// used to resolve the credential for the tenant of the management group.
+
+func (ds *deployments) ListManagementGroupDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+) ([]*armresources.DeploymentExtended, error) {
+	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	results := []*armresources.DeploymentExtended{}
+
+	pager := deploymentClient.NewListAtManagementGroupScopePager(managementGroupId, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, page.Value...)
+	}
+
+	return results, nil
+}
+
+func (ds *deployments) GetManagementGroupDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	deploymentName string,
+) (*armresources.DeploymentExtended, error) {
+	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	deployment, err := deploymentClient.GetAtManagementGroupScope(ctx, managementGroupId, deploymentName, nil)
+	if err != nil {
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return nil, ErrDeploymentNotFound
+		}
+		return nil, fmt.Errorf("getting deployment from management group: %w", err)
+	}
+
+	return &deployment.DeploymentExtended, nil
+}
+
+// Lists the operations of a management group deployment, used to report the progress of the deployment
+func (ds *deployments) listManagementGroupDeploymentOperations(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	deploymentName string,
+) ([]*armresources.DeploymentOperation, error) {
+	deploymentOperations := &deploymentOperations{
+		credentialProvider: ds.credentialProvider,
+		armClientOptions:   ds.armClientOptions,
+	}
+	deploymentOperationsClient, err := deploymentOperations.createDeploymentsOperationsClient(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	result := []*armresources.DeploymentOperation{}
+	pager := deploymentOperationsClient.NewListAtManagementGroupScopePager(managementGroupId, deploymentName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return nil, ErrDeploymentNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed getting list of deployment operations from management group: %w", err)
+		}
+		result = append(result, page.Value...)
+	}
+
+	return result, nil
+}
+
+func (ds *deployments) DeployToManagementGroup(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	tags, err := mergeDeploymentTags(tags, options, func() (*armresources.DeploymentExtended, error) {
+		return ds.GetManagementGroupDeployment(ctx, subscriptionId, managementGroupId, deploymentName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(
+		ctx, subscriptionId, options.managedIdentityClientId(), options.stateChangedPolicies(ds.clock)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	createFromTemplateOperation, err := deploymentClient.BeginCreateOrUpdateAtManagementGroupScope(
+		ctx, managementGroupId, deploymentName,
+		armresources.ScopedDeployment{
+			Properties: &armresources.DeploymentProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+			},
+			Location: to.Ptr(location),
+			Tags:     tags,
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting deployment to management group: %w", err)
+	}
+
+	// wait for deployment creation
+	scope := DeploymentScope{SubscriptionId: subscriptionId, managementGroupId: managementGroupId}
+	deployResult, err := pollDeploymentUntilDone(ctx, ds, createFromTemplateOperation, scope, deploymentName, options)
+	if err != nil {
+		deploymentError := createDeploymentError(err)
+		return nil, fmt.Errorf(
+			"deploying to management group:\n\nDeployment Error Details:\n%w",
+			deploymentError,
+		)
+	}
+
+	return &deployResult.DeploymentExtended, nil
+}
+
+func (ds *deployments) WhatIfDeployToManagementGroup(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(ctx, subscriptionId, options.managedIdentityClientId())
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	createFromTemplateOperation, err := deploymentClient.BeginWhatIfAtManagementGroupScope(
+		ctx, managementGroupId, deploymentName,
+		armresources.ScopedDeploymentWhatIf{
+			Properties: &armresources.DeploymentWhatIfProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+				WhatIfSettings: options.whatIfSettings(),
+			},
+			Location: to.Ptr(location),
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting deployment to management group: %w", err)
+	}
+
+	// wait for deployment creation
+	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	if err != nil {
+		deploymentError := createDeploymentError(err)
+		return nil, fmt.Errorf(
+			"deploying to management group:\n\nDeployment Error Details:\n%w",
+			deploymentError,
+		)
+	}
+
+	return &deployResult.WhatIfOperationResult, nil
+}
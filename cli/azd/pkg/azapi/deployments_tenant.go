@@ -0,0 +1,195 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+)
+
+// Tenant deployments are not bound to a subscription, the subscription id passed to these methods is only used to
+// resolve the credential for the tenant.
+
+// Gets a tenant deployment, used to merge the tags of the existing deployment and to cancel timed out deployments
+func (ds *deployments) getTenantDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	deploymentName string,
+) (*armresources.DeploymentExtended, error) {
+	deploymentClient, err := ds.createDeploymentsClient(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	deployment, err := deploymentClient.GetAtTenantScope(ctx, deploymentName, nil)
+	if err != nil {
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return nil, ErrDeploymentNotFound
+		}
+		return nil, fmt.Errorf("getting deployment from tenant: %w", err)
+	}
+
+	return &deployment.DeploymentExtended, nil
+}
+
+// Lists the operations of a tenant deployment, used to report the progress of the deployment
+func (ds *deployments) listTenantDeploymentOperations(
+	ctx context.Context,
+	subscriptionId string,
+	deploymentName string,
+) ([]*armresources.DeploymentOperation, error) {
+	deploymentOperations := &deploymentOperations{
+		credentialProvider: ds.credentialProvider,
+		armClientOptions:   ds.armClientOptions,
+	}
+	deploymentOperationsClient, err := deploymentOperations.createDeploymentsOperationsClient(ctx, subscriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	result := []*armresources.DeploymentOperation{}
+	pager := deploymentOperationsClient.NewListAtTenantScopePager(deploymentName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		var errDetails *azcore.ResponseError
+		if errors.As(err, &errDetails) && errDetails.StatusCode == 404 {
+			return nil, ErrDeploymentNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed getting list of deployment operations from tenant: %w", err)
+		}
+		result = append(result, page.Value...)
+	}
+
+	return result, nil
+}
+
+func (ds *deployments) DeployToTenant(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	tags, err := mergeDeploymentTags(tags, options, func() (*armresources.DeploymentExtended, error) {
+		return ds.getTenantDeployment(ctx, subscriptionId, deploymentName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(
+		ctx, subscriptionId, options.managedIdentityClientId(), options.stateChangedPolicies(ds.clock)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	createFromTemplateOperation, err := deploymentClient.BeginCreateOrUpdateAtTenantScope(
+		ctx, deploymentName,
+		armresources.ScopedDeployment{
+			Properties: &armresources.DeploymentProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+			},
+			Location: to.Ptr(location),
+			Tags:     tags,
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting deployment to tenant: %w", err)
+	}
+
+	// wait for deployment creation
+	scope := DeploymentScope{SubscriptionId: subscriptionId, tenant: true}
+	deployResult, err := pollDeploymentUntilDone(ctx, ds, createFromTemplateOperation, scope, deploymentName, options)
+	if err != nil {
+		deploymentError := createDeploymentError(err)
+		return nil, fmt.Errorf(
+			"deploying to tenant:\n\nDeployment Error Details:\n%w",
+			deploymentError,
+		)
+	}
+
+	return &deployResult.DeploymentExtended, nil
+}
+
+func (ds *deployments) WhatIfDeployToTenant(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	if location == "" {
+		return nil, ErrLocationRequired
+	}
+
+	if err := options.validateModeAboveResourceGroup(); err != nil {
+		return nil, err
+	}
+
+	if err := options.validateTemplateSource(armTemplate, parameters); err != nil {
+		return nil, err
+	}
+
+	deploymentClient, err := ds.createDeploymentsClientForDeploy(ctx, subscriptionId, options.managedIdentityClientId())
+	if err != nil {
+		return nil, fmt.Errorf("creating deployments client: %w", err)
+	}
+
+	createFromTemplateOperation, err := deploymentClient.BeginWhatIfAtTenantScope(
+		ctx, deploymentName,
+		armresources.ScopedDeploymentWhatIf{
+			Properties: &armresources.DeploymentWhatIfProperties{
+				Template:       inlineTemplate(armTemplate),
+				Parameters:     inlineParameters(parameters),
+				TemplateLink:   options.templateLink(),
+				ParametersLink: options.parametersLink(),
+				Mode:           options.deploymentMode(),
+				WhatIfSettings: options.whatIfSettings(),
+			},
+			Location: to.Ptr(location),
+		}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting deployment to tenant: %w", err)
+	}
+
+	// wait for deployment creation
+	deployResult, err := createFromTemplateOperation.PollUntilDone(ctx, ds.pollUntilDoneOptions(ctx, options))
+	if err != nil {
+		deploymentError := createDeploymentError(err)
+		return nil, fmt.Errorf(
+			"deploying to tenant:\n\nDeployment Error Details:\n%w",
+			deploymentError,
+		)
+	}
+
+	return &deployResult.WhatIfOperationResult, nil
+}
@@ -0,0 +1,1874 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/azure/azure-dev/cli/azd/test/mocks/mockaccount"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_JitteredPollFrequency(t *testing.T) {
+	t.Run("WithinBand", func(t *testing.T) {
+		ds := NewDeploymentsWithOptions(nil, nil, &DeploymentsOptions{
+			PollFrequency: 10 * time.Second,
+			PollJitter:    0.2,
+		}).(*deployments)
+
+		randValues := []float64{0, 0.25, 0.5, 0.75, 0.999999}
+		expected := []time.Duration{8 * time.Second, 9 * time.Second, 10 * time.Second, 11 * time.Second}
+
+		for i, value := range randValues {
+			ds.randFloat = func() float64 { return value }
+			frequency := ds.pollUntilDoneOptions(context.Background(), nil).Frequency
+
+			require.GreaterOrEqual(t, frequency, 8*time.Second)
+			require.Less(t, frequency, 12*time.Second)
+			if i < len(expected) {
+				require.Equal(t, expected[i], frequency)
+			}
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		ds := NewDeployments(nil, nil).(*deployments)
+		ds.randFloat = func() float64 { return 0 }
+
+		require.Equal(t, 24*time.Second, ds.jitteredPollFrequency())
+	})
+
+	t.Run("DefaultJitterWithOptions", func(t *testing.T) {
+		ds := NewDeploymentsWithOptions(nil, nil, &DeploymentsOptions{
+			PollFrequency: 5 * time.Second,
+		}).(*deployments)
+		ds.randFloat = func() float64 { return 0 }
+
+		require.Equal(t, 4*time.Second, ds.jitteredPollFrequency())
+	})
+
+	t.Run("NoJitter", func(t *testing.T) {
+		ds := NewDeploymentsWithOptions(nil, nil, &DeploymentsOptions{
+			PollFrequency: 5 * time.Second,
+			PollJitter:    -1,
+		}).(*deployments)
+		ds.randFloat = func() float64 { return 0 }
+
+		require.Equal(t, 5*time.Second, ds.jitteredPollFrequency())
+	})
+
+	t.Run("MinimumOneSecond", func(t *testing.T) {
+		ds := NewDeploymentsWithOptions(nil, nil, &DeploymentsOptions{
+			PollFrequency: time.Second,
+			PollJitter:    0.5,
+		}).(*deployments)
+		ds.randFloat = func() float64 { return 0 }
+
+		require.Equal(t, time.Second, ds.jitteredPollFrequency())
+	})
+}
+
+func Test_PollUntilDoneOptions(t *testing.T) {
+	ds := NewDeploymentsWithOptions(nil, nil, &DeploymentsOptions{
+		PollFrequency: 20 * time.Second,
+		PollJitter:    -1,
+	}).(*deployments)
+
+	t.Run("Default", func(t *testing.T) {
+		require.Equal(t, 20*time.Second, ds.pollUntilDoneOptions(context.Background(), nil).Frequency)
+		require.Equal(t, 20*time.Second, ds.pollUntilDoneOptions(context.Background(), &DeployOptions{}).Frequency)
+	})
+
+	t.Run("PollingFrequency", func(t *testing.T) {
+		options := &DeployOptions{PollingFrequency: 5 * time.Second}
+		require.Equal(t, 5*time.Second, ds.pollUntilDoneOptions(context.Background(), options).Frequency)
+	})
+
+	t.Run("ContextDeadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		frequency := ds.pollUntilDoneOptions(ctx, nil).Frequency
+		require.LessOrEqual(t, frequency, 10*time.Second)
+		require.Greater(t, frequency, 9*time.Second)
+
+		ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		require.Equal(t, time.Second, ds.pollUntilDoneOptions(ctx, nil).Frequency)
+	})
+}
+
+func Test_DeployToSubscription_IdempotencyKey(t *testing.T) {
+	tests := map[string]struct {
+		// The deployment with the same name as the new deployment, if any
+		byName           *armresources.DeploymentExtended
+		existing         []*armresources.DeploymentExtended
+		expectNewDeploy  bool
+		expectList       bool
+		expectDeployment string
+	}{
+		"FirstDeploy": {
+			existing: []*armresources.DeploymentExtended{
+				testDeployment("other-deploy", "other-key", armresources.ProvisioningStateSucceeded),
+			},
+			expectNewDeploy:  true,
+			expectList:       true,
+			expectDeployment: "new-deploy",
+		},
+		"DuplicateSucceeded": {
+			existing: []*armresources.DeploymentExtended{
+				testDeployment("other-deploy", "other-key", armresources.ProvisioningStateSucceeded),
+				testDeployment("prior-deploy", "key-1", armresources.ProvisioningStateSucceeded),
+			},
+			expectNewDeploy:  false,
+			expectList:       true,
+			expectDeployment: "prior-deploy",
+		},
+		"DuplicateRunning": {
+			existing: []*armresources.DeploymentExtended{
+				testDeployment("prior-deploy", "key-1", armresources.ProvisioningStateRunning),
+			},
+			expectNewDeploy:  false,
+			expectList:       true,
+			expectDeployment: "prior-deploy",
+		},
+		"PriorFailed": {
+			existing: []*armresources.DeploymentExtended{
+				testDeployment("prior-deploy", "key-1", armresources.ProvisioningStateFailed),
+			},
+			expectNewDeploy:  true,
+			expectList:       true,
+			expectDeployment: "new-deploy",
+		},
+		"DuplicateByName": {
+			byName:           testDeployment("new-deploy", "key-1", armresources.ProvisioningStateSucceeded),
+			expectNewDeploy:  false,
+			expectList:       false,
+			expectDeployment: "new-deploy",
+		},
+		"PriorFailedByName": {
+			byName: testDeployment("new-deploy", "key-1", armresources.ProvisioningStateFailed),
+			existing: []*armresources.DeploymentExtended{
+				testDeployment("new-deploy", "key-1", armresources.ProvisioningStateFailed),
+			},
+			expectNewDeploy:  true,
+			expectList:       true,
+			expectDeployment: "new-deploy",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			deployed := mockDeployToSubscription(mockContext, "new-deploy")
+
+			listed := false
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodGet
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(request.URL.Path, "/deployments/") {
+					listed = true
+					return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{
+						Value: test.existing,
+					})
+				}
+
+				if test.byName == nil {
+					return mocks.CreateEmptyHttpResponse(request, http.StatusNotFound)
+				}
+
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, test.byName)
+			})
+
+			ds := newTestDeployments(mockContext)
+			result, err := ds.DeployToSubscription(
+				*mockContext.Context,
+				"SUBSCRIPTION_ID",
+				"eastus2",
+				"new-deploy",
+				testTemplate,
+				nil,
+				map[string]*string{IdempotencyKeyTagName: to.Ptr("key-1")},
+				nil,
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.expectNewDeploy, *deployed)
+			require.Equal(t, test.expectList, listed)
+			require.Equal(t, test.expectDeployment, *result.Name)
+		})
+	}
+}
+
+func Test_Deploy_MergeTags(t *testing.T) {
+	tests := map[string]struct {
+		options  *DeployOptions
+		existing map[string]*string
+		expected map[string]string
+	}{
+		"Replace": {
+			options:  nil,
+			existing: map[string]*string{"cost-center": to.Ptr("1234")},
+			expected: map[string]string{"azd-env-name": "new"},
+		},
+		"Merge": {
+			options:  &DeployOptions{MergeTags: true},
+			existing: map[string]*string{"cost-center": to.Ptr("1234"), "azd-env-name": to.Ptr("old")},
+			expected: map[string]string{"cost-center": "1234", "azd-env-name": "new"},
+		},
+		"MergeFirstDeploy": {
+			options:  &DeployOptions{MergeTags: true},
+			existing: nil,
+			expected: map[string]string{"azd-env-name": "new"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			var deployedTags map[string]string
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				if request.Method == http.MethodGet {
+					if test.existing == nil {
+						return mocks.CreateEmptyHttpResponse(request, http.StatusNotFound)
+					}
+
+					return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+						Name: to.Ptr("DEPLOYMENT_NAME"),
+						Tags: test.existing,
+					})
+				}
+
+				var body struct {
+					Tags map[string]string `json:"tags"`
+				}
+				require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+				deployedTags = body.Tags
+
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+					Name: to.Ptr("DEPLOYMENT_NAME"),
+				})
+			})
+
+			ds := newTestDeployments(mockContext)
+			_, err := ds.DeployToResourceGroup(
+				*mockContext.Context,
+				"SUBSCRIPTION_ID",
+				"RESOURCE_GROUP",
+				"DEPLOYMENT_NAME",
+				testTemplate,
+				nil,
+				map[string]*string{"azd-env-name": to.Ptr("new")},
+				test.options,
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, deployedTags)
+		})
+	}
+}
+
+func Test_Deploy_Mode(t *testing.T) {
+	tests := map[string]struct {
+		options  *DeployOptions
+		expected armresources.DeploymentMode
+		// Whether the mode is also supported at subscription scope
+		subscription bool
+	}{
+		"DefaultIncremental": {
+			options:      nil,
+			expected:     armresources.DeploymentModeIncremental,
+			subscription: true,
+		},
+		"Complete": {
+			options:  &DeployOptions{Mode: armresources.DeploymentModeComplete},
+			expected: armresources.DeploymentModeComplete,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			modes := map[string]armresources.DeploymentMode{}
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return strings.Contains(request.URL.Path, "/providers/Microsoft.Resources/deployments/")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				var body struct {
+					Properties struct {
+						Mode armresources.DeploymentMode `json:"mode"`
+					} `json:"properties"`
+				}
+				require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+				modes[request.URL.Path] = body.Properties.Mode
+
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+					Name: to.Ptr("DEPLOYMENT_NAME"),
+				})
+			})
+
+			ds := newTestDeployments(mockContext)
+			ctx := *mockContext.Context
+
+			_, err := ds.DeployToResourceGroup(
+				ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, test.options)
+			require.NoError(t, err)
+
+			_, err = ds.WhatIfDeployToResourceGroup(
+				ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, test.options)
+			require.NoError(t, err)
+
+			if test.subscription {
+				_, err = ds.DeployToSubscription(
+					ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, test.options)
+				require.NoError(t, err)
+
+				_, err = ds.WhatIfDeployToSubscription(
+					ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, test.options)
+				require.NoError(t, err)
+			}
+
+			// Deploying and previewing each send a single request per scope
+			expectedRequests := 2
+			if test.subscription {
+				expectedRequests = 4
+			}
+			require.Len(t, modes, expectedRequests)
+			for path, mode := range modes {
+				require.Equal(t, test.expected, mode, path)
+			}
+		})
+	}
+}
+
+func Test_Deploy_CompleteModeAboveResourceGroup(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	requested := false
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return strings.Contains(request.URL.Path, "/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		requested = true
+		return mocks.CreateEmptyHttpResponse(request, http.StatusOK)
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+	options := &DeployOptions{Mode: armresources.DeploymentModeComplete}
+
+	_, err := ds.DeployToSubscription(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.WhatIfDeployToSubscription(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.ValidateDeployToSubscription(
+		ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.DeployToManagementGroup(
+		ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.WhatIfDeployToManagementGroup(
+		ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.DeployToTenant(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	_, err = ds.WhatIfDeployToTenant(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, options)
+	require.ErrorIs(t, err, ErrCompleteModeNotSupported)
+
+	// The deployments are rejected before any request is sent
+	require.False(t, requested)
+}
+
+func Test_WhatIf_ResultFormat(t *testing.T) {
+	tests := map[string]struct {
+		options  *DeployOptions
+		expected armresources.WhatIfResultFormat
+	}{
+		"DefaultFullResourcePayloads": {
+			options:  nil,
+			expected: armresources.WhatIfResultFormatFullResourcePayloads,
+		},
+		"ResourceIdOnly": {
+			options:  &DeployOptions{WhatIfResultFormat: armresources.WhatIfResultFormatResourceIDOnly},
+			expected: armresources.WhatIfResultFormatResourceIDOnly,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			formats := map[string]armresources.WhatIfResultFormat{}
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return strings.HasSuffix(request.URL.Path, "/whatIf")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				var body struct {
+					Properties struct {
+						WhatIfSettings struct {
+							ResultFormat armresources.WhatIfResultFormat `json:"resultFormat"`
+						} `json:"whatIfSettings"`
+					} `json:"properties"`
+				}
+				require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+				formats[request.URL.Path] = body.Properties.WhatIfSettings.ResultFormat
+
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.WhatIfOperationResult{
+					Status: to.Ptr("Succeeded"),
+				})
+			})
+
+			ds := newTestDeployments(mockContext)
+			ctx := *mockContext.Context
+
+			_, err := ds.WhatIfDeployToResourceGroup(
+				ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, test.options)
+			require.NoError(t, err)
+
+			_, err = ds.WhatIfDeployToSubscription(
+				ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, test.options)
+			require.NoError(t, err)
+
+			require.Len(t, formats, 2)
+			for path, format := range formats {
+				require.Equal(t, test.expected, format, path)
+			}
+		})
+	}
+}
+
+var testTemplate = []byte(`{
+	"$schema": "https://schema.management.azure.com/schemas/2018-05-01/subscriptionDeploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"resources": []
+}`)
+
+func Test_Deployments_CredentialPerSubscription(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{})
+	})
+
+	credentialSubscriptions := []string{}
+	credentialProvider := mockaccount.SubscriptionCredentialProviderFunc(
+		func(ctx context.Context, subscriptionId string) (azcore.TokenCredential, error) {
+			credentialSubscriptions = append(credentialSubscriptions, subscriptionId)
+			return mockContext.Credentials, nil
+		},
+	)
+
+	// A single deployments service is used across subscriptions, each with the credential of its subscription
+	ds := NewDeployments(credentialProvider, mockContext.ArmClientOptions)
+	_, err := ds.ListSubscriptionDeployments(*mockContext.Context, "SUBSCRIPTION_A")
+	require.NoError(t, err)
+	_, err = ds.ListSubscriptionDeployments(*mockContext.Context, "SUBSCRIPTION_B")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"SUBSCRIPTION_A", "SUBSCRIPTION_B"}, credentialSubscriptions)
+}
+
+func newTestDeployments(mockContext *mocks.MockContext) *deployments {
+	ds := NewDeployments(mockContext.SubscriptionCredentialProvider, mockContext.ArmClientOptions).(*deployments)
+	// Poll as fast as possible within tests
+	ds.pollFrequency = time.Millisecond
+	ds.pollJitter = 0
+
+	return ds
+}
+
+func testDeployment(
+	name string,
+	idempotencyKey string,
+	state armresources.ProvisioningState,
+) *armresources.DeploymentExtended {
+	return &armresources.DeploymentExtended{
+		ID:   to.Ptr("/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/" + name),
+		Name: to.Ptr(name),
+		Tags: map[string]*string{IdempotencyKeyTagName: to.Ptr(idempotencyKey)},
+		Properties: &armresources.DeploymentPropertiesExtended{
+			ProvisioningState: to.Ptr(state),
+		},
+	}
+}
+
+func mockListSubscriptionDeployments(mockContext *mocks.MockContext, deployments []*armresources.DeploymentExtended) {
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{
+			Value: deployments,
+		})
+	})
+}
+
+// Mocks a subscription deployment that completes immediately and returns whether the deployment was started
+func mockDeployToSubscription(mockContext *mocks.MockContext, deploymentName string) *bool {
+	deployed := false
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && strings.HasSuffix(
+			request.URL.Path,
+			"/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/"+deploymentName,
+		)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		deployed = true
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			ID:   to.Ptr("/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/" + deploymentName),
+			Name: to.Ptr(deploymentName),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+			},
+		})
+	})
+
+	return &deployed
+}
+
+func Test_ExportDeploymentBundle(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentPath+"/exportTemplate")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExportResult{
+			Template: map[string]any{
+				"contentVersion": "1.0.0.0",
+				"resources":      []any{},
+			},
+		})
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				Parameters: map[string]any{
+					"location":      map[string]any{"type": "String", "value": "eastus2"},
+					"instanceCount": map[string]any{"type": "Int", "value": 3},
+					"adminPassword": map[string]any{"type": "SecureString"},
+				},
+			},
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	template, parameters, err := ds.ExportDeploymentBundle(
+		*mockContext.Context,
+		"SUBSCRIPTION_ID",
+		"RESOURCE_GROUP",
+		"DEPLOYMENT_NAME",
+	)
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{"contentVersion":"1.0.0.0","resources":[]}`, string(template))
+	require.Equal(t, azure.ArmParameters{
+		"location":      {Value: "eastus2"},
+		"instanceCount": {Value: float64(3)},
+		"adminPassword": {Value: nil},
+	}, parameters)
+}
+
+func Test_DeleteResourceGroupDeployment(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodDelete && strings.HasSuffix(request.URL.Path, deploymentPath+"DEPLOYMENT_NAME")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodDelete && strings.HasSuffix(request.URL.Path, deploymentPath+"MISSING")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNotFound)
+	})
+
+	ds := newTestDeployments(mockContext)
+
+	t.Run("Success", func(t *testing.T) {
+		err := ds.DeleteResourceGroupDeployment(*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME")
+		require.NoError(t, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		err := ds.DeleteResourceGroupDeployment(*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "MISSING")
+		require.ErrorIs(t, err, ErrDeploymentNotFound)
+	})
+}
+
+func Test_ValidateDeploy(t *testing.T) {
+	validationFailure := map[string]any{
+		"error": map[string]any{
+			"code":    "InvalidTemplate",
+			"message": "Deployment template validation failed: 'The template parameter 'location' is not found.'",
+		},
+	}
+
+	tests := map[string]struct {
+		statusCode  int
+		body        any
+		expectError bool
+	}{
+		"Valid": {
+			statusCode: http.StatusOK,
+			body: armresources.DeploymentValidateResult{
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				},
+			},
+		},
+		"Invalid": {
+			statusCode:  http.StatusBadRequest,
+			body:        validationFailure,
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/DEPLOYMENT_NAME/validate")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				return mocks.CreateHttpResponseWithBody(request, test.statusCode, test.body)
+			})
+
+			ds := newTestDeployments(mockContext)
+			ctx := *mockContext.Context
+
+			results := map[string]error{}
+			_, results["ResourceGroup"] = ds.ValidateDeployToResourceGroup(
+				ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+			_, results["Subscription"] = ds.ValidateDeployToSubscription(
+				ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+
+			for scope, err := range results {
+				if !test.expectError {
+					require.NoError(t, err, scope)
+					continue
+				}
+
+				var deploymentErr *AzureDeploymentError
+				require.ErrorAs(t, err, &deploymentErr, scope)
+				require.Contains(t, err.Error(), "InvalidTemplate: Deployment template validation failed", scope)
+			}
+		})
+	}
+}
+
+func Test_CancelDeployment(t *testing.T) {
+	tests := map[string]struct {
+		scope        DeploymentScope
+		state        armresources.ProvisioningState
+		cancelStatus int
+		expectCancel bool
+		expectErr    error
+	}{
+		"RunningResourceGroup": {
+			scope:        DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"},
+			state:        armresources.ProvisioningStateRunning,
+			cancelStatus: http.StatusNoContent,
+			expectCancel: true,
+		},
+		"RunningSubscription": {
+			scope:        DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID"},
+			state:        armresources.ProvisioningStateAccepted,
+			cancelStatus: http.StatusNoContent,
+			expectCancel: true,
+		},
+		"AlreadySucceeded": {
+			scope:        DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID"},
+			state:        armresources.ProvisioningStateSucceeded,
+			expectCancel: false,
+		},
+		"NotCancellable": {
+			scope:        DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"},
+			state:        armresources.ProvisioningStateRunning,
+			cancelStatus: http.StatusConflict,
+			expectCancel: true,
+			expectErr:    ErrDeploymentNotCancellable,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/deployments/DEPLOYMENT_NAME")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+					Name: to.Ptr("DEPLOYMENT_NAME"),
+					Properties: &armresources.DeploymentPropertiesExtended{
+						ProvisioningState: to.Ptr(test.state),
+					},
+				})
+			})
+
+			cancelled := false
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/DEPLOYMENT_NAME/cancel")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				cancelled = true
+				require.Equal(
+					t,
+					test.scope.ResourceGroupName != "",
+					strings.Contains(request.URL.Path, "/resourcegroups/RESOURCE_GROUP/"),
+				)
+
+				return mocks.CreateEmptyHttpResponse(request, test.cancelStatus)
+			})
+
+			ds := newTestDeployments(mockContext)
+			err := ds.CancelDeployment(*mockContext.Context, test.scope, "DEPLOYMENT_NAME")
+			if test.expectErr != nil {
+				require.ErrorIs(t, err, test.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectCancel, cancelled)
+		})
+	}
+}
+
+func Test_DeployToResourceGroup_Progress(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateAccepted),
+			},
+		})
+		response.Header.Set("Azure-AsyncOperation", "https://management.azure.com/operationStatuses/1")
+		return response, err
+	})
+
+	// The deployment completes after a few polls
+	polls := 0
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/operationStatuses/1")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		polls++
+		status := "Running"
+		if polls >= 3 {
+			status = "Succeeded"
+		}
+
+		// Leave time for progress to be reported while the deployment is running
+		time.Sleep(5 * time.Millisecond)
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": status})
+	})
+
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+			},
+		})
+	})
+
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/deployments/DEPLOYMENT_NAME/operations")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+			Value: []*armresources.DeploymentOperation{
+				{
+					OperationID: to.Ptr("OPERATION_ID"),
+					Properties: &armresources.DeploymentOperationProperties{
+						ProvisioningState: to.Ptr("Running"),
+						TargetResource: &armresources.TargetResource{
+							ResourceName: to.Ptr("storage"),
+						},
+					},
+				},
+			},
+		})
+	})
+
+	var lock sync.Mutex
+	reports := 0
+	options := &DeployOptions{
+		ProgressFrequency: time.Millisecond,
+		Progress: func(operations []*armresources.DeploymentOperation) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			reports++
+			require.Len(t, operations, 1)
+			require.Equal(t, "storage", *operations[0].Properties.TargetResource.ResourceName)
+
+			// Callback failures must not abort the deployment
+			return errors.New("failed rendering progress")
+		},
+	}
+
+	ds := newTestDeployments(mockContext)
+	result, err := ds.DeployToResourceGroup(
+		*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+	require.NoError(t, err)
+	require.Equal(t, armresources.ProvisioningStateSucceeded, *result.Properties.ProvisioningState)
+
+	lock.Lock()
+	defer lock.Unlock()
+	// At least one report while running plus the final report
+	require.GreaterOrEqual(t, reports, 2)
+}
+
+func Test_DeployToSubscription_ManagedIdentity(t *testing.T) {
+	tests := map[string]struct {
+		serviceIdentity string
+		deployIdentity  string
+		expectedToken   string
+	}{
+		"SubscriptionCredential": {
+			expectedToken: "ABC123",
+		},
+		"ServiceIdentity": {
+			serviceIdentity: "SERVICE_CLIENT_ID",
+			expectedToken:   "identity-SERVICE_CLIENT_ID",
+		},
+		"DeployIdentityOverride": {
+			serviceIdentity: "SERVICE_CLIENT_ID",
+			deployIdentity:  "DEPLOY_CLIENT_ID",
+			expectedToken:   "identity-DEPLOY_CLIENT_ID",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+
+			var authorization string
+			mockContext.HttpClient.When(func(request *http.Request) bool {
+				return request.Method == http.MethodPut &&
+					strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME")
+			}).RespondFn(func(request *http.Request) (*http.Response, error) {
+				authorization = request.Header.Get("Authorization")
+				return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+					Name: to.Ptr("DEPLOYMENT_NAME"),
+				})
+			})
+
+			identities := []string{}
+			ds := NewDeploymentsWithOptions(
+				mockContext.SubscriptionCredentialProvider,
+				mockContext.ArmClientOptions,
+				&DeploymentsOptions{
+					ManagedIdentityClientId: test.serviceIdentity,
+					ManagedIdentityCredential: func(clientId string) (azcore.TokenCredential, error) {
+						identities = append(identities, clientId)
+						return &mocks.MockCredentials{
+							GetTokenFn: func(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+								return azcore.AccessToken{Token: "identity-" + clientId, ExpiresOn: time.Now().Add(time.Hour)}, nil
+							},
+						}, nil
+					},
+				},
+			)
+
+			_, err := ds.DeployToSubscription(
+				*mockContext.Context,
+				"SUBSCRIPTION_ID",
+				"eastus2",
+				"DEPLOYMENT_NAME",
+				testTemplate,
+				nil,
+				nil,
+				&DeployOptions{ManagedIdentityClientId: test.deployIdentity},
+			)
+			require.NoError(t, err)
+			require.Equal(t, "Bearer "+test.expectedToken, authorization)
+
+			if test.serviceIdentity == "" && test.deployIdentity == "" {
+				require.Empty(t, identities)
+			}
+		})
+	}
+}
+
+func Test_ManagementGroupDeployments(t *testing.T) {
+	deploymentsPath := "/providers/Microsoft.Management/managementGroups/MANAGEMENT_GROUP" +
+		"/providers/Microsoft.Resources/deployments/"
+	deployment := armresources.DeploymentExtended{
+		ID:       to.Ptr(deploymentsPath + "DEPLOYMENT_NAME"),
+		Name:     to.Ptr("DEPLOYMENT_NAME"),
+		Location: to.Ptr("eastus2"),
+		Properties: &armresources.DeploymentPropertiesExtended{
+			ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+		},
+	}
+
+	mockContext := mocks.NewMockContext(context.Background())
+	var deployRequest armresources.ScopedDeployment
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentsPath+"DEPLOYMENT_NAME")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&deployRequest))
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, deployment)
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentsPath+"DEPLOYMENT_NAME")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, deployment)
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentsPath+"MISSING")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNotFound)
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentsPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{
+			Value: []*armresources.DeploymentExtended{&deployment},
+		})
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentsPath+"DEPLOYMENT_NAME/whatIf")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.WhatIfOperationResult{
+			Status: to.Ptr("Succeeded"),
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	t.Run("Deploy", func(t *testing.T) {
+		result, err := ds.DeployToManagementGroup(
+			ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "DEPLOYMENT_NAME", *result.Name)
+		require.Equal(t, "eastus2", *deployRequest.Location)
+		require.Equal(t, armresources.DeploymentModeIncremental, *deployRequest.Properties.Mode)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		result, err := ds.GetManagementGroupDeployment(ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "DEPLOYMENT_NAME")
+		require.NoError(t, err)
+		require.Equal(t, *deployment.ID, *result.ID)
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		_, err := ds.GetManagementGroupDeployment(ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "MISSING")
+		require.ErrorIs(t, err, ErrDeploymentNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		results, err := ds.ListManagementGroupDeployments(ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+	})
+
+	t.Run("WhatIf", func(t *testing.T) {
+		result, err := ds.WhatIfDeployToManagementGroup(
+			ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "Succeeded", *result.Status)
+	})
+}
+
+func Test_ManagementGroupDeployments_DeployOptions(t *testing.T) {
+	deploymentPath := "/providers/Microsoft.Management/managementGroups/MANAGEMENT_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+	statusUrl := "https://management.azure.com/operationStatuses/1"
+
+	mockDeployment := func(
+		mockContext *mocks.MockContext,
+		state armresources.ProvisioningState,
+		statuses ...string,
+	) *armresources.ScopedDeployment {
+		deployRequest := &armresources.ScopedDeployment{}
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			require.NoError(t, json.NewDecoder(request.Body).Decode(deployRequest))
+			response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(armresources.ProvisioningStateAccepted),
+				},
+			})
+			response.Header.Set("Azure-AsyncOperation", statusUrl)
+			return response, err
+		})
+
+		var polls atomic.Int32
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && request.URL.String() == statusUrl
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			poll := min(int(polls.Add(1))-1, len(statuses)-1)
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": statuses[poll]})
+		})
+
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Tags: map[string]*string{"existing": to.Ptr("value")},
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(state),
+				},
+			})
+		})
+
+		return deployRequest
+	}
+
+	t.Run("ProgressStateAndTags", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		deployRequest := mockDeployment(mockContext, armresources.ProvisioningStateSucceeded, "Running", "Succeeded")
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath+"/operations")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+				Value: []*armresources.DeploymentOperation{{OperationID: to.Ptr("1")}},
+			})
+		})
+
+		states := []armresources.ProvisioningState{}
+		reported := 0
+		ds := newTestDeployments(mockContext)
+		_, err := ds.DeployToManagementGroup(
+			*mockContext.Context, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil,
+			map[string]*string{"azd-env-name": to.Ptr("dev")},
+			&DeployOptions{
+				MergeTags: true,
+				Progress: func(operations []*armresources.DeploymentOperation) error {
+					require.Len(t, operations, 1)
+					reported++
+					return nil
+				},
+				StateChanged: func(state armresources.ProvisioningState, timestamp time.Time) {
+					states = append(states, state)
+				},
+			})
+		require.NoError(t, err)
+
+		require.Equal(t, map[string]*string{"azd-env-name": to.Ptr("dev"), "existing": to.Ptr("value")}, deployRequest.Tags)
+		require.Equal(t, []armresources.ProvisioningState{
+			armresources.ProvisioningStateAccepted,
+			armresources.ProvisioningStateRunning,
+			armresources.ProvisioningStateSucceeded,
+		}, states)
+		require.Positive(t, reported)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockDeployment(mockContext, armresources.ProvisioningStateRunning, "Running")
+
+		cancelled := false
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentPath+"/cancel")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			cancelled = true
+			return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+		})
+
+		ds := newTestDeployments(mockContext)
+		_, err := ds.DeployToManagementGroup(
+			*mockContext.Context, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil,
+			&DeployOptions{Timeout: 50 * time.Millisecond})
+		require.ErrorIs(t, err, ErrDeploymentTimeout)
+		require.True(t, cancelled)
+	})
+}
+
+func Test_Deploy_LocationRequired(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	deployed := mockDeployToSubscription(mockContext, "DEPLOYMENT_NAME")
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	t.Run("Empty", func(t *testing.T) {
+		_, err := ds.DeployToSubscription(ctx, "SUBSCRIPTION_ID", "", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+
+		_, err = ds.WhatIfDeployToSubscription(ctx, "SUBSCRIPTION_ID", "", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+
+		_, err = ds.ValidateDeployToSubscription(ctx, "SUBSCRIPTION_ID", "", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+
+		_, err = ds.DeployToManagementGroup(
+			ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+
+		_, err = ds.WhatIfDeployToManagementGroup(
+			ctx, "SUBSCRIPTION_ID", "MANAGEMENT_GROUP", "", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+
+		require.False(t, *deployed)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		_, err := ds.DeployToSubscription(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.NoError(t, err)
+		require.True(t, *deployed)
+	})
+}
+
+func Test_TenantDeployments(t *testing.T) {
+	deploymentPath := "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	var deployRequest armresources.ScopedDeployment
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && request.URL.Path == deploymentPath
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&deployRequest))
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			ID:   to.Ptr(deploymentPath),
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+		})
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost && request.URL.Path == deploymentPath+"/whatIf"
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.WhatIfOperationResult{
+			Status: to.Ptr("Succeeded"),
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	t.Run("Deploy", func(t *testing.T) {
+		result, err := ds.DeployToTenant(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "DEPLOYMENT_NAME", *result.Name)
+		require.Equal(t, "eastus2", *deployRequest.Location)
+		require.Equal(t, armresources.DeploymentModeIncremental, *deployRequest.Properties.Mode)
+	})
+
+	t.Run("WhatIf", func(t *testing.T) {
+		result, err := ds.WhatIfDeployToTenant(ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "Succeeded", *result.Status)
+	})
+
+	t.Run("LocationRequired", func(t *testing.T) {
+		_, err := ds.DeployToTenant(ctx, "SUBSCRIPTION_ID", "", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrLocationRequired)
+	})
+}
+
+func Test_TenantDeployments_DeployOptions(t *testing.T) {
+	deploymentPath := "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+	statusUrl := "https://management.azure.com/operationStatuses/1"
+
+	mockDeployment := func(
+		mockContext *mocks.MockContext,
+		state armresources.ProvisioningState,
+		statuses ...string,
+	) *armresources.ScopedDeployment {
+		deployRequest := &armresources.ScopedDeployment{}
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPut && request.URL.Path == deploymentPath
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			require.NoError(t, json.NewDecoder(request.Body).Decode(deployRequest))
+			response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(armresources.ProvisioningStateAccepted),
+				},
+			})
+			response.Header.Set("Azure-AsyncOperation", statusUrl)
+			return response, err
+		})
+
+		var polls atomic.Int32
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && request.URL.String() == statusUrl
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			poll := min(int(polls.Add(1))-1, len(statuses)-1)
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": statuses[poll]})
+		})
+
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && request.URL.Path == deploymentPath
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Tags: map[string]*string{"existing": to.Ptr("value")},
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(state),
+				},
+			})
+		})
+
+		return deployRequest
+	}
+
+	t.Run("ProgressStateAndTags", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		deployRequest := mockDeployment(mockContext, armresources.ProvisioningStateSucceeded, "Running", "Succeeded")
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && request.URL.Path == deploymentPath+"/operations"
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentOperationsListResult{
+				Value: []*armresources.DeploymentOperation{{OperationID: to.Ptr("1")}},
+			})
+		})
+
+		states := []armresources.ProvisioningState{}
+		reported := 0
+		ds := newTestDeployments(mockContext)
+		_, err := ds.DeployToTenant(
+			*mockContext.Context, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil,
+			map[string]*string{"azd-env-name": to.Ptr("dev")},
+			&DeployOptions{
+				MergeTags: true,
+				Progress: func(operations []*armresources.DeploymentOperation) error {
+					require.Len(t, operations, 1)
+					reported++
+					return nil
+				},
+				StateChanged: func(state armresources.ProvisioningState, timestamp time.Time) {
+					states = append(states, state)
+				},
+			})
+		require.NoError(t, err)
+
+		require.Equal(t, map[string]*string{"azd-env-name": to.Ptr("dev"), "existing": to.Ptr("value")}, deployRequest.Tags)
+		require.Equal(t, []armresources.ProvisioningState{
+			armresources.ProvisioningStateAccepted,
+			armresources.ProvisioningStateRunning,
+			armresources.ProvisioningStateSucceeded,
+		}, states)
+		require.Positive(t, reported)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockDeployment(mockContext, armresources.ProvisioningStateRunning, "Running")
+
+		cancelled := false
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && request.URL.Path == deploymentPath+"/cancel"
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			cancelled = true
+			return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+		})
+
+		ds := newTestDeployments(mockContext)
+		_, err := ds.DeployToTenant(
+			*mockContext.Context, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", testTemplate, nil, nil,
+			&DeployOptions{Timeout: 50 * time.Millisecond})
+		require.ErrorIs(t, err, ErrDeploymentTimeout)
+		require.True(t, cancelled)
+	})
+}
+
+func Test_ExportDeploymentTemplate(t *testing.T) {
+	subscriptionPath := "/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/"
+	resourceGroupPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	for _, path := range []string{subscriptionPath, resourceGroupPath} {
+		exportPath := path + "DEPLOYMENT_NAME/exportTemplate"
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, exportPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExportResult{
+				Template: map[string]any{
+					"contentVersion": "1.0.0.0",
+					"resources":      []any{},
+				},
+			})
+		})
+	}
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "MISSING/exportTemplate")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateEmptyHttpResponse(request, http.StatusNotFound)
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	t.Run("Subscription", func(t *testing.T) {
+		template, err := ds.ExportDeploymentTemplate(
+			ctx, DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID"}, "DEPLOYMENT_NAME")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"contentVersion":"1.0.0.0","resources":[]}`, string(template))
+	})
+
+	t.Run("ResourceGroup", func(t *testing.T) {
+		template, err := ds.ExportDeploymentTemplate(
+			ctx, DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID", ResourceGroupName: "RESOURCE_GROUP"}, "DEPLOYMENT_NAME")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"contentVersion":"1.0.0.0","resources":[]}`, string(template))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := ds.ExportDeploymentTemplate(ctx, DeploymentScope{SubscriptionId: "SUBSCRIPTION_ID"}, "MISSING")
+		require.ErrorIs(t, err, ErrDeploymentNotFound)
+	})
+}
+
+func Test_Deploy_TemplateLink(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	bodies := map[string]map[string]any{}
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return strings.Contains(request.URL.Path, "/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		var body struct {
+			Properties map[string]any `json:"properties"`
+		}
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+		bodies[request.URL.Path] = body.Properties
+
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+	options := &DeployOptions{
+		TemplateLink:   &armresources.TemplateLink{URI: to.Ptr("https://storage/main.json?sas")},
+		ParametersLink: &armresources.ParametersLink{URI: to.Ptr("https://storage/main.parameters.json?sas")},
+	}
+
+	t.Run("Linked", func(t *testing.T) {
+		_, err := ds.DeployToResourceGroup(
+			ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", nil, nil, nil, options)
+		require.NoError(t, err)
+
+		_, err = ds.WhatIfDeployToResourceGroup(
+			ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", nil, nil, options)
+		require.NoError(t, err)
+
+		require.Len(t, bodies, 2)
+		for path, properties := range bodies {
+			require.Equal(t, map[string]any{"uri": "https://storage/main.json?sas"}, properties["templateLink"], path)
+			require.Equal(t,
+				map[string]any{"uri": "https://storage/main.parameters.json?sas"}, properties["parametersLink"], path)
+			require.NotContains(t, properties, "template", path)
+			require.NotContains(t, properties, "parameters", path)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		_, err := ds.DeployToResourceGroup(
+			ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, options)
+		require.ErrorIs(t, err, ErrInvalidTemplateSource)
+
+		_, err = ds.WhatIfDeployToSubscription(
+			ctx, "SUBSCRIPTION_ID", "eastus2", "DEPLOYMENT_NAME", nil, azure.ArmParameters{
+				"location": {Value: "eastus2"},
+			}, options)
+		require.ErrorIs(t, err, ErrInvalidTemplateSource)
+	})
+}
+
+func Test_Deployments_UserAgent(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	var userAgent string
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		userAgent = request.Header.Get("User-Agent")
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+		})
+	})
+
+	ds := NewDeploymentsWithOptions(
+		mockContext.SubscriptionCredentialProvider,
+		mockContext.ArmClientOptions,
+		&DeploymentsOptions{UserAgent: "partner-pid-1234"},
+	)
+
+	_, err := ds.GetSubscriptionDeployment(*mockContext.Context, "SUBSCRIPTION_ID", "DEPLOYMENT_NAME")
+	require.NoError(t, err)
+	require.Contains(t, userAgent, "partner-pid-1234")
+}
+
+func Test_DeployedTemplateHash(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	timestamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Timestamp:         to.Ptr(timestamp),
+			},
+		})
+	})
+
+	exports := 0
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentPath+"/exportTemplate")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		exports++
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExportResult{
+			Template: map[string]any{
+				"contentVersion": fmt.Sprintf("%d.0.0.0", exports),
+				"resources":      []any{},
+			},
+		})
+	})
+
+	var hashedTemplate map[string]any
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPost &&
+			strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/calculateTemplateHash")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&hashedTemplate))
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.TemplateHashResult{
+			TemplateHash: to.Ptr(fmt.Sprintf("HASH-%s", hashedTemplate["contentVersion"])),
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	hash, err := ds.DeployedTemplateHash(ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME")
+	require.NoError(t, err)
+	require.Equal(t, "HASH-1.0.0.0", hash)
+	require.Equal(t, map[string]any{"contentVersion": "1.0.0.0", "resources": []any{}}, hashedTemplate)
+
+	// The hash is cached while the timestamp of the deployment is unchanged
+	hash, err = ds.DeployedTemplateHash(ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME")
+	require.NoError(t, err)
+	require.Equal(t, "HASH-1.0.0.0", hash)
+	require.Equal(t, 1, exports)
+
+	// Redeploying with the same deployment name changes the timestamp and the deployed template hash
+	timestamp = timestamp.Add(time.Hour)
+	hash, err = ds.DeployedTemplateHash(ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME")
+	require.NoError(t, err)
+	require.Equal(t, "HASH-2.0.0.0", hash)
+	require.Equal(t, 2, exports)
+}
+
+func Test_CalculateTemplateHash_Cache(t *testing.T) {
+	newMockContext := func(requests *atomic.Int32) *mocks.MockContext {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost &&
+				strings.HasSuffix(request.URL.Path, "/providers/Microsoft.Resources/calculateTemplateHash")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			body, err := io.ReadAll(request.Body)
+			require.NoError(t, err)
+
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.TemplateHashResult{
+				TemplateHash: to.Ptr(fmt.Sprintf("HASH-%d", len(body))),
+			})
+		})
+
+		return mockContext
+	}
+
+	otherTemplate := []byte(`{"contentVersion": "1.0.0.0", "resources": [], "outputs": {}}`)
+
+	t.Run("Cached", func(t *testing.T) {
+		requests := &atomic.Int32{}
+		mockContext := newMockContext(requests)
+		ds := newTestDeployments(mockContext)
+		ctx := *mockContext.Context
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := ds.CalculateTemplateHash(ctx, "SUBSCRIPTION_ID", testTemplate)
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		first, err := ds.CalculateTemplateHash(ctx, "SUBSCRIPTION_ID", testTemplate)
+		require.NoError(t, err)
+
+		// Concurrent calls may race before the first result is cached, later calls never reach ARM
+		cachedRequests := requests.Load()
+		_, err = ds.CalculateTemplateHash(ctx, "OTHER_SUBSCRIPTION_ID", testTemplate)
+		require.NoError(t, err)
+		require.Equal(t, cachedRequests, requests.Load())
+
+		other, err := ds.CalculateTemplateHash(ctx, "SUBSCRIPTION_ID", otherTemplate)
+		require.NoError(t, err)
+		require.Equal(t, cachedRequests+1, requests.Load())
+		require.NotEqual(t, *first.TemplateHash, *other.TemplateHash)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		requests := &atomic.Int32{}
+		mockContext := newMockContext(requests)
+		ds := NewDeploymentsWithOptions(
+			mockContext.SubscriptionCredentialProvider,
+			mockContext.ArmClientOptions,
+			&DeploymentsOptions{DisableTemplateHashCache: true},
+		)
+
+		for i := 0; i < 2; i++ {
+			_, err := ds.CalculateTemplateHash(*mockContext.Context, "SUBSCRIPTION_ID", testTemplate)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(2), requests.Load())
+	})
+}
+
+func Test_ListSubscriptionDeploymentsWithOptions(t *testing.T) {
+	deployments := []*armresources.DeploymentExtended{
+		testDeployment("deploy-1", "key-1", armresources.ProvisioningStateFailed),
+		testDeployment("deploy-2", "key-2", armresources.ProvisioningStateSucceeded),
+		testDeployment("deploy-3", "key-3", armresources.ProvisioningStateFailed),
+		testDeployment("deploy-4", "key-4", armresources.ProvisioningStateFailed),
+	}
+
+	var query url.Values
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet &&
+			strings.HasSuffix(request.URL.Path, "/subscriptions/SUBSCRIPTION_ID/providers/Microsoft.Resources/deployments/")
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		query = request.URL.Query()
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentListResult{
+			Value: deployments,
+		})
+	})
+
+	ds := newTestDeployments(mockContext)
+	ctx := *mockContext.Context
+
+	t.Run("Filter", func(t *testing.T) {
+		results, err := ds.ListSubscriptionDeploymentsWithOptions(ctx, "SUBSCRIPTION_ID", &ListDeploymentsOptions{
+			Filter:   to.Ptr("provisioningState eq 'Failed'"),
+			MaxCount: 2,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "provisioningState eq 'Failed'", query.Get("$filter"))
+		require.Equal(t, "2", query.Get("$top"))
+		require.Len(t, results, 2)
+	})
+
+	t.Run("Predicate", func(t *testing.T) {
+		results, err := ds.ListSubscriptionDeploymentsWithOptions(ctx, "SUBSCRIPTION_ID", &ListDeploymentsOptions{
+			Predicate: func(deployment *armresources.DeploymentExtended) bool {
+				return *deployment.Properties.ProvisioningState == armresources.ProvisioningStateFailed
+			},
+			MaxCount: 2,
+		})
+		require.NoError(t, err)
+		require.False(t, query.Has("$top"))
+		require.Len(t, results, 2)
+		require.Equal(t, "deploy-1", *results[0].Name)
+		require.Equal(t, "deploy-3", *results[1].Name)
+	})
+
+	t.Run("All", func(t *testing.T) {
+		results, err := ds.ListSubscriptionDeployments(ctx, "SUBSCRIPTION_ID")
+		require.NoError(t, err)
+		require.False(t, query.Has("$filter"))
+		require.Len(t, results, 4)
+	})
+}
+
+func Test_Deploy_ContextErrors(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	mockRunningDeployment := func(mockContext *mocks.MockContext) {
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(armresources.ProvisioningStateAccepted),
+				},
+			})
+			response.Header.Set("Azure-AsyncOperation", "https://management.azure.com/operationStatuses/1")
+			return response, err
+		})
+
+		// The deployment never completes
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, "/operationStatuses/1")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": "Running"})
+		})
+	}
+
+	t.Run("Timeout", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockRunningDeployment(mockContext)
+		ds := newTestDeployments(mockContext)
+
+		ctx, cancel := context.WithTimeout(*mockContext.Context, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := ds.DeployToResourceGroup(
+			ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrDeploymentTimeout)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, ErrDeploymentCanceled)
+		require.Contains(t, err.Error(), "'DEPLOYMENT_NAME' after")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockRunningDeployment(mockContext)
+		ds := newTestDeployments(mockContext)
+
+		ctx, cancel := context.WithCancel(*mockContext.Context)
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		_, err := ds.DeployToResourceGroup(
+			ctx, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.ErrorIs(t, err, ErrDeploymentCanceled)
+		require.NotErrorIs(t, err, ErrDeploymentTimeout)
+	})
+
+	t.Run("DeploymentTimeout", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockRunningDeployment(mockContext)
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+				Name: to.Ptr("DEPLOYMENT_NAME"),
+				Properties: &armresources.DeploymentPropertiesExtended{
+					ProvisioningState: to.Ptr(armresources.ProvisioningStateRunning),
+				},
+			})
+		})
+
+		cancelled := false
+		mockContext.HttpClient.When(func(request *http.Request) bool {
+			return request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, deploymentPath+"/cancel")
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			cancelled = true
+			return mocks.CreateEmptyHttpResponse(request, http.StatusNoContent)
+		})
+
+		ds := newTestDeployments(mockContext)
+
+		_, err := ds.DeployToResourceGroup(
+			*mockContext.Context,
+			"SUBSCRIPTION_ID",
+			"RESOURCE_GROUP",
+			"DEPLOYMENT_NAME",
+			testTemplate,
+			nil,
+			nil,
+			&DeployOptions{Timeout: 50 * time.Millisecond},
+		)
+		require.ErrorIs(t, err, ErrDeploymentTimeout)
+		require.Contains(t, err.Error(), "'DEPLOYMENT_NAME' did not complete within 50ms and was canceled")
+		require.True(t, cancelled)
+	})
+
+	t.Run("ArmFailure", func(t *testing.T) {
+		require.NoError(t, deploymentContextError(nil, "DEPLOYMENT_NAME", time.Second))
+
+		armErr := errors.New("deployment failed")
+		require.Equal(t, armErr, deploymentContextError(armErr, "DEPLOYMENT_NAME", time.Second))
+	})
+}
+
+func Test_Deploy_LongRunningOperation(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+
+	t.Run("Succeeded", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.HttpClient.RegisterLRO(http.MethodPut, deploymentPath, "Succeeded", armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+				Outputs:           map[string]any{"endpoint": map[string]any{"type": "String", "value": "https://api"}},
+			},
+		})
+		ds := newTestDeployments(mockContext)
+
+		deployment, err := ds.DeployToResourceGroup(
+			*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, armresources.ProvisioningStateSucceeded, *deployment.Properties.ProvisioningState)
+		require.Contains(t, deployment.Properties.Outputs, "endpoint")
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.HttpClient.RegisterLRO(http.MethodPut, deploymentPath, "Failed", map[string]any{
+			"code":    "InvalidTemplateDeployment",
+			"message": "The template deployment failed because of policy violation.",
+		})
+		ds := newTestDeployments(mockContext)
+
+		_, err := ds.DeployToResourceGroup(
+			*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "InvalidTemplateDeployment: The template deployment failed because of policy violation.")
+	})
+}
+
+func Test_Deploy_PollRetryAfter(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+	statusUrl := "https://management.azure.com/operationStatuses/1"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+		})
+		response.Header.Set("Azure-AsyncOperation", statusUrl)
+		return response, err
+	})
+
+	var mu sync.Mutex
+	polls := []time.Time{}
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && request.URL.String() == statusUrl
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		polls = append(polls, time.Now())
+		if len(polls) == 1 {
+			// ARM asks clients under load to wait before polling again
+			response, err := mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": "Running"})
+			response.Header.Set("Retry-After", "1")
+			return response, err
+		}
+
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, map[string]any{"status": "Succeeded"})
+	})
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateSucceeded),
+			},
+		})
+	})
+
+	// The deployments of the test poll every millisecond, the Retry-After header overrides the poll frequency
+	ds := newTestDeployments(mockContext)
+	_, err := ds.DeployToResourceGroup(
+		*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil, nil)
+	require.NoError(t, err)
+
+	require.Len(t, polls, 2)
+	require.GreaterOrEqual(t, polls[1].Sub(polls[0]), time.Second)
+}
+
+func Test_Deploy_StateChanged(t *testing.T) {
+	deploymentPath := "/subscriptions/SUBSCRIPTION_ID/resourcegroups/RESOURCE_GROUP" +
+		"/providers/Microsoft.Resources/deployments/DEPLOYMENT_NAME"
+	statusUrl := "https://management.azure.com/operationStatuses/1"
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut && strings.HasSuffix(request.URL.Path, deploymentPath)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		response, err := mocks.CreateHttpResponseWithBody(request, http.StatusCreated, armresources.DeploymentExtended{
+			Name: to.Ptr("DEPLOYMENT_NAME"),
+			Properties: &armresources.DeploymentPropertiesExtended{
+				ProvisioningState: to.Ptr(armresources.ProvisioningStateAccepted),
+			},
+		})
+		response.Header.Set("Azure-AsyncOperation", statusUrl)
+		return response, err
+	})
+
+	// The state is reported once per change, not once per poll
+	statuses := []map[string]any{
+		{"status": "Running"},
+		{"status": "Running"},
+		{"status": "Running"},
+		{
+			"status": "Failed",
+			"error": map[string]any{
+				"code":    "InvalidTemplateDeployment",
+				"message": "The template deployment failed because of policy violation.",
+			},
+		},
+	}
+	var polls atomic.Int32
+	mockContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && request.URL.String() == statusUrl
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		poll := min(int(polls.Add(1))-1, len(statuses)-1)
+		return mocks.CreateHttpResponseWithBody(request, http.StatusOK, statuses[poll])
+	})
+
+	// State changes are timestamped with the clock of the service
+	mockContext.AdvanceTime(time.Hour)
+	states := []armresources.ProvisioningState{}
+	ds := newTestDeployments(mockContext)
+	ds.clock = mockContext.Clock
+	_, err := ds.DeployToResourceGroup(
+		*mockContext.Context, "SUBSCRIPTION_ID", "RESOURCE_GROUP", "DEPLOYMENT_NAME", testTemplate, nil, nil,
+		&DeployOptions{
+			StateChanged: func(state armresources.ProvisioningState, timestamp time.Time) {
+				require.Equal(t, time.Unix(3600, 0), timestamp)
+				states = append(states, state)
+			},
+		})
+
+	// The failure is still returned with its details after the failed state is reported
+	require.ErrorContains(t, err, "InvalidTemplateDeployment: The template deployment failed because of policy violation.")
+	require.Equal(t, []armresources.ProvisioningState{
+		armresources.ProvisioningStateAccepted,
+		armresources.ProvisioningStateRunning,
+		armresources.ProvisioningStateFailed,
+	}, states)
+}
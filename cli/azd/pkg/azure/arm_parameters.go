@@ -3,6 +3,8 @@
 
 package azure
 
+import "encoding/json"
+
 // ArmParameters is a map of arm template parameters to their configured values.
 type ArmParameters map[string]ArmParameterValue
 
@@ -17,4 +19,34 @@ type ArmParameterFile struct {
 // ArmParameterValue wraps the configured value for the parameter.
 type ArmParameterValue struct {
 	Value any `json:"value"`
+	// A reference to a Key Vault secret holding the value of the parameter, resolved by ARM at deployment time.
+	// When set, the value is ignored.
+	Reference *KeyVaultParameterReference `json:"reference,omitempty"`
+}
+
+// KeyVaultParameterReference is a reference to the Key Vault secret holding the value of a secure parameter:
+// https://learn.microsoft.com/azure/azure-resource-manager/templates/key-vault-parameter
+type KeyVaultParameterReference struct {
+	KeyVault      KeyVaultReference `json:"keyVault"`
+	SecretName    string            `json:"secretName"`
+	SecretVersion string            `json:"secretVersion,omitempty"`
+}
+
+// KeyVaultReference identifies a Key Vault by its resource ID.
+type KeyVaultReference struct {
+	Id string `json:"id"`
+}
+
+// MarshalJSON writes the parameter as either '{"value": ...}' or '{"reference": ...}', since ARM rejects parameters
+// that have both.
+func (v ArmParameterValue) MarshalJSON() ([]byte, error) {
+	if v.Reference != nil {
+		return json.Marshal(struct {
+			Reference *KeyVaultParameterReference `json:"reference"`
+		}{v.Reference})
+	}
+
+	return json.Marshal(struct {
+		Value any `json:"value"`
+	}{v.Value})
 }
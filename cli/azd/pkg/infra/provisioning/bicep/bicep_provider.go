@@ -428,19 +428,10 @@ func (p *BicepProvider) deploymentScope(deploymentScope azure.DeploymentScope) (
 	return nil, fmt.Errorf("unsupported scope: %s", deploymentScope)
 }
 
-// cArmDeploymentNameLengthMax is the maximum length of the name of a deployment in ARM.
-const cArmDeploymentNameLengthMax = 64
-
-// deploymentNameForEnv creates a name to use for the deployment object for a given environment. It appends the current
-// unix time to the environment name (separated by a hyphen) to provide a unique name for each deployment. If the resulting
-// name is longer than the ARM limit, the longest suffix of the name under the limit is returned.
+// deploymentNameForEnv creates a name to use for the deployment object for a given environment, see
+// azapi.GenerateDeploymentName.
 func deploymentNameForEnv(envName string, clock clock.Clock) string {
-	name := fmt.Sprintf("%s-%d", envName, clock.Now().Unix())
-	if len(name) <= cArmDeploymentNameLengthMax {
-		return name
-	}
-
-	return name[len(name)-cArmDeploymentNameLengthMax:]
+	return azapi.GenerateDeploymentName(envName, clock.Now())
 }
 
 // deploymentState returns the latests deployment if it is the same as the deployment within deploymentData or an error
@@ -21,7 +21,6 @@ import (
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockaccount"
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockazcli"
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockenv"
-	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -244,10 +243,6 @@ func registerContainerDependencies(mockContext *mocks.MockContext, env *environm
 		return mockazcli.NewAzCliFromMockContext(mockContext)
 	})
 
-	mockContext.Container.MustRegisterSingleton(func() clock.Clock {
-		return clock.NewMock()
-	})
-
 	mockContext.Container.MustRegisterSingleton(func() *cloud.Cloud {
 		return cloud.AzurePublic()
 	})
@@ -71,7 +71,7 @@ func (s *ResourceGroupDeployment) Deploy(
 	ctx context.Context, template azure.RawArmTemplate, parameters azure.ArmParameters, tags map[string]*string,
 ) (*armresources.DeploymentExtended, error) {
 	return s.deployments.DeployToResourceGroup(
-		ctx, s.subscriptionId, s.resourceGroupName, s.name, template, parameters, tags)
+		ctx, s.subscriptionId, s.resourceGroupName, s.name, template, parameters, tags, nil)
 }
 
 func (s *ResourceGroupDeployment) DeployPreview(
@@ -79,7 +79,7 @@ func (s *ResourceGroupDeployment) DeployPreview(
 	template azure.RawArmTemplate,
 	parameters azure.ArmParameters) (*armresources.WhatIfOperationResult, error) {
 	return s.deployments.WhatIfDeployToResourceGroup(
-		ctx, s.subscriptionId, s.resourceGroupName, s.name, template, parameters)
+		ctx, s.subscriptionId, s.resourceGroupName, s.name, template, parameters, nil)
 }
 
 // GetDeployment fetches the result of the most recent deployment.
@@ -204,7 +204,7 @@ func (s *SubscriptionDeployment) Location() string {
 func (s *SubscriptionDeployment) Deploy(
 	ctx context.Context, template azure.RawArmTemplate, parameters azure.ArmParameters, tags map[string]*string,
 ) (*armresources.DeploymentExtended, error) {
-	return s.deploymentsService.DeployToSubscription(ctx, s.subscriptionId, s.location, s.name, template, parameters, tags)
+	return s.deploymentsService.DeployToSubscription(ctx, s.subscriptionId, s.location, s.name, template, parameters, tags, nil)
 }
 
 // Deploy a given template with a set of parameters.
@@ -213,7 +213,7 @@ func (s *SubscriptionDeployment) DeployPreview(
 	template azure.RawArmTemplate,
 	parameters azure.ArmParameters) (*armresources.WhatIfOperationResult, error) {
 	return s.deploymentsService.WhatIfDeployToSubscription(
-		ctx, s.subscriptionId, s.location, s.name, template, parameters)
+		ctx, s.subscriptionId, s.location, s.name, template, parameters, nil)
 }
 
 // GetDeployment fetches the result of the most recent deployment.
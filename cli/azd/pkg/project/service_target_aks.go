@@ -311,7 +311,7 @@ func (t *aksTarget) deployManifests(
 	}
 
 	task.SetProgress(NewServiceProgress("Applying k8s manifests"))
-	err := t.kubectl.Apply(
+	results, err := t.kubectl.Apply(
 		ctx,
 		deploymentPath,
 		nil,
@@ -320,6 +320,10 @@ func (t *aksTarget) deployManifests(
 		return false, nil, fmt.Errorf("failed applying kube manifests: %w", err)
 	}
 
+	for _, result := range results {
+		log.Printf("applied k8s manifest '%s':\n%s", result.Path, strings.TrimSpace(result.Stdout))
+	}
+
 	deploymentName := serviceConfig.K8s.Deployment.Name
 	if deploymentName == "" {
 		deploymentName = serviceConfig.Name
@@ -640,21 +644,8 @@ func (t *aksTarget) ensureClusterContext(
 
 // Ensures the k8s namespace exists otherwise creates it
 func (t *aksTarget) ensureNamespace(ctx context.Context, namespace string) error {
-	namespaceResult, err := t.kubectl.CreateNamespace(
-		ctx,
-		namespace,
-		&kubectl.KubeCliFlags{
-			DryRun: kubectl.DryRunTypeClient,
-			Output: kubectl.OutputTypeYaml,
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed creating kube namespace: %w", err)
-	}
-
-	_, err = t.kubectl.ApplyWithStdIn(ctx, namespaceResult.Stdout, nil)
-	if err != nil {
-		return fmt.Errorf("failed applying kube namespace: %w", err)
+	if err := t.kubectl.EnsureNamespace(ctx, namespace, nil); err != nil {
+		return fmt.Errorf("failed ensuring kube namespace: %w", err)
 	}
 
 	return nil
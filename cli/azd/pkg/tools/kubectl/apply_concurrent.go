@@ -0,0 +1,58 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Applies the manifests, ordered by kind priority, with up to the concurrency limit of manifests applied at once.
+// The manifests of a priority tier are all applied before the next tier starts, so that ex. namespaces and CRDs exist
+// before the resources that use them. When any manifest of a tier fails, the following tiers are not applied and
+// the errors of all the failed manifests of the tier are returned together.
+func (cli *kubectlCli) applyManifestsConcurrently(
+	ctx context.Context,
+	manifests []manifestContent,
+	flags *KubeCliFlags,
+	crds *crdTracker,
+	concurrency int,
+) ([]*ApplyResult, error) {
+	results := []*ApplyResult{}
+
+	for _, tier := range manifestPriorityTiers(manifests) {
+		tierResults := make([]*ApplyResult, len(tier))
+		tierErrors := make([]error, len(tier))
+
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, concurrency)
+
+		for i, manifest := range tier {
+			wg.Add(1)
+			semaphore <- struct{}{}
+
+			go func(i int, manifest manifestContent) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+
+				tierResults[i], tierErrors[i] = cli.applyManifest(ctx, manifest, flags, crds)
+			}(i, manifest)
+		}
+
+		wg.Wait()
+
+		// Results keep the apply order of the manifests rather than the order the applies completed in
+		for _, result := range tierResults {
+			if result != nil {
+				results = append(results, result)
+			}
+		}
+
+		if err := errors.Join(tierErrors...); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,118 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Apply_Concurrency(t *testing.T) {
+	writeManifests := func(t *testing.T, manifests map[string]string) string {
+		tempDir := t.TempDir()
+		for name, kind := range manifests {
+			manifest := fmt.Sprintf("apiVersion: v1\nkind: %s\nmetadata:\n  name: %s\n", kind, name)
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, name+".yaml"), []byte(manifest), osutil.PermissionFile))
+		}
+
+		return tempDir
+	}
+
+	manifests := map[string]string{
+		"a-api":       "Deployment",
+		"b-worker":    "Deployment",
+		"c-web":       "Deployment",
+		"d-config":    "ConfigMap",
+		"e-settings":  "ConfigMap",
+		"f-namespace": "Namespace",
+	}
+
+	t.Run("TiersInOrder", func(t *testing.T) {
+		tempDir := writeManifests(t, manifests)
+
+		var mu sync.Mutex
+		applied := []string{}
+		inFlight := 0
+		maxInFlight := 0
+
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl apply -f")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			inFlight--
+			applied = append(applied, manifests[strings.TrimSuffix(filepath.Base(args.Args[2]), ".yaml")])
+
+			return exec.NewRunResult(0, "", ""), nil
+		})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		results, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{ApplyConcurrency: 2})
+		require.NoError(t, err)
+
+		// A tier is fully applied before the next one starts
+		require.Equal(t, []string{
+			"Namespace", "ConfigMap", "ConfigMap", "Deployment", "Deployment", "Deployment",
+		}, applied)
+		require.LessOrEqual(t, maxInFlight, 2)
+
+		// Results keep the apply order of the manifests
+		paths := []string{}
+		for _, result := range results {
+			paths = append(paths, filepath.Base(result.Path))
+		}
+		require.Equal(t, []string{
+			"f-namespace.yaml", "d-config.yaml", "e-settings.yaml", "a-api.yaml", "b-worker.yaml", "c-web.yaml",
+		}, paths)
+	})
+
+	t.Run("AggregatesTierErrors", func(t *testing.T) {
+		tempDir := writeManifests(t, manifests)
+
+		var mu sync.Mutex
+		applied := []string{}
+
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl apply -f")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			name := strings.TrimSuffix(filepath.Base(args.Args[2]), ".yaml")
+
+			mu.Lock()
+			applied = append(applied, name)
+			mu.Unlock()
+
+			if manifests[name] == "ConfigMap" {
+				return exec.NewRunResult(1, "", ""), fmt.Errorf("configmap '%s' is invalid", name)
+			}
+
+			return exec.NewRunResult(0, "", ""), nil
+		})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		results, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{ApplyConcurrency: 4})
+		require.ErrorContains(t, err, "configmap 'd-config' is invalid")
+		require.ErrorContains(t, err, "configmap 'e-settings' is invalid")
+
+		// The deployments are not applied after the config maps fail
+		require.ElementsMatch(t, []string{"f-namespace", "d-config", "e-settings"}, applied)
+		require.Len(t, results, 1)
+	})
+}
@@ -0,0 +1,41 @@
+package kubectl
+
+import (
+	"strings"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// The result of a kubectl apply command
+type ApplyResult struct {
+	exec.RunResult
+	// The manifest file or directory that was applied, only set by Apply
+	Path string
+	// Warnings reported by kubectl on stderr for a successful apply, ex. usage of deprecated API versions
+	Warnings []string
+}
+
+func newApplyResult(res exec.RunResult) *ApplyResult {
+	return &ApplyResult{
+		RunResult: res,
+		Warnings:  parseWarnings(res.Stderr),
+	}
+}
+
+// Parses the 'Warning: ...' lines that kubectl writes to stderr
+func parseWarnings(stderr string) []string {
+	warnings := []string{}
+
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < len("warning:") || !strings.EqualFold(line[:len("warning:")], "warning:") {
+			continue
+		}
+
+		if warning := strings.TrimSpace(line[len("warning:"):]); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,49 @@
+package kubectl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Apply_Warnings(t *testing.T) {
+	stdout := "deployment.apps/api configured\nservice/api unchanged\n"
+	stderr := strings.Join([]string{
+		"Warning: autoscaling/v2beta2 HorizontalPodAutoscaler is deprecated in v1.23+, unavailable in v1.26+",
+		"W0101 00:00:00.000000 1 client.go:1] not a kubectl warning line",
+		"warning: resource deployments/api is missing the kubectl.kubernetes.io/last-applied-configuration annotation",
+		"",
+	}, "\n")
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply")
+	}).Respond(exec.NewRunResult(0, stdout, stderr))
+
+	cli := NewKubectl(mockContext.CommandRunner)
+
+	t.Run("StdIn", func(t *testing.T) {
+		result, err := cli.ApplyWithStdIn(*mockContext.Context, "input", nil)
+		require.NoError(t, err)
+		require.Equal(t, stdout, result.Stdout)
+		require.Equal(t, []string{
+			"autoscaling/v2beta2 HorizontalPodAutoscaler is deprecated in v1.23+, unavailable in v1.26+",
+			"resource deployments/api is missing the kubectl.kubernetes.io/last-applied-configuration annotation",
+		}, result.Warnings)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		result, err := cli.ApplyWithFile(*mockContext.Context, "file.yaml", nil)
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 2)
+	})
+}
+
+func Test_ParseWarnings_NoWarnings(t *testing.T) {
+	require.Empty(t, parseWarnings(""))
+	require.Empty(t, parseWarnings("error: something else"))
+}
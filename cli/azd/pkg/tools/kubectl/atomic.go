@@ -0,0 +1,173 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// The metadata fields populated by the server, which must be removed before a prior state can be applied again
+var serverMetadataFields = []string{
+	"resourceVersion",
+	"uid",
+	"creationTimestamp",
+	"generation",
+	"managedFields",
+	"selfLink",
+}
+
+// Collects the journal entries of an atomic apply so the resources can be rolled back, forwarding each entry to the
+// journal configured by the caller when set
+type rollbackJournal struct {
+	next    ManifestJournal
+	entries []JournalEntry
+	mu      sync.Mutex
+}
+
+func (j *rollbackJournal) Record(ctx context.Context, entry JournalEntry) error {
+	j.mu.Lock()
+	j.entries = append(j.entries, entry)
+	j.mu.Unlock()
+
+	if j.next != nil {
+		return j.next.Record(ctx, entry)
+	}
+
+	return nil
+}
+
+// Applies the manifests at the specified path and, when any of them fails, rolls back the resources already applied
+// so the cluster returns to its state before the apply. Resources that did not exist are deleted and resources that
+// existed are restored to their prior state. A directory containing a kustomization is rendered with kustomize first, and
+// the rendered resources are journaled and applied together.
+//
+// The rollback is best effort: it is not transactional on the server, resources changed by another process while
+// applying are overwritten with their prior state, and side effects of the applied resources (ex. pods started by a
+// deployment) are not undone. The returned error includes any rollback failures.
+func (cli *kubectlCli) ApplyAtomic(ctx context.Context, path string, flags *KubeCliFlags) error {
+	// The prior state of pruned resources is not journaled, so they could not be restored
+	if flags != nil && flags.Prune {
+		return errors.New("pruning is not supported by atomic apply")
+	}
+
+	journal := &rollbackJournal{}
+	atomicFlags := &KubeCliFlags{}
+	if flags != nil {
+		*atomicFlags = *flags
+		journal.next = flags.Journal
+	}
+	atomicFlags.Journal = journal
+
+	var applyErr error
+	if isKustomization(path) {
+		applyErr = cli.applyRenderedKustomization(ctx, path, atomicFlags)
+	} else {
+		_, applyErr = cli.Apply(ctx, path, atomicFlags)
+	}
+	if applyErr == nil {
+		return nil
+	}
+
+	// Nothing is persisted by a dry run, so there is nothing to roll back
+	if atomicFlags.DryRun != "" && atomicFlags.DryRun != DryRunTypeNone {
+		return applyErr
+	}
+
+	if err := cli.rollback(ctx, journal.entries); err != nil {
+		return fmt.Errorf("%w, rolling back applied resources failed, %w", applyErr, err)
+	}
+
+	return fmt.Errorf("%w, applied resources were rolled back", applyErr)
+}
+
+// Renders the kustomization at the specified path and applies the rendered resources, so that each of them is
+// journaled before it is applied. 'kubectl apply -k' would apply the resources without journaling them.
+func (cli *kubectlCli) applyRenderedKustomization(ctx context.Context, path string, flags *KubeCliFlags) error {
+	res, err := cli.Exec(ctx, nil, "kustomize", path)
+	if err != nil {
+		return fmt.Errorf("failed rendering kustomization '%s', %w", path, err)
+	}
+
+	if flags.ValidateFirst && (flags.DryRun == "" || flags.DryRun == DryRunTypeNone) {
+		validateFlags := *flags
+		validateFlags.DryRun = DryRunTypeServer
+		validateFlags.Journal = nil
+
+		if _, err := cli.ApplyWithStdIn(ctx, res.Stdout, &validateFlags); err != nil {
+			return fmt.Errorf("validating kustomization failed, nothing was applied:\n%w", err)
+		}
+	}
+
+	if err := cli.journalPriorState(ctx, res.Stdout, flags); err != nil {
+		return err
+	}
+
+	if _, err := cli.ApplyWithStdIn(ctx, res.Stdout, flags); err != nil {
+		return fmt.Errorf("failed applying kustomization '%s', %w", path, err)
+	}
+
+	return nil
+}
+
+// Restores the prior state of the journaled resources, in the reverse order they were applied.
+// Rollback continues past failures so as many resources as possible are restored.
+func (cli *kubectlCli) rollback(ctx context.Context, entries []JournalEntry) error {
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		resource := Resource{
+			ApiVersion: entry.ApiVersion,
+			Kind:       entry.Kind,
+			Metadata:   ResourceMetadata{Name: entry.Name, Namespace: entry.Namespace},
+		}
+
+		if !entry.Existed {
+			_, err := cli.Exec(
+				ctx,
+				&KubeCliFlags{Namespace: entry.Namespace},
+				"delete", resourceTypeName(resource), entry.Name, "--ignore-not-found",
+			)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed deleting %s '%s', %w", entry.Kind, entry.Name, err))
+			}
+
+			continue
+		}
+
+		priorState, err := restorableState(entry.PriorState)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed reading prior state of %s '%s', %w", entry.Kind, entry.Name, err))
+			continue
+		}
+
+		if _, err := cli.ApplyWithStdIn(ctx, priorState, &KubeCliFlags{Namespace: entry.Namespace}); err != nil {
+			errs = append(errs, fmt.Errorf("failed restoring %s '%s', %w", entry.Kind, entry.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Removes the status and server populated metadata from the prior state of a resource
+func restorableState(priorState json.RawMessage) (string, error) {
+	var state map[string]any
+	if err := json.Unmarshal(priorState, &state); err != nil {
+		return "", err
+	}
+
+	delete(state, "status")
+	if metadata, ok := state["metadata"].(map[string]any); ok {
+		for _, field := range serverMetadataFields {
+			delete(metadata, field)
+		}
+	}
+
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+
+	return string(stateJson), nil
+}
@@ -0,0 +1,176 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ApplyAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: existing-app
+`,
+		"b.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: new-svc
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), osutil.PermissionFile))
+	}
+
+	existingState := `{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {"name": "existing-app", "resourceVersion": "42", "uid": "UID", "labels": {"version": "1"}},
+		"spec": {"replicas": 2},
+		"status": {"readyReplicas": 2}
+	}`
+
+	commands := []string{}
+	restored := ""
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment.v1.apps existing-app")
+	}).Respond(exec.NewRunResult(0, existingState, ""))
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get configmap new-config") ||
+			strings.Contains(command, "kubectl get service new-svc")
+	}).Respond(exec.NewRunResult(0, "", ""))
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		if args.Args[2] == "-" {
+			input, err := io.ReadAll(args.StdIn)
+			require.NoError(t, err)
+			restored = string(input)
+			commands = append(commands, "restore")
+			return exec.NewRunResult(0, "", ""), nil
+		}
+
+		commands = append(commands, "apply "+filepath.Base(args.Args[2]))
+		if filepath.Base(args.Args[2]) == "b.yaml" {
+			return exec.NewRunResult(1, "", "invalid service"), errors.New("invalid service")
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl delete")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.Equal(t, "test-namespace", args.Args[5])
+		commands = append(commands, "delete "+args.Args[2])
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	err := cli.ApplyAtomic(*mockContext.Context, tempDir, &KubeCliFlags{Namespace: "test-namespace"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid service")
+	require.Contains(t, err.Error(), "rolled back")
+
+	// Resources are rolled back in the reverse order they were applied, including the ones of the failed manifest
+	require.Equal(t, []string{
+		"apply a.yaml",
+		"apply b.yaml",
+		"delete new-svc",
+		"restore",
+		"delete new-config",
+	}, commands)
+
+	var state map[string]any
+	require.NoError(t, json.Unmarshal([]byte(restored), &state))
+	require.NotContains(t, state, "status")
+	require.Equal(t, map[string]any{
+		"name":   "existing-app",
+		"labels": map[string]any{"version": "1"},
+	}, state["metadata"])
+	require.Equal(t, map[string]any{"replicas": float64(2)}, state["spec"])
+}
+
+func Test_ApplyAtomic_Kustomization(t *testing.T) {
+	tempDir := t.TempDir()
+	kustomization := "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n  - config.yaml\n"
+	require.NoError(t, os.WriteFile(
+		filepath.Join(tempDir, "kustomization.yaml"), []byte(kustomization), osutil.PermissionFile))
+
+	rendered := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new-config
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: new-svc
+`
+
+	commands := []string{}
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl kustomize")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.Equal(t, []string{"kustomize", tempDir}, args.Args)
+		commands = append(commands, "kustomize")
+		return exec.NewRunResult(0, rendered, ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		commands = append(commands, "get "+args.Args[2])
+		return exec.NewRunResult(0, "", ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.Equal(t, []string{"apply", "-f", "-", "-n", "test-namespace"}, args.Args)
+		input, err := io.ReadAll(args.StdIn)
+		require.NoError(t, err)
+		require.Equal(t, rendered, string(input))
+
+		commands = append(commands, "apply")
+		return exec.NewRunResult(1, "", "invalid service"), errors.New("invalid service")
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl delete")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		commands = append(commands, "delete "+args.Args[2])
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	err := cli.ApplyAtomic(*mockContext.Context, tempDir, &KubeCliFlags{Namespace: "test-namespace"})
+	require.ErrorContains(t, err, "invalid service")
+	require.ErrorContains(t, err, "rolled back")
+
+	// The rendered resources are journaled before they are applied, so they are rolled back
+	require.Equal(t, []string{
+		"kustomize",
+		"get new-config",
+		"get new-svc",
+		"apply",
+		"delete new-svc",
+		"delete new-config",
+	}, commands)
+}
@@ -0,0 +1,100 @@
+package kubectl
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sethvargo/go-retry"
+)
+
+const kindCustomResourceDefinition = "CustomResourceDefinition"
+
+var (
+	// How long after applying a CRD an apply failing because the kind is not yet registered is retried
+	crdRetryWindow = 30 * time.Second
+	// The backoff used to retry applies that race ahead of the registration of a CRD
+	crdRetryBackoff = func() retry.Backoff {
+		return retry.WithMaxRetries(5, retry.NewExponential(time.Second))
+	}
+)
+
+// Errors returned by the API server when a custom resource is applied before its CRD is registered
+var crdNotReadyErrors = []string{
+	"no matches for kind",
+	"the server could not find the requested resource",
+}
+
+// Tracks when CRDs are applied so that custom resources which race ahead of the CRD registration can be retried
+type crdTracker struct {
+	lock        sync.Mutex
+	lastApplied time.Time
+}
+
+// Applies the manifest using the apply function. When the apply fails because a kind is not yet registered, and
+// a CRD was applied within the retry window, the apply is retried with backoff.
+func (t *crdTracker) apply(
+	ctx context.Context,
+	manifest string,
+	applyFn func() (*ApplyResult, error),
+) (*ApplyResult, error) {
+	var result *ApplyResult
+
+	err := retry.Do(ctx, crdRetryBackoff(), func(ctx context.Context) error {
+		var err error
+		result, err = applyFn()
+		if err != nil && isCrdNotReadyError(err) && t.withinRetryWindow() {
+			log.Printf("custom resource applied before its CRD was registered, retrying: %v", err)
+			return retry.RetryableError(err)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if containsCrd(manifest) {
+		t.lock.Lock()
+		t.lastApplied = time.Now()
+		t.lock.Unlock()
+	}
+
+	return result, nil
+}
+
+func (t *crdTracker) withinRetryWindow() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return !t.lastApplied.IsZero() && time.Since(t.lastApplied) <= crdRetryWindow
+}
+
+func isCrdNotReadyError(err error) bool {
+	message := err.Error()
+	for _, crdError := range crdNotReadyErrors {
+		if strings.Contains(message, crdError) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets whether the manifest defines any CRDs
+func containsCrd(manifest string) bool {
+	resources, err := parseManifestResources(manifest)
+	if err != nil {
+		return false
+	}
+
+	for _, resource := range resources {
+		if resource.Kind == kindCustomResourceDefinition {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/sethvargo/go-retry"
+	"github.com/stretchr/testify/require"
+)
+
+const testCrdManifest = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`
+
+const testWidgetManifest = `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: my-widget
+`
+
+func Test_Apply_CrdRetry(t *testing.T) {
+	originalBackoff := crdRetryBackoff
+	crdRetryBackoff = func() retry.Backoff {
+		return retry.WithMaxRetries(3, retry.NewConstant(time.Millisecond))
+	}
+	t.Cleanup(func() { crdRetryBackoff = originalBackoff })
+
+	tests := map[string]struct {
+		withCrd       bool
+		failures      int
+		expectError   bool
+		expectApplies int
+	}{
+		"TransientNoMatchesAfterCrd": {
+			withCrd:       true,
+			failures:      2,
+			expectApplies: 3,
+		},
+		"PersistentNoMatchesAfterCrd": {
+			withCrd:       true,
+			failures:      10,
+			expectError:   true,
+			expectApplies: 4,
+		},
+		"NoMatchesWithoutCrd": {
+			withCrd:       false,
+			failures:      1,
+			expectError:   true,
+			expectApplies: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if test.withCrd {
+				err := os.WriteFile(filepath.Join(tempDir, "01-crd.yaml"), []byte(testCrdManifest), osutil.PermissionFile)
+				require.NoError(t, err)
+			}
+			err := os.WriteFile(filepath.Join(tempDir, "02-widget.yaml"), []byte(testWidgetManifest), osutil.PermissionFile)
+			require.NoError(t, err)
+
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl apply -f") && strings.Contains(command, "01-crd.yaml")
+			}).Respond(exec.NewRunResult(0, "customresourcedefinition.apiextensions.k8s.io/widgets.example.com created", ""))
+
+			applies := 0
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl apply -f") && strings.Contains(command, "02-widget.yaml")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				applies++
+				if applies <= test.failures {
+					return exec.NewRunResult(1, "", ""), errors.New(
+						`error: resource mapping not found for name: "my-widget": ` +
+							`no matches for kind "Widget" in version "example.com/v1"`,
+					)
+				}
+
+				return exec.NewRunResult(0, "widget.example.com/my-widget created", ""), nil
+			})
+
+			cli := NewKubectl(mockContext.CommandRunner)
+			_, err = cli.Apply(*mockContext.Context, tempDir, nil)
+			if test.expectError {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "no matches for kind")
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.expectApplies, applies)
+		})
+	}
+}
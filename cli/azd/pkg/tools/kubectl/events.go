@@ -0,0 +1,56 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// A k8s event, ex. describing why a pod can't be scheduled or its image can't be pulled
+type Event struct {
+	Resource
+	// The type of the event, either 'Normal' or 'Warning'
+	Type string `json:"type"`
+	// A short machine readable reason, ex. 'FailedScheduling', 'BackOff' or 'OOMKilling'
+	Reason string `json:"reason"`
+	// A human readable description of the event
+	Message string `json:"message"`
+	// The resource the event is about
+	InvolvedObject EventObjectReference `json:"involvedObject"`
+	// The number of times the event occurred
+	Count          int        `json:"count"`
+	FirstTimestamp *time.Time `json:"firstTimestamp"`
+	LastTimestamp  *time.Time `json:"lastTimestamp"`
+	// The time the event was first observed, set instead of the first and last timestamps by newer event sources
+	EventTime *time.Time `json:"eventTime"`
+}
+
+// A reference to the resource an event is about
+type EventObjectReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// Gets the events within the namespace of the flags, ordered from the oldest to the most recent.
+// Use the field selector of the flags to get the events of a single object, ex. 'involvedObject.name=api'.
+func (cli *kubectlCli) GetEvents(ctx context.Context, flags *KubeCliFlags) ([]Event, error) {
+	getFlags := &KubeCliFlags{}
+	if flags != nil {
+		*getFlags = *flags
+	}
+	getFlags.Output = OutputTypeJson
+
+	res, err := cli.Exec(ctx, getFlags, "get", string(ResourceTypeEvent), "--sort-by=.lastTimestamp")
+	if err != nil {
+		return nil, fmt.Errorf("failed getting events, %w", err)
+	}
+
+	var events List[Event]
+	if err := json.Unmarshal([]byte(res.Stdout), &events); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling events JSON, %w", err)
+	}
+
+	return events.Items, nil
+}
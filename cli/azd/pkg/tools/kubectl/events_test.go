@@ -0,0 +1,55 @@
+package kubectl
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetEvents(t *testing.T) {
+	eventsJson, err := os.ReadFile("../../../test/testdata/k8s/parse/events.json")
+	require.NoError(t, err)
+
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get events")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = args
+		return exec.NewRunResult(0, string(eventsJson), ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	events, err := cli.GetEvents(*mockContext.Context, &KubeCliFlags{
+		Namespace:     "todo",
+		FieldSelector: "involvedObject.name=api-7c9d8f6b5d-x2k4q",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"get", "events", "--sort-by=.lastTimestamp",
+		"--field-selector=involvedObject.name=api-7c9d8f6b5d-x2k4q",
+		"-n", "todo",
+		"-o", "json",
+	}, runArgs.Args)
+
+	require.Len(t, events, 2)
+	require.Equal(t, "Warning", events[0].Type)
+	require.Equal(t, "FailedScheduling", events[0].Reason)
+	require.Equal(t, "0/2 nodes are available: 2 Insufficient cpu.", events[0].Message)
+	require.Equal(t, EventObjectReference{
+		Kind:      "Pod",
+		Name:      "api-7c9d8f6b5d-x2k4q",
+		Namespace: "todo",
+	}, events[0].InvolvedObject)
+
+	require.Equal(t, "BackOff", events[1].Reason)
+	require.Equal(t, 4, events[1].Count)
+	require.Equal(t, time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC), events[1].LastTimestamp.UTC())
+}
@@ -0,0 +1,146 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JournalEntry is the state of a k8s resource captured before it was applied
+type JournalEntry struct {
+	ApiVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Whether the resource existed on the server before it was applied
+	Existed bool `json:"existed"`
+	// The JSON server state of the resource before it was applied, empty when the resource did not exist
+	PriorState json.RawMessage `json:"priorState,omitempty"`
+}
+
+// ManifestJournal records the prior state of k8s resources so an apply can later be rolled back.
+// Resources that did not exist are recorded with Existed set to false and can be deleted to restore the prior state.
+type ManifestJournal interface {
+	Record(ctx context.Context, entry JournalEntry) error
+}
+
+type manifestJournalWriter struct {
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+// Creates a new ManifestJournal that writes each entry to the writer as a line of JSON
+func NewManifestJournal(writer io.Writer) ManifestJournal {
+	return &manifestJournalWriter{
+		writer: writer,
+	}
+}
+
+// Writes the journal entry as a single line of JSON
+func (j *manifestJournalWriter) Record(ctx context.Context, entry JournalEntry) error {
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed marshalling journal entry, %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.writer.Write(append(entryJson, '\n')); err != nil {
+		return fmt.Errorf("failed writing journal entry, %w", err)
+	}
+
+	return nil
+}
+
+// Records the current server state of every resource within the manifest to the journal configured in the flags
+func (cli *kubectlCli) journalPriorState(ctx context.Context, manifest string, flags *KubeCliFlags) error {
+	if flags == nil || flags.Journal == nil {
+		return nil
+	}
+
+	resources, err := parseManifestResources(manifest)
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range resources {
+		namespace := resource.Metadata.Namespace
+		if namespace == "" {
+			namespace = flags.Namespace
+		}
+
+		res, err := cli.Exec(
+			ctx,
+			&KubeCliFlags{Namespace: namespace, Output: OutputTypeJson},
+			"get", resourceTypeName(resource), resource.Metadata.Name, "--ignore-not-found",
+		)
+		if err != nil {
+			return fmt.Errorf("failed getting prior state of %s '%s', %w", resource.Kind, resource.Metadata.Name, err)
+		}
+
+		entry := JournalEntry{
+			ApiVersion: resource.ApiVersion,
+			Kind:       resource.Kind,
+			Name:       resource.Metadata.Name,
+			Namespace:  namespace,
+		}
+
+		// kubectl returns empty output when the resource doesn't exist and '--ignore-not-found' is set
+		if priorState := strings.TrimSpace(res.Stdout); priorState != "" {
+			entry.Existed = true
+			entry.PriorState = json.RawMessage(priorState)
+		}
+
+		if err := flags.Journal.Record(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parses the resource identity of each document within a multi-document yaml manifest
+func parseManifestResources(manifest string) ([]Resource, error) {
+	resources := []Resource{}
+	decoder := yaml.NewDecoder(bytes.NewBufferString(manifest))
+
+	for {
+		var resource Resource
+		err := decoder.Decode(&resource)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing manifest, %w", err)
+		}
+
+		// Skip empty documents
+		if resource.Kind == "" || resource.Metadata.Name == "" {
+			continue
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// Gets the fully qualified resource type name (ex. deployment.v1.apps) for use with kubectl commands
+func resourceTypeName(resource Resource) string {
+	kind := strings.ToLower(resource.Kind)
+	group, version, hasGroup := strings.Cut(resource.ApiVersion, "/")
+	if !hasGroup {
+		// Core API group resources (ex. v1) don't require qualification
+		return kind
+	}
+
+	return fmt.Sprintf("%s.%s.%s", kind, version, group)
+}
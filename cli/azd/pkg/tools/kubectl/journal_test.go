@@ -0,0 +1,88 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Apply_Journal(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: existing-app
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: new-svc
+  namespace: other
+`
+	existingState := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"existing-app"}}`
+
+	tempDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(tempDir, "app.yaml"), []byte(manifest), osutil.PermissionFile)
+	require.NoError(t, err)
+
+	applied := false
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment.v1.apps existing-app")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.False(t, applied, "prior state must be captured before apply")
+		require.Contains(t, args.Args, "test-namespace")
+		return exec.NewRunResult(0, existingState, ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get service new-svc")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.False(t, applied, "prior state must be captured before apply")
+		require.Contains(t, args.Args, "other")
+		return exec.NewRunResult(0, "", ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		applied = true
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	journalBuffer := &bytes.Buffer{}
+	cli := NewKubectl(mockContext.CommandRunner)
+	_, err = cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{
+		Namespace: "test-namespace",
+		Journal:   NewManifestJournal(journalBuffer),
+	})
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	entries := []JournalEntry{}
+	for _, line := range strings.Split(strings.TrimSpace(journalBuffer.String()), "\n") {
+		var entry JournalEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "Deployment", entries[0].Kind)
+	require.Equal(t, "existing-app", entries[0].Name)
+	require.Equal(t, "test-namespace", entries[0].Namespace)
+	require.True(t, entries[0].Existed)
+	require.JSONEq(t, existingState, string(entries[0].PriorState))
+
+	require.Equal(t, "Service", entries[1].Kind)
+	require.Equal(t, "new-svc", entries[1].Name)
+	require.Equal(t, "other", entries[1].Namespace)
+	require.False(t, entries[1].Existed)
+	require.Empty(t, entries[1].PriorState)
+}
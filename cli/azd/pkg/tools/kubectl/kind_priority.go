@@ -0,0 +1,103 @@
+package kubectl
+
+import (
+	"slices"
+	"sort"
+)
+
+// Kinds that other resources depend on, in the order they need to exist within the cluster.
+// Resources of any other kind, ex. workloads and custom resources, are ordered after all of these.
+var kindPriorities = []string{
+	"Namespace",
+	kindCustomResourceDefinition,
+	"PriorityClass",
+	"StorageClass",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"ConfigMap",
+	"Secret",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"Service",
+}
+
+// Gets the apply priority of the kind, lower priorities are applied first
+func kindPriority(kind string) int {
+	if index := slices.Index(kindPriorities, kind); index >= 0 {
+		return index
+	}
+
+	return len(kindPriorities)
+}
+
+// Sorts the resources in the order they should be applied, so that dependencies like namespaces, CRDs and config
+// are created before the resources that consume them. Resources of the same priority keep their original order.
+func applyOrder(resources []Resource) []Resource {
+	ordered := slices.Clone(resources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return kindPriority(ordered[i].Kind) < kindPriority(ordered[j].Kind)
+	})
+
+	return ordered
+}
+
+// Groups the manifests, already sorted in apply order, into tiers of manifests with the same kind priority
+func manifestPriorityTiers(manifests []manifestContent) [][]manifestContent {
+	tiers := [][]manifestContent{}
+	lastPriority := -1
+	for _, manifest := range manifests {
+		priority := manifestPriority(manifest.Content)
+		if len(tiers) == 0 || priority != lastPriority {
+			tiers = append(tiers, []manifestContent{})
+			lastPriority = priority
+		}
+
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], manifest)
+	}
+
+	return tiers
+}
+
+// Sorts the resources in the order they should be deleted, which is the reverse of the apply order.
+// Custom resources and workloads are removed before their CRDs and namespaces, which otherwise hang while terminating.
+func deleteOrder(resources []Resource) []Resource {
+	ordered := applyOrder(resources)
+	slices.Reverse(ordered)
+
+	return ordered
+}
+
+// Gets the apply priority of a manifest, which is the lowest priority of the resources it defines.
+// Manifests that cannot be parsed get the lowest priority and are left to kubectl to report the error.
+func manifestPriority(manifest string) int {
+	resources, err := parseManifestResources(manifest)
+	if err != nil {
+		return len(kindPriorities)
+	}
+
+	priority := len(kindPriorities)
+	for _, resource := range resources {
+		priority = min(priority, kindPriority(resource.Kind))
+	}
+
+	return priority
+}
+
+// Sorts the manifests in the order they should be applied based on the kinds of the resources they define.
+// Manifests of the same priority keep their original order.
+func manifestApplyOrder(manifests []manifestContent) []manifestContent {
+	priorities := make(map[string]int, len(manifests))
+	for _, manifest := range manifests {
+		priorities[manifest.Path] = manifestPriority(manifest.Content)
+	}
+
+	ordered := slices.Clone(manifests)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priorities[ordered[i].Path] < priorities[ordered[j].Path]
+	})
+
+	return ordered
+}
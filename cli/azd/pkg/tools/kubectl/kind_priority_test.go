@@ -0,0 +1,141 @@
+package kubectl
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KindOrder(t *testing.T) {
+	resources := []Resource{
+		{Kind: "Deployment", Metadata: ResourceMetadata{Name: "api"}},
+		{Kind: "Widget", Metadata: ResourceMetadata{Name: "widget"}},
+		{Kind: "ConfigMap", Metadata: ResourceMetadata{Name: "config"}},
+		{Kind: "Service", Metadata: ResourceMetadata{Name: "api"}},
+		{Kind: "CustomResourceDefinition", Metadata: ResourceMetadata{Name: "widgets.example.com"}},
+		{Kind: "Secret", Metadata: ResourceMetadata{Name: "secret"}},
+		{Kind: "Namespace", Metadata: ResourceMetadata{Name: "app"}},
+	}
+
+	kinds := func(resources []Resource) []string {
+		result := []string{}
+		for _, resource := range resources {
+			result = append(result, resource.Kind)
+		}
+		return result
+	}
+
+	applied := applyOrder(resources)
+	require.Equal(t, []string{
+		"Namespace", "CustomResourceDefinition", "ConfigMap", "Secret", "Service", "Deployment", "Widget",
+	}, kinds(applied))
+
+	deleted := deleteOrder(resources)
+	expected := slices.Clone(applied)
+	slices.Reverse(expected)
+	require.Equal(t, expected, deleted)
+
+	// The input is not modified
+	require.Equal(t, "Deployment", resources[0].Kind)
+}
+
+func Test_DeleteManifests(t *testing.T) {
+	tempDir := t.TempDir()
+
+	manifests := map[string]string{
+		"a-deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+`,
+		"b-widget.yaml": `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: widgets
+`,
+		"c-namespace.yaml": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`,
+	}
+	for name, content := range manifests {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), osutil.PermissionFile))
+	}
+
+	deleted := [][]string{}
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl delete")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		deleted = append(deleted, args.Args)
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	err := cli.DeleteManifests(*mockContext.Context, tempDir, &KubeCliFlags{Namespace: "app"})
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"delete", "widget.v1.example.com", "widget", "--ignore-not-found", "-n", "widgets"},
+		{"delete", "deployment.v1.apps", "api", "--ignore-not-found", "-n", "app"},
+		{"delete", "customresourcedefinition.v1.apiextensions.k8s.io", "widgets.example.com", "--ignore-not-found", "-n", "app"},
+		{"delete", "namespace", "app", "--ignore-not-found", "-n", "app"},
+	}, deleted)
+}
+
+func Test_Apply_KindOrder(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"a-deployment.yaml":           "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n",
+		"b-config.yaml":               "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"c-secret.tmpl.yaml":          "apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\n",
+		"d-service.yaml":              "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n",
+		filepath.Join("z", "ns.yaml"): "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: app\n",
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(tempDir, "z"), osutil.PermissionDirectory))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), osutil.PermissionFile))
+	}
+
+	applied := []string{}
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		if args.Args[2] == "-" {
+			input, err := io.ReadAll(args.StdIn)
+			require.NoError(t, err)
+			resources, err := parseManifestResources(string(input))
+			require.NoError(t, err)
+			applied = append(applied, resources[0].Metadata.Name)
+		} else {
+			applied = append(applied, filepath.Base(args.Args[2]))
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	_, err := cli.Apply(*mockContext.Context, tempDir, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"ns.yaml", "b-config.yaml", "secret", "d-service.yaml", "a-deployment.yaml"}, applied)
+}
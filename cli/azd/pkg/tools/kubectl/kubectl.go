@@ -3,15 +3,23 @@ package kubectl
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/azure/azure-dev/cli/azd/pkg/exec"
 	"github.com/azure/azure-dev/cli/azd/pkg/tools"
+	"github.com/benbjohnson/clock"
+	"gopkg.in/yaml.v3"
 )
 
 // Executes commands against the Kubernetes CLI
@@ -23,26 +31,135 @@ type KubectlCli interface {
 	SetEnv(env map[string]string)
 	// Sets the KUBECONFIG environment variable
 	SetKubeConfig(kubeConfig string)
-	// Applies one or more files from the specified path
-	Apply(ctx context.Context, path string, flags *KubeCliFlags) error
+	// Sets the path of the kubeconfig file passed with '--kubeconfig' to every command, which takes precedence over
+	// the KUBECONFIG environment variable. An empty path clears it.
+	SetKubeConfigPath(path string)
+	// Applies the manifest file, or all the manifest files within the directory, at the specified path and returns the
+	// result of each applied manifest. A directory containing a kustomization is applied with kustomize.
+	Apply(ctx context.Context, path string, flags *KubeCliFlags) ([]*ApplyResult, error)
+	// Renders the manifests at the specified path as they would be applied, without invoking kubectl
+	RenderManifests(ctx context.Context, path string, flags *KubeCliFlags) ([]RenderedManifest, error)
+	// Applies one or more files from the specified path, rolling back the applied resources when any of them fails
+	ApplyAtomic(ctx context.Context, path string, flags *KubeCliFlags) error
 	// Applies manifests from the specified input
-	ApplyWithStdIn(ctx context.Context, input string, flags *KubeCliFlags) (*exec.RunResult, error)
+	ApplyWithStdIn(ctx context.Context, input string, flags *KubeCliFlags) (*ApplyResult, error)
+	// Applies the manifests from the specified inputs together in a single invocation
+	ApplyMultiple(ctx context.Context, inputs []string, flags *KubeCliFlags) (*ApplyResult, error)
 	// Applies manifests from the specified file path
-	ApplyWithFile(ctx context.Context, filePath string, flags *KubeCliFlags) (*exec.RunResult, error)
+	ApplyWithFile(ctx context.Context, filePath string, flags *KubeCliFlags) (*ApplyResult, error)
 	// Views the current k8s configuration including available clusters, contexts & users
 	ConfigView(ctx context.Context, merge bool, flatten bool, flags *KubeCliFlags) (*exec.RunResult, error)
 	// Sets the k8s context to use for future CLI commands
 	ConfigUseContext(ctx context.Context, name string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Gets the k8s contexts of the configuration, ex. to choose the cluster to deploy to
+	ConfigGetContexts(ctx context.Context, flags *KubeCliFlags) ([]KubeContext, error)
+	// Gets the name of the current k8s context
+	ConfigCurrentContext(ctx context.Context) (string, error)
+	// Gets the namespace of the current k8s context, defaults to 'default' when the context doesn't set one
+	CurrentNamespace(ctx context.Context) (string, error)
 	// Creates a new k8s namespace with the specified name
 	CreateNamespace(ctx context.Context, name string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Creates the k8s namespace with the specified name when it doesn't already exist
+	EnsureNamespace(ctx context.Context, name string, flags *KubeCliFlags) error
 	// Executes a k8s CLI command from the specified arguments and flags
 	Exec(ctx context.Context, flags *KubeCliFlags, args ...string) (exec.RunResult, error)
+	// Creates a configmap from the specified 'key=value' literals
+	CreateConfigMapFromLiterals(ctx context.Context, name string, values []string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Creates a configmap from the specified file or directory
+	CreateConfigMapFromFile(ctx context.Context, name string, filePath string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Creates a TLS secret from the specified PEM encoded certificate and key files
+	CreateSecretTLS(
+		ctx context.Context,
+		name string,
+		certPath string,
+		keyPath string,
+		flags *KubeCliFlags,
+	) (*exec.RunResult, error)
+	// Creates a docker-registry secret used to pull images from a private container registry
+	CreateSecretDockerRegistry(
+		ctx context.Context,
+		name string,
+		server string,
+		username string,
+		password string,
+		email string,
+		flags *KubeCliFlags,
+	) (*exec.RunResult, error)
+	// Gets the resource of the specified type and name as parsed JSON, ErrResourceNotFound when it doesn't exist
+	Get(ctx context.Context, resourceType string, name string, flags *KubeCliFlags) (map[string]any, error)
+	// Gets the resources of the specified type within each of the namespaces, tagged with the namespace they were found in
+	GetAcrossNamespaces(
+		ctx context.Context,
+		resourceType string,
+		namespaces []string,
+		flags *KubeCliFlags,
+	) ([]ResourceRef, error)
 	// Gets the deployment rollout status
 	RolloutStatus(ctx context.Context, deploymentName string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Gets the deployment rollout state without waiting for the rollout to complete
+	GetRolloutState(ctx context.Context, deploymentName string, flags *KubeCliFlags) (*RolloutState, error)
+	// Restarts the pods of the deployment with a new rollout
+	RolloutRestart(ctx context.Context, deploymentName string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Rolls back the deployment to the specified revision, or to the previous revision when toRevision is zero
+	RolloutUndo(ctx context.Context, deploymentName string, toRevision int, flags *KubeCliFlags) (*exec.RunResult, error)
 	// Applies the manifests at the specified path using kustomize
 	ApplyWithKustomize(ctx context.Context, path string, flags *KubeCliFlags) error
+	// Patches the fields of the existing resource of the specified type and name
+	Patch(
+		ctx context.Context,
+		resourceType string,
+		name string,
+		patchType PatchType,
+		patch string,
+		flags *KubeCliFlags,
+	) (*exec.RunResult, error)
+	// Sets the labels of the existing resource, keys ending with '-' remove the label
+	Label(
+		ctx context.Context,
+		resourceType string,
+		name string,
+		labels map[string]string,
+		overwrite bool,
+		flags *KubeCliFlags,
+	) (*exec.RunResult, error)
+	// Sets the annotations of the existing resource, keys ending with '-' remove the annotation
+	Annotate(
+		ctx context.Context,
+		resourceType string,
+		name string,
+		annotations map[string]string,
+		overwrite bool,
+		flags *KubeCliFlags,
+	) (*exec.RunResult, error)
+	// Deletes the resource of the specified type and name
+	Delete(ctx context.Context, resourceType string, name string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Deletes the resources of the specified type matching the label selector
+	DeleteWithSelector(ctx context.Context, resourceType string, selector string, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Deletes the resources of the manifests at the specified path, in the reverse order they are applied
+	DeleteManifests(ctx context.Context, path string, flags *KubeCliFlags) error
+	// Deletes the resources matching the label selector that are not defined by the manifests at the specified path
+	Prune(ctx context.Context, path string, selector string, flags *KubeCliFlags, options *PruneOptions) ([]Resource, error)
+	// Gets a summary of the status of each pod within the namespace
+	GetPods(ctx context.Context, flags *KubeCliFlags) ([]PodSummary, error)
+	// Gets the events within the namespace, ordered from the oldest to the most recent
+	GetEvents(ctx context.Context, flags *KubeCliFlags) ([]Event, error)
+	// Gets the current CPU and memory usage of each pod within the namespace
+	TopPods(ctx context.Context, flags *KubeCliFlags) ([]PodMetrics, error)
+	// Gets the current CPU and memory usage of each node of the cluster
+	TopNodes(ctx context.Context, flags *KubeCliFlags) ([]NodeMetrics, error)
+	// Gets the logs of the pod
+	Logs(ctx context.Context, podName string, opts LogOptions, flags *KubeCliFlags) (*exec.RunResult, error)
+	// Streams the logs of all the pods of the deployment to the writer, prefixing each line with the pod name
+	LogsForDeployment(ctx context.Context, deploymentName string, follow bool, flags *KubeCliFlags, out io.Writer) error
+	// Forwards the local ports to the resource until the returned session is stopped
+	PortForward(ctx context.Context, resource string, ports []string, flags *KubeCliFlags) (PortForwardSession, error)
+	// Watches resources of the specified type and invokes the handler for each change until the context is canceled
+	Watch(ctx context.Context, resourceType ResourceType, flags *KubeCliFlags, handler WatchHandlerFn) error
 }
 
+// The namespace kubectl uses when the current context doesn't specify one
+const defaultNamespace = "default"
+
 type OutputType string
 
 const (
@@ -50,6 +167,18 @@ const (
 	OutputTypeYaml OutputType = "yaml"
 )
 
+// The format of a patch applied with kubectl patch
+type PatchType string
+
+const (
+	// Merged with the resource using the patch strategy of each field, ex. to merge lists by key. Defaults to this.
+	PatchTypeStrategic PatchType = "strategic"
+	// A JSON merge patch (RFC 7386), lists are replaced entirely
+	PatchTypeMerge PatchType = "merge"
+	// A JSON patch (RFC 6902), a list of operations applied in order
+	PatchTypeJson PatchType = "json"
+)
+
 type DryRunType string
 
 const (
@@ -68,6 +197,42 @@ type KubeCliFlags struct {
 	DryRun DryRunType
 	// The expected output, typically JSON or YAML
 	Output OutputType
+	// When true, all manifests are rendered as Go templates before being applied.
+	// Otherwise only '*.tmpl.yaml', '*.yaml.tmpl', '*.tmpl.json' and '*.json.tmpl' files are rendered.
+	RenderTemplates bool
+	// When true, rendering a template fails with the names of all the referenced environment variables that are not
+	// set, including the ones only referenced by conditions. Otherwise unset variables are rendered as "<no value>".
+	StrictEnvSubst bool
+	// The maximum number of manifests applied concurrently by Apply. Manifests are applied in tiers of the same kind
+	// priority, and a tier is fully applied before the next one starts. Defaults to applying manifests sequentially.
+	// Not used when pruning, which applies all the manifests in a single invocation.
+	ApplyConcurrency int
+	// When set, the current server state of each resource is recorded to the journal before it is applied
+	Journal ManifestJournal
+	// Whether to compare the resources requested by the manifests with the namespace resource quotas before applying
+	QuotaCheck QuotaCheckMode
+	// When true, commands don't fail for resources that don't exist. Only supported by get and delete commands.
+	IgnoreNotFound bool
+	// When true, manifests are applied by the API server instead of kubectl, which tracks field ownership and doesn't
+	// store the last-applied-configuration annotation. Only supported by apply commands.
+	ServerSide bool
+	// The name of the field manager recorded as the owner of the applied fields, only used with server-side apply
+	FieldManager string
+	// When true, server-side apply takes ownership of the fields it conflicts on with other field managers
+	ForceConflicts bool
+	// When true, resources matching the prune selector that are not defined by the applied manifests are deleted.
+	// Pruning is destructive so it requires a prune selector. Only supported by apply commands.
+	Prune bool
+	// The label selector of the resources considered for pruning, ex. 'app.kubernetes.io/managed-by=azd'
+	PruneSelector string
+	// When true, all the manifests are validated with a server-side dry run and only applied when all of them are
+	// valid, instead of failing mid-apply and leaving the cluster partially updated. Only supported by Apply.
+	ValidateFirst bool
+	// The maximum duration of each kubectl command, after which it is stopped and fails with ErrCommandTimeout.
+	// Defaults to zero, which means no timeout.
+	Timeout time.Duration
+	// Filters the resources by field values, ex. 'involvedObject.name=api'. Only supported by get commands.
+	FieldSelector string
 }
 
 // templateRoot is the structure of the template available within the test templates that can be used within k8s manifests.
@@ -82,6 +247,10 @@ type kubectlCli struct {
 	commandRunner exec.CommandRunner
 	env           map[string]string
 	cwd           string
+	// The kubeconfig file passed to every command, ex. when managing multiple clusters within the same run
+	kubeConfigPath string
+	// The clock used to get the current time, ex. for the age of resources, replaceable for deterministic tests
+	clock clock.Clock
 }
 
 // Creates a new K8s CLI instance
@@ -89,6 +258,7 @@ func NewKubectl(commandRunner exec.CommandRunner) KubectlCli {
 	return &kubectlCli{
 		commandRunner: commandRunner,
 		env:           map[string]string{},
+		clock:         clock.New(),
 	}
 }
 
@@ -151,6 +321,12 @@ func (cli *kubectlCli) SetKubeConfig(kubeConfig string) {
 	cli.env[KubeConfigEnvVarName] = kubeConfig
 }
 
+// Sets the path of the kubeconfig file passed with '--kubeconfig' to every command.
+// Unlike KUBECONFIG, '--kubeconfig' only supports a single file. When empty, KUBECONFIG is used.
+func (cli *kubectlCli) SetKubeConfigPath(path string) {
+	cli.kubeConfigPath = path
+}
+
 // Sets the current working directory
 func (cli *kubectlCli) Cwd(cwd string) {
 	cli.cwd = cwd
@@ -166,6 +342,66 @@ func (cli *kubectlCli) ConfigUseContext(ctx context.Context, name string, flags
 	return &res, nil
 }
 
+// Gets the k8s contexts of the configuration, with the cluster, user and namespace of each context
+func (cli *kubectlCli) ConfigGetContexts(ctx context.Context, flags *KubeCliFlags) ([]KubeContext, error) {
+	viewFlags := &KubeCliFlags{}
+	if flags != nil {
+		*viewFlags = *flags
+	}
+	viewFlags.Output = OutputTypeYaml
+
+	res, err := cli.Exec(ctx, viewFlags, "config", "view")
+	if err != nil {
+		return nil, fmt.Errorf("failed reading kubectl config: %w", err)
+	}
+
+	var config KubeConfig
+	if err := yaml.Unmarshal([]byte(res.Stdout), &config); err != nil {
+		return nil, fmt.Errorf("failed parsing kubectl config: %w", err)
+	}
+
+	contexts := make([]KubeContext, 0, len(config.Contexts))
+	for _, kubeContext := range config.Contexts {
+		if kubeContext != nil {
+			contexts = append(contexts, *kubeContext)
+		}
+	}
+
+	return contexts, nil
+}
+
+// Gets the name of the current k8s context, so callers can verify they point to the intended cluster before applying.
+// Fails when no current context is set.
+func (cli *kubectlCli) ConfigCurrentContext(ctx context.Context) (string, error) {
+	res, err := cli.Exec(ctx, nil, "config", "current-context")
+	if err != nil {
+		return "", fmt.Errorf("failed getting current kubectl context: %w", err)
+	}
+
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// Gets the namespace of the current k8s context, defaults to 'default' when the context doesn't set one
+func (cli *kubectlCli) CurrentNamespace(ctx context.Context) (string, error) {
+	res, err := cli.Exec(ctx, nil, "config", "view", "--minify")
+	if err != nil {
+		return "", fmt.Errorf("failed reading kubectl config: %w", err)
+	}
+
+	var config KubeConfig
+	if err := yaml.Unmarshal([]byte(res.Stdout), &config); err != nil {
+		return "", fmt.Errorf("failed parsing kubectl config: %w", err)
+	}
+
+	for _, kubeContext := range config.Contexts {
+		if kubeContext != nil && kubeContext.Name == config.CurrentContext && kubeContext.Context.Namespace != "" {
+			return kubeContext.Context.Namespace, nil
+		}
+	}
+
+	return defaultNamespace, nil
+}
+
 // Views the current k8s configuration including available clusters, contexts & users
 func (cli *kubectlCli) ConfigView(
 	ctx context.Context,
@@ -197,7 +433,7 @@ func (cli *kubectlCli) ConfigView(
 	return &res, nil
 }
 
-func (cli *kubectlCli) ApplyWithStdIn(ctx context.Context, input string, flags *KubeCliFlags) (*exec.RunResult, error) {
+func (cli *kubectlCli) ApplyWithStdIn(ctx context.Context, input string, flags *KubeCliFlags) (*ApplyResult, error) {
 	runArgs := exec.
 		NewRunArgs("kubectl", "apply", "-f", "-").
 		WithStdIn(strings.NewReader(input))
@@ -207,10 +443,38 @@ func (cli *kubectlCli) ApplyWithStdIn(ctx context.Context, input string, flags *
 		return nil, fmt.Errorf("kubectl apply -f: %w", err)
 	}
 
-	return &res, nil
+	return newApplyResult(res), nil
 }
 
-func (cli *kubectlCli) ApplyWithFile(ctx context.Context, filePath string, flags *KubeCliFlags) (*exec.RunResult, error) {
+// Applies the manifests from the specified inputs together in a single kubectl invocation, which reports a single
+// result. Each input is rendered as a template using the azd environment variables before the inputs are joined
+// into a multi-document manifest, so a template error reports the input it comes from.
+func (cli *kubectlCli) ApplyMultiple(ctx context.Context, inputs []string, flags *KubeCliFlags) (*ApplyResult, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("no manifests to apply")
+	}
+
+	strict := flags != nil && flags.StrictEnvSubst
+	manifests := make([]string, 0, len(inputs))
+	for i, input := range inputs {
+		source := fmt.Sprintf("manifest input %d", i)
+		k8sTemplate, err := template.New(source).Parse(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s, %w", source, err)
+		}
+
+		manifest, err := cli.executeTemplate(k8sTemplate, source, strict)
+		if err != nil {
+			return nil, err
+		}
+
+		manifests = append(manifests, strings.TrimSpace(manifest))
+	}
+
+	return cli.ApplyWithStdIn(ctx, strings.Join(manifests, "\n---\n"), flags)
+}
+
+func (cli *kubectlCli) ApplyWithFile(ctx context.Context, filePath string, flags *KubeCliFlags) (*ApplyResult, error) {
 	runArgs := exec.NewRunArgs("kubectl", "apply", "-f", filePath)
 
 	res, err := cli.executeCommandWithArgs(ctx, runArgs, flags)
@@ -218,30 +482,156 @@ func (cli *kubectlCli) ApplyWithFile(ctx context.Context, filePath string, flags
 		return nil, fmt.Errorf("kubectl apply -f: %w", err)
 	}
 
-	return &res, nil
+	return newApplyResult(res), nil
 }
 
-// Applies manifests from the specified input
-func (cli *kubectlCli) Apply(ctx context.Context, path string, flags *KubeCliFlags) error {
-	if err := cli.applyTemplates(ctx, path, flags); err != nil {
-		return fmt.Errorf("failed process templates, %w", err)
+// Applies the manifests at the specified path and returns the result of each applied manifest.
+// A directory containing a kustomization is applied with 'kubectl apply -k' instead.
+func (cli *kubectlCli) Apply(ctx context.Context, path string, flags *KubeCliFlags) ([]*ApplyResult, error) {
+	// Kustomize composes the manifests itself, so they are neither rendered as templates nor applied one by one
+	if isKustomization(path) {
+		if flags != nil && flags.ValidateFirst && (flags.DryRun == "" || flags.DryRun == DryRunTypeNone) {
+			validateFlags := *flags
+			validateFlags.DryRun = DryRunTypeServer
+			validateFlags.Prune = false
+
+			if _, err := cli.applyKustomization(ctx, path, &validateFlags); err != nil {
+				return nil, fmt.Errorf("validating kustomization failed, nothing was applied:\n%w", err)
+			}
+		}
+
+		result, err := cli.applyKustomization(ctx, path, flags)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*ApplyResult{result}, nil
 	}
 
-	return nil
+	if flags != nil && flags.QuotaCheck != QuotaCheckNone {
+		manifests, err := cli.readManifests(path, flags)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading manifests, %w", err)
+		}
+
+		if err := cli.checkResourceQuota(ctx, manifests, flags); err != nil {
+			return nil, err
+		}
+	}
+
+	// The results of the manifests applied before a failure are returned along with the error
+	results, err := cli.applyTemplates(ctx, path, flags, &crdTracker{})
+	if err != nil {
+		return results, fmt.Errorf("failed process templates, %w", err)
+	}
+
+	return results, nil
 }
 
 // Applies the manifests at the specified path using kustomize
 func (cli *kubectlCli) ApplyWithKustomize(ctx context.Context, path string, flags *KubeCliFlags) error {
+	_, err := cli.applyKustomization(ctx, path, flags)
+	return err
+}
+
+func (cli *kubectlCli) applyKustomization(ctx context.Context, path string, flags *KubeCliFlags) (*ApplyResult, error) {
 	runArgs := exec.NewRunArgs("kubectl", "apply", "-k", path)
 
-	_, err := cli.executeCommandWithArgs(ctx, runArgs, flags)
+	res, err := cli.executeCommandWithArgs(ctx, runArgs, flags)
+	if err != nil {
+		return nil, fmt.Errorf("failing running kubectl apply -k: %w", err)
+	}
+
+	result := newApplyResult(res)
+	result.Path = path
+
+	return result, nil
+}
+
+// The file names kubectl recognizes as the kustomization of a directory
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Gets whether the path is a directory containing a kustomization
+func isKustomization(path string) bool {
+	for _, fileName := range kustomizationFileNames {
+		if info, err := os.Stat(filepath.Join(path, fileName)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Deletes the resources of the manifests at the specified path, in the reverse order they are applied so that
+// resources are removed before the CRDs and namespaces they depend on. Resources that don't exist are ignored.
+func (cli *kubectlCli) DeleteManifests(ctx context.Context, path string, flags *KubeCliFlags) error {
+	manifests, err := cli.readManifests(path, flags)
 	if err != nil {
-		return fmt.Errorf("failing running kubectl apply -k: %w", err)
+		return fmt.Errorf("failed reading manifests, %w", err)
+	}
+
+	resources := []Resource{}
+	for _, manifest := range manifests {
+		manifestResources, err := parseManifestResources(manifest.Content)
+		if err != nil {
+			return fmt.Errorf("failed parsing manifest '%s', %w", manifest.Path, err)
+		}
+
+		resources = append(resources, manifestResources...)
+	}
+
+	for _, resource := range deleteOrder(resources) {
+		deleteFlags := &KubeCliFlags{IgnoreNotFound: true}
+		if flags != nil {
+			deleteFlags.Namespace = flags.Namespace
+			deleteFlags.DryRun = flags.DryRun
+		}
+		if resource.Metadata.Namespace != "" {
+			deleteFlags.Namespace = resource.Metadata.Namespace
+		}
+
+		if _, err := cli.Delete(ctx, resourceTypeName(resource), resource.Metadata.Name, deleteFlags); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Deletes the resource of the specified type and name
+func (cli *kubectlCli) Delete(
+	ctx context.Context,
+	resourceType string,
+	name string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(ctx, flags, "delete", resourceType, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed deleting %s '%s', %w", resourceType, name, err)
+	}
+
+	return &res, nil
+}
+
+// Deletes the resources of the specified type matching the label selector, ex. 'app=api'
+func (cli *kubectlCli) DeleteWithSelector(
+	ctx context.Context,
+	resourceType string,
+	selector string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, fmt.Errorf("a label selector is required to delete %s", resourceType)
+	}
+
+	res, err := cli.Exec(ctx, flags, "delete", resourceType, "-l", selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed deleting %s matching '%s', %w", resourceType, selector, err)
+	}
+
+	return &res, nil
+}
+
 // Creates a new k8s namespace with the specified name
 func (cli *kubectlCli) CreateNamespace(ctx context.Context, name string, flags *KubeCliFlags) (*exec.RunResult, error) {
 	args := []string{"create", "namespace", name}
@@ -254,6 +644,208 @@ func (cli *kubectlCli) CreateNamespace(ctx context.Context, name string, flags *
 	return &res, nil
 }
 
+// Creates the k8s namespace with the specified name when it doesn't already exist.
+// The namespace manifest is generated with a client dry-run and applied, so that an existing namespace is left as is
+// instead of failing with an AlreadyExists error.
+//
+// Only the namespace, dry-run and timeout flags are used, the other flags of a manifest apply (ex. pruning or
+// server-side apply) are not valid or don't apply to the namespace.
+func (cli *kubectlCli) EnsureNamespace(ctx context.Context, name string, flags *KubeCliFlags) error {
+	applyFlags := &KubeCliFlags{}
+	if flags != nil {
+		applyFlags.Namespace = flags.Namespace
+		applyFlags.DryRun = flags.DryRun
+		applyFlags.Timeout = flags.Timeout
+	}
+
+	createFlags := *applyFlags
+	createFlags.DryRun = DryRunTypeClient
+	createFlags.Output = OutputTypeYaml
+
+	namespaceResult, err := cli.CreateNamespace(ctx, name, &createFlags)
+	if err != nil {
+		return fmt.Errorf("failed creating namespace '%s' manifest, %w", name, err)
+	}
+
+	if _, err := cli.ApplyWithStdIn(ctx, namespaceResult.Stdout, applyFlags); err != nil {
+		return fmt.Errorf("failed applying namespace '%s', %w", name, err)
+	}
+
+	return nil
+}
+
+// Creates a configmap from the specified 'key=value' literals.
+// Set the dry-run and output flags to generate the configmap manifest without creating it.
+func (cli *kubectlCli) CreateConfigMapFromLiterals(
+	ctx context.Context,
+	name string,
+	values []string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	args := []string{"create", "configmap", name}
+	for _, value := range values {
+		args = append(args, fmt.Sprintf("--from-literal=%s", value))
+	}
+
+	res, err := cli.Exec(ctx, flags, args...)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl create configmap: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Creates a configmap from the specified file, or from each file of the specified directory.
+// Set the dry-run and output flags to generate the configmap manifest without creating it.
+func (cli *kubectlCli) CreateConfigMapFromFile(
+	ctx context.Context,
+	name string,
+	filePath string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(ctx, flags, "create", "configmap", name, fmt.Sprintf("--from-file=%s", filePath))
+	if err != nil {
+		return nil, fmt.Errorf("kubectl create configmap: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Creates a TLS secret from the specified PEM encoded certificate and key files, ex. for ingress TLS termination.
+// Set the dry-run and output flags to generate the secret manifest without creating it.
+func (cli *kubectlCli) CreateSecretTLS(
+	ctx context.Context,
+	name string,
+	certPath string,
+	keyPath string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(
+		ctx,
+		flags,
+		"create", "secret", "tls", name, fmt.Sprintf("--cert=%s", certPath), fmt.Sprintf("--key=%s", keyPath),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl create secret tls: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Creates a docker-registry secret used to pull images from a private container registry. The email is optional.
+// Set the dry-run and output flags to generate the secret manifest without creating it.
+func (cli *kubectlCli) CreateSecretDockerRegistry(
+	ctx context.Context,
+	name string,
+	server string,
+	username string,
+	password string,
+	email string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	args := []string{
+		"create", "secret", "docker-registry", name,
+		fmt.Sprintf("--docker-server=%s", server),
+		fmt.Sprintf("--docker-username=%s", username),
+		fmt.Sprintf("--docker-password=%s", password),
+	}
+	if email != "" {
+		args = append(args, fmt.Sprintf("--docker-email=%s", email))
+	}
+
+	// The password is redacted from the logged command line
+	runArgs := exec.NewRunArgsWithSensitiveData("kubectl", args, []string{password})
+
+	res, err := cli.executeCommandWithArgs(ctx, runArgs, flags)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl create secret docker-registry: %w", err)
+	}
+
+	return &res, nil
+}
+
+// Gets the resource of the specified type and name as parsed JSON, regardless of the output type of the flags.
+// Returns ErrResourceNotFound when the resource doesn't exist, see GetResource to parse into a typed struct.
+func (cli *kubectlCli) Get(
+	ctx context.Context,
+	resourceType string,
+	name string,
+	flags *KubeCliFlags,
+) (map[string]any, error) {
+	getFlags := &KubeCliFlags{}
+	if flags != nil {
+		*getFlags = *flags
+	}
+	getFlags.Output = OutputTypeJson
+
+	res, err := cli.Exec(ctx, getFlags, "get", resourceType, name)
+	if err != nil {
+		if isNotFoundError(res, err) {
+			return nil, fmt.Errorf("%s '%s', %w", resourceType, name, ErrResourceNotFound)
+		}
+
+		return nil, fmt.Errorf("failed getting %s '%s', %w", resourceType, name, err)
+	}
+
+	// kubectl returns empty output when the resource doesn't exist and '--ignore-not-found' is set
+	if strings.TrimSpace(res.Stdout) == "" {
+		return nil, fmt.Errorf("%s '%s', %w", resourceType, name, ErrResourceNotFound)
+	}
+
+	var resource map[string]any
+	if err := json.Unmarshal([]byte(res.Stdout), &resource); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling %s '%s' JSON, %w", resourceType, name, err)
+	}
+
+	return resource, nil
+}
+
+// Gets whether kubectl failed because the requested resource doesn't exist,
+// ex. 'Error from server (NotFound): deployments.apps "api" not found'
+func isNotFoundError(res exec.RunResult, err error) bool {
+	return strings.Contains(res.Stderr, "(NotFound)") || strings.Contains(err.Error(), "(NotFound)")
+}
+
+// Gets the resources of the specified type within each of the namespaces, tagged with the namespace they were found in.
+// Each namespace is queried separately since the caller may not have permissions to list across all namespaces.
+func (cli *kubectlCli) GetAcrossNamespaces(
+	ctx context.Context,
+	resourceType string,
+	namespaces []string,
+	flags *KubeCliFlags,
+) ([]ResourceRef, error) {
+	refs := []ResourceRef{}
+	for _, namespace := range namespaces {
+		namespaceFlags := &KubeCliFlags{}
+		if flags != nil {
+			*namespaceFlags = *flags
+		}
+		namespaceFlags.Namespace = namespace
+		namespaceFlags.Output = OutputTypeJson
+
+		res, err := cli.Exec(ctx, namespaceFlags, "get", resourceType)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting %s in namespace '%s', %w", resourceType, namespace, err)
+		}
+
+		var list List[Resource]
+		if err := json.Unmarshal([]byte(res.Stdout), &list); err != nil {
+			return nil, fmt.Errorf("failed unmarshalling %s JSON in namespace '%s', %w", resourceType, namespace, err)
+		}
+
+		for _, item := range list.Items {
+			refs = append(refs, ResourceRef{
+				ApiVersion: item.ApiVersion,
+				Kind:       item.Kind,
+				Name:       item.Metadata.Name,
+				Namespace:  namespace,
+			})
+		}
+	}
+
+	return refs, nil
+}
+
 // Gets the deployment rollout status
 func (cli *kubectlCli) RolloutStatus(
 	ctx context.Context,
@@ -268,6 +860,122 @@ func (cli *kubectlCli) RolloutStatus(
 	return &res, nil
 }
 
+// Patches the fields of the existing resource without re-applying its whole manifest, which would overwrite the
+// fields managed by other controllers
+func (cli *kubectlCli) Patch(
+	ctx context.Context,
+	resourceType string,
+	name string,
+	patchType PatchType,
+	patch string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	if patchType == "" {
+		patchType = PatchTypeStrategic
+	}
+
+	res, err := cli.Exec(ctx, flags, "patch", resourceType, name, "--type", string(patchType), "-p", patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed patching %s '%s', %w", resourceType, name, err)
+	}
+
+	return &res, nil
+}
+
+// Sets the labels of the existing resource, ex. to identify the resources managed by azd.
+// Keys ending with '-' remove the label, otherwise existing labels are only updated when overwrite is true.
+func (cli *kubectlCli) Label(
+	ctx context.Context,
+	resourceType string,
+	name string,
+	labels map[string]string,
+	overwrite bool,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(ctx, flags, metadataArgs("label", resourceType, name, labels, overwrite)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed labeling %s '%s', %w", resourceType, name, err)
+	}
+
+	return &res, nil
+}
+
+// Sets the annotations of the existing resource.
+// Keys ending with '-' remove the annotation, otherwise existing annotations are only updated when overwrite is true.
+func (cli *kubectlCli) Annotate(
+	ctx context.Context,
+	resourceType string,
+	name string,
+	annotations map[string]string,
+	overwrite bool,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(ctx, flags, metadataArgs("annotate", resourceType, name, annotations, overwrite)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed annotating %s '%s', %w", resourceType, name, err)
+	}
+
+	return &res, nil
+}
+
+// Gets the arguments of the label or annotate command, with the values sorted by key
+func metadataArgs(command string, resourceType string, name string, values map[string]string, overwrite bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := []string{command, resourceType, name}
+	for _, key := range keys {
+		if strings.HasSuffix(key, "-") {
+			args = append(args, key)
+		} else {
+			args = append(args, fmt.Sprintf("%s=%s", key, values[key]))
+		}
+	}
+
+	if overwrite {
+		args = append(args, "--overwrite")
+	}
+
+	return args
+}
+
+// Restarts the pods of the deployment with a new rollout, ex. to pick up changes to a referenced secret or configmap
+func (cli *kubectlCli) RolloutRestart(
+	ctx context.Context,
+	deploymentName string,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	res, err := cli.Exec(ctx, flags, "rollout", "restart", fmt.Sprintf("deployment/%s", deploymentName))
+	if err != nil {
+		return nil, fmt.Errorf("deployment rollout restart failed, %w", err)
+	}
+
+	return &res, nil
+}
+
+// Rolls back the deployment to the specified revision, or to the previous revision when toRevision is zero
+func (cli *kubectlCli) RolloutUndo(
+	ctx context.Context,
+	deploymentName string,
+	toRevision int,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	args := []string{"rollout", "undo", fmt.Sprintf("deployment/%s", deploymentName)}
+	if toRevision > 0 {
+		args = append(args, fmt.Sprintf("--to-revision=%d", toRevision))
+	}
+
+	res, err := cli.Exec(ctx, flags, args...)
+	if err != nil {
+		return nil, fmt.Errorf("deployment rollout undo failed, %w", err)
+	}
+
+	return &res, nil
+}
+
 // Executes a k8s CLI command from the specified arguments and flags
 func (cli *kubectlCli) Exec(ctx context.Context, flags *KubeCliFlags, args ...string) (exec.RunResult, error) {
 	runArgs := exec.
@@ -277,69 +985,220 @@ func (cli *kubectlCli) Exec(ctx context.Context, flags *KubeCliFlags, args ...st
 	return cli.executeCommandWithArgs(ctx, runArgs, flags)
 }
 
-func (cli *kubectlCli) applyTemplate(ctx context.Context, filePath string, flags *KubeCliFlags) (*exec.RunResult, error) {
-	k8sTemplate, err := template.ParseFiles(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed parsing template file '%s', %w", filePath, err)
+// Applies a manifest rendered from the template at the specified file path
+func (cli *kubectlCli) applyTemplate(
+	ctx context.Context,
+	filePath string,
+	manifest string,
+	flags *KubeCliFlags,
+	crds *crdTracker,
+) (*ApplyResult, error) {
+	if err := cli.journalPriorState(ctx, manifest, flags); err != nil {
+		return nil, err
 	}
 
-	builder := strings.Builder{}
-	err = k8sTemplate.Execute(&builder, templateRoot{Env: cli.env})
+	result, err := crds.apply(ctx, manifest, func() (*ApplyResult, error) {
+		return cli.ApplyWithStdIn(ctx, manifest, flags)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed executing template file '%s', %w", filePath, err)
+		return nil, fmt.Errorf("failed applying file '%s', %w", filePath, err)
 	}
 
-	result, err := cli.ApplyWithStdIn(ctx, builder.String(), flags)
+	return result, nil
+}
+
+// Matches the error returned when a template references a key missing from the environment
+var missingEnvKeyRegex = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+
+// Renders the Go template at the specified file path using the azd environment variables.
+// When strict, fails with the names of all the referenced environment variables that are not set.
+func (cli *kubectlCli) renderTemplate(filePath string, strict bool) (string, error) {
+	k8sTemplate, err := template.ParseFiles(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed applying file '%s', %w", filePath, err)
+		return "", fmt.Errorf("failed parsing template file '%s', %w", filePath, err)
 	}
 
-	return result, nil
+	return cli.executeTemplate(k8sTemplate, fmt.Sprintf("template file '%s'", filePath), strict)
+}
+
+// Executes the parsed template using the azd environment variables. The source describes the template in errors.
+// When strict, fails with the names of all the referenced environment variables that are not set.
+func (cli *kubectlCli) executeTemplate(k8sTemplate *template.Template, source string, strict bool) (string, error) {
+	if !strict {
+		builder := strings.Builder{}
+		err := k8sTemplate.Execute(&builder, templateRoot{Env: cli.env})
+		if err != nil {
+			return "", fmt.Errorf("failed executing %s, %w", source, err)
+		}
+
+		return builder.String(), nil
+	}
+
+	// Execution stops at the first missing key, so each missing key is recorded and set to empty before executing
+	// again in order to report all of them at once
+	k8sTemplate = k8sTemplate.Option("missingkey=error")
+	env := maps.Clone(cli.env)
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	missing := []string{}
+	var manifest string
+	for {
+		builder := strings.Builder{}
+		err := k8sTemplate.Execute(&builder, templateRoot{Env: env})
+		if err == nil {
+			manifest = builder.String()
+			break
+		}
+
+		matches := missingEnvKeyRegex.FindStringSubmatch(err.Error())
+		if matches == nil {
+			return "", fmt.Errorf("failed executing %s, %w", source, err)
+		}
+
+		if _, has := env[matches[1]]; has {
+			// The key is already set, so the error comes from a different map than the environment
+			return "", fmt.Errorf("failed executing %s, %w", source, err)
+		}
+
+		missing = append(missing, matches[1])
+		env[matches[1]] = ""
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf(
+			"%s references environment variables that are not set: %s",
+			source,
+			strings.Join(missing, ", "),
+		)
+	}
+
+	return manifest, nil
+}
+
+// Applies the raw manifest file without any template processing
+func (cli *kubectlCli) applyFile(
+	ctx context.Context,
+	filePath string,
+	manifest string,
+	flags *KubeCliFlags,
+	crds *crdTracker,
+) (*ApplyResult, error) {
+	if err := cli.journalPriorState(ctx, manifest, flags); err != nil {
+		return nil, err
+	}
+
+	return crds.apply(ctx, manifest, func() (*ApplyResult, error) {
+		return cli.ApplyWithFile(ctx, filePath, flags)
+	})
 }
 
-// Recursively loops through the specified directory and applies all k8s manifests
-// If the file is a *.tmpl file, it will be parsed as a template to support environment injection.
+// Applies the k8s manifest file at the specified path, or recursively all the k8s manifests within the directory.
+// If the file is a *.tmpl.yaml, *.yaml.tmpl, *.tmpl.json or *.json.tmpl file, it will be parsed as a template to support
+// environment injection.
 // Otherwise the actual file contents will be applied.
-func (cli *kubectlCli) applyTemplates(ctx context.Context, directoryPath string, flags *KubeCliFlags) error {
-	entries, err := os.ReadDir(directoryPath)
+//
+// Manifests are applied in kind priority order instead of directory order, so that namespaces, CRDs, config maps and
+// secrets are created before the workloads that consume them. A manifest defining multiple resources is applied
+// according to the resource with the highest priority.
+// When pruning, all the manifests are applied together in that order by a single kubectl invocation.
+func (cli *kubectlCli) applyTemplates(
+	ctx context.Context,
+	path string,
+	flags *KubeCliFlags,
+	crds *crdTracker,
+) ([]*ApplyResult, error) {
+	manifests, err := cli.readManifests(path, flags)
 	if err != nil {
-		return fmt.Errorf("failed reading files in path, '%s', %w", directoryPath, err)
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		entryPath := filepath.Join(directoryPath, entry.Name())
+	manifests = manifestApplyOrder(manifests)
 
-		if entry.IsDir() {
-			if err := cli.applyTemplates(ctx, entryPath, flags); err != nil {
-				return fmt.Errorf("failed applying templates at '%s', %w", entryPath, err)
-			}
+	// A dry run already validates without persisting anything
+	if flags != nil && flags.ValidateFirst && (flags.DryRun == "" || flags.DryRun == DryRunTypeNone) {
+		if err := cli.validateManifests(ctx, manifests, flags); err != nil {
+			return nil, err
+		}
+	}
 
-			continue
+	// Pruning deletes the resources that are not defined by the manifests of each kubectl invocation, so all of the
+	// manifests are applied at once
+	if flags != nil && flags.Prune {
+		contents := make([]string, 0, len(manifests))
+		for _, manifest := range manifests {
+			contents = append(contents, manifest.Content)
 		}
 
-		ext := filepath.Ext(entry.Name())
-		var err error
+		result, err := cli.applyTemplate(ctx, path, strings.Join(contents, "\n---\n"), flags, crds)
+		if err != nil {
+			return nil, err
+		}
 
-		switch ext {
-		case ".yaml", ".yml": // Only include yaml files
-			fileNameWithoutExtension := strings.TrimSuffix(entry.Name(), ext)
-			isTemplateFile := strings.HasSuffix(fileNameWithoutExtension, ".tmpl")
+		result.Path = path
+		return []*ApplyResult{result}, nil
+	}
 
-			if isTemplateFile {
-				_, err = cli.applyTemplate(ctx, entryPath, flags)
-			} else {
-				_, err = cli.ApplyWithFile(ctx, entryPath, flags)
-			}
-		default: // Ignore all other files
-			continue
-		}
+	if flags != nil && flags.ApplyConcurrency > 1 {
+		return cli.applyManifestsConcurrently(ctx, manifests, flags, crds, flags.ApplyConcurrency)
+	}
 
+	results := []*ApplyResult{}
+	for _, manifest := range manifests {
+		result, err := cli.applyManifest(ctx, manifest, flags, crds)
 		if err != nil {
-			return fmt.Errorf("failed applying file '%s', %w", entryPath, err)
+			return results, err
 		}
+
+		results = append(results, result)
 	}
 
-	return nil
+	return results, nil
+}
+
+// Applies the manifest read from disk, either as a rendered template or as the raw file
+func (cli *kubectlCli) applyManifest(
+	ctx context.Context,
+	manifest manifestContent,
+	flags *KubeCliFlags,
+	crds *crdTracker,
+) (*ApplyResult, error) {
+	var result *ApplyResult
+	var err error
+	if manifest.Rendered {
+		result, err = cli.applyTemplate(ctx, manifest.Path, manifest.Content, flags, crds)
+	} else {
+		result, err = cli.applyFile(ctx, manifest.Path, manifest.Content, flags, crds)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed applying file '%s', %w", manifest.Path, err)
+	}
+
+	result.Path = manifest.Path
+	return result, nil
+}
+
+// Gets whether the file is a k8s manifest and whether it should be rendered as a Go template.
+// Manifests are yaml or json files, templates are named either '*.tmpl.<ext>' or '*.<ext>.tmpl'
+func manifestFileType(fileName string) (isManifest bool, isTemplate bool) {
+	ext := filepath.Ext(fileName)
+	fileNameWithoutExtension := strings.TrimSuffix(fileName, ext)
+
+	if ext == ".tmpl" {
+		ext = filepath.Ext(fileNameWithoutExtension)
+		isTemplate = true
+	} else {
+		isTemplate = strings.HasSuffix(fileNameWithoutExtension, ".tmpl")
+	}
+
+	switch ext {
+	case ".yaml", ".yml", ".json": // Only include yaml and json files
+		return true, isTemplate
+	default:
+		return false, false
+	}
 }
 
 func (cli *kubectlCli) executeCommandWithArgs(
@@ -353,21 +1212,76 @@ func (cli *kubectlCli) executeCommandWithArgs(
 
 	args = args.WithEnv(environ(cli.env))
 
+	if cli.kubeConfigPath != "" {
+		args = args.AppendParams("--kubeconfig", cli.kubeConfigPath)
+	}
+
 	if flags != nil {
+		if flags.Prune && strings.TrimSpace(flags.PruneSelector) == "" {
+			return exec.RunResult{}, errors.New("a prune selector is required to prune resources")
+		}
+
 		if flags.DryRun != "" {
 			args = args.AppendParams(fmt.Sprintf("--dry-run=%s", flags.DryRun))
 		}
+		if flags.IgnoreNotFound {
+			args = args.AppendParams("--ignore-not-found")
+		}
+		if flags.ServerSide {
+			args = args.AppendParams("--server-side")
+
+			if flags.FieldManager != "" {
+				args = args.AppendParams(fmt.Sprintf("--field-manager=%s", flags.FieldManager))
+			}
+			if flags.ForceConflicts {
+				args = args.AppendParams("--force-conflicts")
+			}
+		}
+		if flags.Prune {
+			args = args.AppendParams("--prune", "-l", flags.PruneSelector)
+		}
+		if flags.FieldSelector != "" {
+			args = args.AppendParams(fmt.Sprintf("--field-selector=%s", flags.FieldSelector))
+		}
 		if flags.Namespace != "" {
 			args = args.AppendParams("-n", flags.Namespace)
 		}
 		if flags.Output != "" {
 			args = args.AppendParams("-o", string(flags.Output))
 		}
+
+		if flags.Timeout > 0 {
+			timeoutCtx, cancel := context.WithTimeout(ctx, flags.Timeout)
+			defer cancel()
+
+			res, err := cli.commandRunner.Run(timeoutCtx, args)
+			// Only report a timeout when the deadline of the command expired, not the deadline of the caller
+			if err != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				return res, fmt.Errorf(
+					"kubectl %s did not complete within %s, %w", subcommand(args.Args), flags.Timeout, ErrCommandTimeout)
+			}
+
+			return res, err
+		}
 	}
 
 	return cli.commandRunner.Run(ctx, args)
 }
 
+// Gets the kubectl subcommand from the arguments, ex. 'rollout status'
+func subcommand(args []string) string {
+	words := []string{}
+	for _, arg := range args {
+		if len(words) == 2 || strings.HasPrefix(arg, "-") {
+			break
+		}
+
+		words = append(words, arg)
+	}
+
+	return strings.Join(words, " ")
+}
+
 func environ(values map[string]string) []string {
 	env := []string{}
 	for key, value := range values {
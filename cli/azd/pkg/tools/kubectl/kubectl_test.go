@@ -2,11 +2,13 @@ package kubectl
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/azure/azure-dev/cli/azd/pkg/exec"
 	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
@@ -37,7 +39,7 @@ func Test_ApplyFiles(t *testing.T) {
 	err := os.WriteFile("test.yaml", []byte("yaml"), osutil.PermissionFile)
 	require.NoError(t, err)
 
-	err = cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{
+	_, err = cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{
 		Namespace: "test-namespace",
 	})
 	require.NoError(t, err)
@@ -48,6 +50,118 @@ func Test_ApplyFiles(t *testing.T) {
 	require.Equal(t, []string{"apply", "-f", filepath.Join(tempDir, "test.yaml"), "-n", "test-namespace"}, runArgs.Args)
 }
 
+func Test_ApplyFiles_SingleFile(t *testing.T) {
+	tempDir := t.TempDir()
+	ostest.Chdir(t, tempDir)
+
+	var runArgs []exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = append(runArgs, args)
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	cli.SetEnv(map[string]string{"NAME": "api"})
+
+	require.NoError(t, os.WriteFile("test.yaml", []byte("yaml"), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile("other.yaml", []byte("other"), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile("test.tmpl.yaml", []byte("name: {{ .Env.NAME }}"), osutil.PermissionFile))
+
+	t.Run("File", func(t *testing.T) {
+		runArgs = nil
+		_, err := cli.Apply(*mockContext.Context, filepath.Join(tempDir, "test.yaml"), nil)
+		require.NoError(t, err)
+
+		require.Len(t, runArgs, 1)
+		require.Equal(t, []string{"apply", "-f", filepath.Join(tempDir, "test.yaml")}, runArgs[0].Args)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		runArgs = nil
+		_, err := cli.Apply(*mockContext.Context, filepath.Join(tempDir, "test.tmpl.yaml"), nil)
+		require.NoError(t, err)
+
+		require.Len(t, runArgs, 1)
+		require.Equal(t, []string{"apply", "-f", "-"}, runArgs[0].Args)
+
+		input, err := io.ReadAll(runArgs[0].StdIn)
+		require.NoError(t, err)
+		require.Equal(t, "name: api", string(input))
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		runArgs = nil
+		_, err := cli.Apply(*mockContext.Context, tempDir, nil)
+		require.NoError(t, err)
+		require.Len(t, runArgs, 3)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := cli.Apply(*mockContext.Context, filepath.Join(tempDir, "missing.yaml"), nil)
+		require.Error(t, err)
+	})
+}
+
+func Test_Apply_Prune(t *testing.T) {
+	tempDir := t.TempDir()
+
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n"
+	namespace := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: app\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "deployment.yaml"), []byte(deployment), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "namespace.yaml"), []byte(namespace), osutil.PermissionFile))
+
+	var runArgs []exec.RunArgs
+	var input string
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = append(runArgs, args)
+		if args.StdIn != nil {
+			content, err := io.ReadAll(args.StdIn)
+			require.NoError(t, err)
+			input = string(content)
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+
+	t.Run("AppliedTogether", func(t *testing.T) {
+		_, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{
+			Prune:         true,
+			PruneSelector: "app=todo",
+		})
+		require.NoError(t, err)
+
+		// Applying the manifests separately would prune the resources of each other
+		require.Len(t, runArgs, 1)
+		require.Equal(t, []string{"apply", "-f", "-", "--prune", "-l", "app=todo"}, runArgs[0].Args)
+		require.Equal(t, namespace+"\n---\n"+deployment, input)
+	})
+
+	t.Run("SelectorRequired", func(t *testing.T) {
+		runArgs = nil
+		_, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{Prune: true})
+		require.ErrorContains(t, err, "a prune selector is required")
+		require.Empty(t, runArgs)
+	})
+
+	t.Run("NotAtomic", func(t *testing.T) {
+		err := cli.ApplyAtomic(*mockContext.Context, tempDir, &KubeCliFlags{
+			Prune:         true,
+			PruneSelector: "app=todo",
+		})
+		require.Error(t, err)
+		require.Empty(t, runArgs)
+	})
+}
+
 func Test_Command_Args(t *testing.T) {
 	tempDir := t.TempDir()
 	ostest.Chdir(t, tempDir)
@@ -68,6 +182,51 @@ func Test_Command_Args(t *testing.T) {
 				return err
 			},
 		},
+		"apply-server-side": {
+			mockCommandPredicate: "kubectl apply -f",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"apply", "-f", "file.yaml", "--server-side", "--field-manager=azd", "--force-conflicts", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.ApplyWithFile(*mockContext.Context, "file.yaml", &KubeCliFlags{
+					Namespace:      "test-namespace",
+					ServerSide:     true,
+					FieldManager:   "azd",
+					ForceConflicts: true,
+				})
+
+				return err
+			},
+		},
+		"apply-server-side-defaults": {
+			mockCommandPredicate: "kubectl apply -f -",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"apply", "-f", "-", "--server-side"},
+			testFn: func() error {
+				_, err := cli.ApplyWithStdIn(*mockContext.Context, "input", &KubeCliFlags{
+					ServerSide: true,
+				})
+
+				return err
+			},
+		},
+		"apply-prune": {
+			mockCommandPredicate: "kubectl apply -f",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"apply", "-f", "file.yaml", "--prune", "-l", "app.kubernetes.io/managed-by=azd", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.ApplyWithFile(*mockContext.Context, "file.yaml", &KubeCliFlags{
+					Namespace:     "test-namespace",
+					Prune:         true,
+					PruneSelector: "app.kubernetes.io/managed-by=azd",
+				})
+
+				return err
+			},
+		},
 		"apply-with-file": {
 			mockCommandPredicate: "kubectl apply -f",
 			expectedCmd:          "kubectl",
@@ -113,6 +272,96 @@ func Test_Command_Args(t *testing.T) {
 				return err
 			},
 		},
+		"delete": {
+			mockCommandPredicate: "kubectl delete deployment",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"delete", "deployment", "api", "--ignore-not-found", "-n", "test-namespace"},
+			testFn: func() error {
+				_, err := cli.Delete(*mockContext.Context, "deployment", "api", &KubeCliFlags{
+					Namespace:      "test-namespace",
+					IgnoreNotFound: true,
+				})
+
+				return err
+			},
+		},
+		"delete-with-selector": {
+			mockCommandPredicate: "kubectl delete pods -l",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"delete", "pods", "-l", "app=api", "-n", "test-namespace"},
+			testFn: func() error {
+				_, err := cli.DeleteWithSelector(*mockContext.Context, "pods", "app=api", &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"create-configmap-from-literals": {
+			mockCommandPredicate: "kubectl create configmap app-config --from-literal",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"create", "configmap", "app-config", "--from-literal=LOG_LEVEL=debug", "--from-literal=PORT=8080",
+				"--dry-run=client", "-o", "yaml",
+			},
+			testFn: func() error {
+				_, err := cli.CreateConfigMapFromLiterals(
+					*mockContext.Context, "app-config", []string{"LOG_LEVEL=debug", "PORT=8080"}, &KubeCliFlags{
+						DryRun: DryRunTypeClient,
+						Output: OutputTypeYaml,
+					})
+
+				return err
+			},
+		},
+		"create-configmap-from-file": {
+			mockCommandPredicate: "kubectl create configmap app-config --from-file",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"create", "configmap", "app-config", "--from-file=config/app.json", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.CreateConfigMapFromFile(*mockContext.Context, "app-config", "config/app.json", &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"create-secret-tls": {
+			mockCommandPredicate: "kubectl create secret tls",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"create", "secret", "tls", "ingress-tls", "--cert=tls.crt", "--key=tls.key", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.CreateSecretTLS(*mockContext.Context, "ingress-tls", "tls.crt", "tls.key", &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"create-secret-docker-registry": {
+			mockCommandPredicate: "kubectl create secret docker-registry",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"create", "secret", "docker-registry", "registry-creds",
+				"--docker-server=myregistry.azurecr.io",
+				"--docker-username=user",
+				"--docker-password=password",
+				"-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.CreateSecretDockerRegistry(
+					*mockContext.Context, "registry-creds", "myregistry.azurecr.io", "user", "password", "",
+					&KubeCliFlags{
+						Namespace: "test-namespace",
+					})
+
+				return err
+			},
+		},
 		"rollout-status": {
 			mockCommandPredicate: "kubectl rollout status",
 			expectedCmd:          "kubectl",
@@ -125,6 +374,100 @@ func Test_Command_Args(t *testing.T) {
 				return err
 			},
 		},
+		"patch": {
+			mockCommandPredicate: "kubectl patch",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"patch", "deployment", "api", "--type", "merge", "-p", `{"spec":{"replicas":2}}`, "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.Patch(
+					*mockContext.Context, "deployment", "api", PatchTypeMerge, `{"spec":{"replicas":2}}`, &KubeCliFlags{
+						Namespace: "test-namespace",
+					})
+
+				return err
+			},
+		},
+		"patch-default-type": {
+			mockCommandPredicate: "kubectl patch",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"patch", "service", "api", "--type", "strategic", "-p", `{"metadata":{"annotations":{"a":"b"}}}`,
+			},
+			testFn: func() error {
+				_, err := cli.Patch(
+					*mockContext.Context, "service", "api", "", `{"metadata":{"annotations":{"a":"b"}}}`, nil)
+
+				return err
+			},
+		},
+		"label": {
+			mockCommandPredicate: "kubectl label",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"label", "deployment", "api", "app=api", "stale-", "tier=web", "--overwrite", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.Label(*mockContext.Context, "deployment", "api", map[string]string{
+					"tier":   "web",
+					"app":    "api",
+					"stale-": "",
+				}, true, &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"annotate": {
+			mockCommandPredicate: "kubectl annotate",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"annotate", "service", "api", "azd/managed=true"},
+			testFn: func() error {
+				_, err := cli.Annotate(*mockContext.Context, "service", "api", map[string]string{
+					"azd/managed": "true",
+				}, false, nil)
+
+				return err
+			},
+		},
+		"rollout-restart": {
+			mockCommandPredicate: "kubectl rollout restart",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"rollout", "restart", "deployment/deployment-name", "-n", "test-namespace"},
+			testFn: func() error {
+				_, err := cli.RolloutRestart(*mockContext.Context, "deployment-name", &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"rollout-undo": {
+			mockCommandPredicate: "kubectl rollout undo",
+			expectedCmd:          "kubectl",
+			expectedArgs: []string{
+				"rollout", "undo", "deployment/deployment-name", "--to-revision=3", "-n", "test-namespace",
+			},
+			testFn: func() error {
+				_, err := cli.RolloutUndo(*mockContext.Context, "deployment-name", 3, &KubeCliFlags{
+					Namespace: "test-namespace",
+				})
+
+				return err
+			},
+		},
+		"rollout-undo-previous": {
+			mockCommandPredicate: "kubectl rollout undo",
+			expectedCmd:          "kubectl",
+			expectedArgs:         []string{"rollout", "undo", "deployment/deployment-name"},
+			testFn: func() error {
+				_, err := cli.RolloutUndo(*mockContext.Context, "deployment-name", 0, nil)
+
+				return err
+			},
+		},
 		"exec": {
 			mockCommandPredicate: "kubectl get deployment",
 			expectedCmd:          "kubectl",
@@ -215,7 +558,7 @@ func Test_Apply_Template(t *testing.T) {
 			Namespace: "test",
 		}
 
-		err := cli.Apply(
+		_, err := cli.Apply(
 			*mockContext.Context,
 			"../../../test/testdata/k8s/apply/raw",
 			flags,
@@ -236,7 +579,7 @@ func Test_Apply_Template(t *testing.T) {
 			Namespace: "test",
 		}
 
-		err := cli.Apply(
+		_, err := cli.Apply(
 			*mockContext.Context,
 			"../../../test/testdata/k8s/apply/templates",
 			flags,
@@ -253,3 +596,576 @@ func Test_Apply_Template(t *testing.T) {
 		require.Contains(t, yaml, "EXAMPLE_CLIENT_ID")
 	})
 }
+
+func Test_Apply_Template_Conditional(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+{{- if .Env.ENABLE_FEATURE }}
+  feature: "{{ .Env.ENABLE_FEATURE }}"
+{{- else }}
+  feature: "disabled"
+{{- end }}
+`
+
+	tests := map[string]struct {
+		fileName string
+		flags    *KubeCliFlags
+		env      map[string]string
+		expected string
+	}{
+		"TmplExtensionEnabled": {
+			fileName: "config.yaml.tmpl",
+			env:      map[string]string{"ENABLE_FEATURE": "enabled"},
+			expected: `feature: "enabled"`,
+		},
+		"TmplExtensionDisabled": {
+			fileName: "config.yaml.tmpl",
+			env:      map[string]string{},
+			expected: `feature: "disabled"`,
+		},
+		"RenderTemplatesFlag": {
+			fileName: "config.yaml",
+			flags:    &KubeCliFlags{RenderTemplates: true},
+			env:      map[string]string{"ENABLE_FEATURE": "on"},
+			expected: `feature: "on"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, test.fileName), []byte(manifest), osutil.PermissionFile)
+			require.NoError(t, err)
+
+			var stdIn string
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl apply -f -")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				stdInBytes, err := io.ReadAll(args.StdIn)
+				require.NoError(t, err)
+				stdIn = string(stdInBytes)
+
+				return exec.NewRunResult(0, "", ""), nil
+			})
+
+			cli := NewKubectl(mockContext.CommandRunner)
+			cli.SetEnv(test.env)
+
+			_, err = cli.Apply(*mockContext.Context, tempDir, test.flags)
+			require.NoError(t, err)
+			require.Contains(t, stdIn, test.expected)
+			require.NotContains(t, stdIn, "{{")
+		})
+	}
+}
+
+func Test_CurrentNamespace(t *testing.T) {
+	tests := map[string]struct {
+		config   string
+		expected string
+	}{
+		"ExplicitNamespace": {
+			config: `apiVersion: v1
+kind: Config
+current-context: aks
+contexts:
+- name: aks
+  context:
+    cluster: aks
+    namespace: my-app
+    user: aks-user
+`,
+			expected: "my-app",
+		},
+		"NoNamespace": {
+			config: `apiVersion: v1
+kind: Config
+current-context: aks
+contexts:
+- name: aks
+  context:
+    cluster: aks
+    user: aks-user
+`,
+			expected: "default",
+		},
+		"EmptyContextEntry": {
+			config: `apiVersion: v1
+kind: Config
+current-context: aks
+contexts:
+-
+- name: aks
+  context:
+    cluster: aks
+    namespace: my-app
+`,
+			expected: "my-app",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl config view --minify")
+			}).Respond(exec.NewRunResult(0, test.config, ""))
+
+			cli := NewKubectl(mockContext.CommandRunner)
+
+			namespace, err := cli.CurrentNamespace(*mockContext.Context)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, namespace)
+		})
+	}
+}
+
+func Test_ConfigGetContexts(t *testing.T) {
+	config := `apiVersion: v1
+kind: Config
+current-context: aks-prod
+contexts:
+- name: aks-dev
+  context:
+    cluster: aks-dev
+    user: clusterUser_rg-dev_aks-dev
+- name: aks-prod
+  context:
+    cluster: aks-prod
+    namespace: todo
+    user: clusterUser_rg-prod_aks-prod
+`
+
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.
+		WhenKubectl("config", "view").
+		RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			runArgs = args
+			return exec.NewRunResult(0, config, ""), nil
+		})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	contexts, err := cli.ConfigGetContexts(*mockContext.Context, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"config", "view", "-o", "yaml"}, runArgs.Args)
+	require.Equal(t, []KubeContext{
+		{
+			Name:    "aks-dev",
+			Context: KubeContextData{Cluster: "aks-dev", User: "clusterUser_rg-dev_aks-dev"},
+		},
+		{
+			Name:    "aks-prod",
+			Context: KubeContextData{Cluster: "aks-prod", Namespace: "todo", User: "clusterUser_rg-prod_aks-prod"},
+		},
+	}, contexts)
+}
+
+func Test_ConfigCurrentContext(t *testing.T) {
+	t.Run("Set", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.WhenKubectl("config", "current-context").Respond("aks-prod\n", 0)
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		currentContext, err := cli.ConfigCurrentContext(*mockContext.Context)
+		require.NoError(t, err)
+		require.Equal(t, "aks-prod", currentContext)
+	})
+
+	t.Run("NotSet", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.WhenKubectl("config", "current-context").Respond("", 1)
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		_, err := cli.ConfigCurrentContext(*mockContext.Context)
+		require.ErrorContains(t, err, "failed getting current kubectl context")
+	})
+}
+
+func Test_EnsureNamespace(t *testing.T) {
+	namespaceManifest := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: my-app\n"
+
+	var createArgs []string
+	var applyArgs []string
+	var appliedManifest string
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl create namespace")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		createArgs = args.Args
+		return exec.NewRunResult(0, namespaceManifest, ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f -")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		applyArgs = args.Args
+		input, err := io.ReadAll(args.StdIn)
+		require.NoError(t, err)
+		appliedManifest = string(input)
+
+		// Applying an existing namespace succeeds, unlike creating it
+		return exec.NewRunResult(0, "namespace/my-app unchanged", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	err := cli.EnsureNamespace(*mockContext.Context, "my-app", nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"create", "namespace", "my-app", "--dry-run=client", "-o", "yaml"}, createArgs)
+	require.Equal(t, []string{"apply", "-f", "-"}, applyArgs)
+	require.Equal(t, namespaceManifest, appliedManifest)
+
+	// The flags of the manifest apply are not used for the namespace
+	err = cli.EnsureNamespace(*mockContext.Context, "my-app", &KubeCliFlags{
+		Namespace:     "my-app",
+		DryRun:        DryRunTypeServer,
+		ServerSide:    true,
+		FieldManager:  "azd",
+		Prune:         true,
+		PruneSelector: "app=my-app",
+		FieldSelector: "status.phase=Running",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"create", "namespace", "my-app", "--dry-run=client", "-n", "my-app", "-o", "yaml"}, createArgs)
+	require.Equal(t, []string{"apply", "-f", "-", "--dry-run=server", "-n", "my-app"}, applyArgs)
+}
+
+func Test_GetAcrossNamespaces(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.Equal(t, []string{"get", "pods", "-n", args.Args[3], "-o", "json"}, args.Args)
+
+		switch args.Args[3] {
+		case "tenant-a":
+			return exec.NewRunResult(0, `{"items": [
+				{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "api-1"}},
+				{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "api-2"}}
+			]}`, ""), nil
+		case "tenant-b":
+			return exec.NewRunResult(0, `{"items": [
+				{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "api-1"}}
+			]}`, ""), nil
+		default:
+			return exec.NewRunResult(0, `{"items": []}`, ""), nil
+		}
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+
+	refs, err := cli.GetAcrossNamespaces(*mockContext.Context, "pods", []string{"tenant-a", "tenant-b", "empty"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []ResourceRef{
+		{ApiVersion: "v1", Kind: "Pod", Name: "api-1", Namespace: "tenant-a"},
+		{ApiVersion: "v1", Kind: "Pod", Name: "api-2", Namespace: "tenant-a"},
+		{ApiVersion: "v1", Kind: "Pod", Name: "api-1", Namespace: "tenant-b"},
+	}, refs)
+}
+
+func Test_Get(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.
+			WhenKubectl("get", "deployment", "api").
+			RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				require.Equal(t, []string{"get", "deployment", "api", "-n", "test-namespace", "-o", "json"}, args.Args)
+				return exec.NewRunResult(0, `{"kind": "Deployment", "spec": {"replicas": 2}}`, ""), nil
+			})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		// The output type is always JSON
+		resource, err := cli.Get(*mockContext.Context, "deployment", "api", &KubeCliFlags{
+			Namespace: "test-namespace",
+			Output:    OutputTypeYaml,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Deployment", resource["kind"])
+		require.Equal(t, map[string]any{"replicas": float64(2)}, resource["spec"])
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.
+			WhenKubectl("get", "deployment", "api").
+			RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				return exec.NewRunResult(1, "", `Error from server (NotFound): deployments.apps "api" not found`),
+					errors.New("exit code: 1")
+			})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		_, err := cli.Get(*mockContext.Context, "deployment", "api", nil)
+		require.ErrorIs(t, err, ErrResourceNotFound)
+	})
+}
+
+func Test_ApplyMultiple(t *testing.T) {
+	inputs := []string{
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Env.SERVICE_NAME }}-config\n",
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Env.SERVICE_NAME }}\n",
+	}
+
+	t.Run("SingleInvocation", func(t *testing.T) {
+		applyCount := 0
+		var appliedManifest string
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.
+			WhenKubectl("apply").
+			RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				applyCount++
+				input, err := io.ReadAll(args.StdIn)
+				require.NoError(t, err)
+				appliedManifest = string(input)
+
+				return exec.NewRunResult(0, "configmap/api-config created\ndeployment.apps/api created", ""), nil
+			})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		cli.SetEnv(map[string]string{"SERVICE_NAME": "api"})
+
+		result, err := cli.ApplyMultiple(*mockContext.Context, inputs, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, applyCount)
+		require.Equal(
+			t,
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: api-config\n---\n"+
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api",
+			appliedManifest,
+		)
+		require.Equal(t, "configmap/api-config created\ndeployment.apps/api created", result.Stdout)
+	})
+
+	t.Run("StrictEnvSubst", func(t *testing.T) {
+		mockContext := mocks.NewMockContext(context.Background())
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		_, err := cli.ApplyMultiple(*mockContext.Context, inputs, &KubeCliFlags{StrictEnvSubst: true})
+		require.ErrorContains(t, err, "manifest input 0 references environment variables that are not set: SERVICE_NAME")
+	})
+}
+
+func Test_Apply_Template_StrictEnvSubst(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Env.SERVICE_NAME }}
+spec:
+  template:
+    spec:
+      containers:
+      - image: {{ .Env.SERVICE_IMAGE_NAME }}:{{ .Env.SERVICE_IMAGE_TAG }}
+`
+
+	tests := map[string]struct {
+		flags        *KubeCliFlags
+		env          map[string]string
+		expectedErr  string
+		expectedYaml string
+	}{
+		"Lenient": {
+			flags:        nil,
+			env:          map[string]string{"SERVICE_NAME": "api"},
+			expectedYaml: "image: <no value>:<no value>",
+		},
+		"StrictMissing": {
+			flags:       &KubeCliFlags{StrictEnvSubst: true},
+			env:         map[string]string{"SERVICE_NAME": "api"},
+			expectedErr: "environment variables that are not set: SERVICE_IMAGE_NAME, SERVICE_IMAGE_TAG",
+		},
+		"StrictSet": {
+			flags: &KubeCliFlags{StrictEnvSubst: true},
+			env: map[string]string{
+				"SERVICE_NAME":       "api",
+				"SERVICE_IMAGE_NAME": "myregistry.azurecr.io/api",
+				"SERVICE_IMAGE_TAG":  "v1",
+			},
+			expectedYaml: "image: myregistry.azurecr.io/api:v1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tempDir, "deployment.tmpl.yaml"), []byte(manifest), osutil.PermissionFile)
+			require.NoError(t, err)
+
+			applied := ""
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl apply -f -")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				stdInBytes, err := io.ReadAll(args.StdIn)
+				require.NoError(t, err)
+				applied = string(stdInBytes)
+
+				return exec.NewRunResult(0, "", ""), nil
+			})
+
+			cli := NewKubectl(mockContext.CommandRunner)
+			cli.SetEnv(test.env)
+
+			_, err = cli.Apply(*mockContext.Context, tempDir, test.flags)
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				require.Empty(t, applied)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Contains(t, applied, test.expectedYaml)
+		})
+	}
+}
+
+func Test_Apply_JsonManifests(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"service.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n",
+		"config.json":  `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config"}}`,
+		"deployment.tmpl.json": `{"apiVersion": "apps/v1", "kind": "Deployment", ` +
+			`"metadata": {"name": "{{ .Env.SERVICE_NAME }}"}}`,
+		"README.md": "# ignored",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), osutil.PermissionFile))
+	}
+
+	appliedFiles := []string{}
+	appliedStdIn := []string{}
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply -f")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		if args.Args[2] == "-" {
+			stdInBytes, err := io.ReadAll(args.StdIn)
+			require.NoError(t, err)
+			appliedStdIn = append(appliedStdIn, string(stdInBytes))
+		} else {
+			appliedFiles = append(appliedFiles, filepath.Base(args.Args[2]))
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	cli.SetEnv(map[string]string{"SERVICE_NAME": "api"})
+
+	_, err := cli.Apply(*mockContext.Context, tempDir, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"config.json", "service.yaml"}, appliedFiles)
+	require.Equal(t, []string{`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "api"}}`}, appliedStdIn)
+}
+
+func Test_Exec_Timeout(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl rollout status")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		// The command runner stops the process when the context deadline expires
+		time.Sleep(20 * time.Millisecond)
+		return exec.NewRunResult(-1, "", ""), errors.New("signal: killed")
+	})
+	mockContext.CommandRunner.WhenKubectl("get").Respond("{}", 0)
+
+	cli := NewKubectl(mockContext.CommandRunner)
+
+	t.Run("Expired", func(t *testing.T) {
+		_, err := cli.RolloutStatus(*mockContext.Context, "api", &KubeCliFlags{Timeout: time.Millisecond})
+		require.ErrorIs(t, err, ErrCommandTimeout)
+		require.ErrorContains(t, err, "kubectl rollout status did not complete within 1ms")
+	})
+
+	t.Run("NoTimeout", func(t *testing.T) {
+		_, err := cli.RolloutStatus(*mockContext.Context, "api", nil)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrCommandTimeout)
+	})
+
+	t.Run("Completed", func(t *testing.T) {
+		_, err := cli.Exec(*mockContext.Context, &KubeCliFlags{Timeout: time.Minute}, "get", "pods")
+		require.NoError(t, err)
+	})
+}
+
+func Test_SetKubeConfigPath(t *testing.T) {
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = args
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	cli.SetEnv(map[string]string{KubeConfigEnvVarName: "/home/user/.kube/config"})
+
+	t.Run("ExplicitPath", func(t *testing.T) {
+		cli.SetKubeConfigPath("/tmp/cluster-a.yaml")
+
+		_, err := cli.Exec(*mockContext.Context, &KubeCliFlags{Namespace: "app"}, "get", "pods")
+		require.NoError(t, err)
+		require.Equal(t, []string{"get", "pods", "--kubeconfig", "/tmp/cluster-a.yaml", "-n", "app"}, runArgs.Args)
+	})
+
+	t.Run("EnvVar", func(t *testing.T) {
+		cli.SetKubeConfigPath("")
+
+		_, err := cli.Exec(*mockContext.Context, nil, "get", "pods")
+		require.NoError(t, err)
+		require.Equal(t, []string{"get", "pods"}, runArgs.Args)
+		require.Contains(t, runArgs.Env, "KUBECONFIG=/home/user/.kube/config")
+	})
+}
+
+func Test_Apply_Kustomization(t *testing.T) {
+	tempDir := t.TempDir()
+
+	kustomization := "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n  - deployment.yaml\n"
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Env.NAME }}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "kustomization.yaml"), []byte(kustomization), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "deployment.yaml"), []byte(deployment), osutil.PermissionFile))
+
+	var runArgs []exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl apply")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = append(runArgs, args)
+		return exec.NewRunResult(0, "deployment.apps/api configured", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+
+	t.Run("Apply", func(t *testing.T) {
+		runArgs = nil
+		results, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{Namespace: "app", RenderTemplates: true})
+		require.NoError(t, err)
+
+		// The manifests are applied by kustomize instead of walking the files
+		require.Len(t, runArgs, 1)
+		require.Equal(t, []string{"apply", "-k", tempDir, "-n", "app"}, runArgs[0].Args)
+
+		require.Len(t, results, 1)
+		require.Equal(t, tempDir, results[0].Path)
+		require.Equal(t, "deployment.apps/api configured", results[0].Stdout)
+	})
+
+	t.Run("ValidateFirst", func(t *testing.T) {
+		runArgs = nil
+		_, err := cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{ValidateFirst: true})
+		require.NoError(t, err)
+
+		require.Len(t, runArgs, 2)
+		require.Equal(t, []string{"apply", "-k", tempDir, "--dry-run=server"}, runArgs[0].Args)
+		require.Equal(t, []string{"apply", "-k", tempDir}, runArgs[1].Args)
+	})
+}
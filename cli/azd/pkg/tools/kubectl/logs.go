@@ -0,0 +1,285 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// The interval used to discover new pods while following the logs of a deployment
+var podDiscoveryInterval = 5 * time.Second
+
+// Optional settings used when getting the logs of a pod
+type LogOptions struct {
+	// The container to get the logs of, required for pods with multiple containers
+	Container string
+	// The number of most recent lines to return, zero returns all lines
+	Tail int
+	// Only returns the logs newer than the duration, ex. 5 minutes
+	Since time.Duration
+	// Returns the logs of the previous instance of the container, ex. to diagnose a container that crashed
+	Previous bool
+}
+
+// Gets the logs of the pod, returned as the stdout of the result
+func (cli *kubectlCli) Logs(
+	ctx context.Context,
+	podName string,
+	opts LogOptions,
+	flags *KubeCliFlags,
+) (*exec.RunResult, error) {
+	args := []string{"logs", podName}
+	if opts.Container != "" {
+		args = append(args, "--container", opts.Container)
+	}
+	if opts.Tail > 0 {
+		args = append(args, fmt.Sprintf("--tail=%d", opts.Tail))
+	}
+	if opts.Since > 0 {
+		args = append(args, fmt.Sprintf("--since=%s", opts.Since))
+	}
+	if opts.Previous {
+		args = append(args, "--previous")
+	}
+
+	res, err := cli.Exec(ctx, flags, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting logs of pod '%s', %w", podName, err)
+	}
+
+	return &res, nil
+}
+
+// Streams the logs of all the pods of the deployment to the writer, prefixing each line with the pod name.
+// When follow is set, logs are streamed until the context is canceled and pods created after the call
+// (ex. during a rollout or scale out) are picked up as they start running.
+func (cli *kubectlCli) LogsForDeployment(
+	ctx context.Context,
+	deploymentName string,
+	follow bool,
+	flags *KubeCliFlags,
+	out io.Writer,
+) error {
+	namespace := ""
+	if flags != nil {
+		namespace = flags.Namespace
+	}
+
+	deployment, err := GetResource[Deployment](ctx, cli, ResourceTypeDeployment, deploymentName, &KubeCliFlags{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed getting deployment '%s', %w", deploymentName, err)
+	}
+
+	selector := labelSelector(deployment.Spec.Selector.MatchLabels)
+	if selector == "" {
+		return fmt.Errorf("deployment '%s' does not define a label selector", deploymentName)
+	}
+
+	// The streams are canceled and waited on for every return, so they never write to out after returning
+	streamCtx, cancel := context.WithCancel(ctx)
+	var streamsWg sync.WaitGroup
+	defer func() {
+		cancel()
+		streamsWg.Wait()
+	}()
+
+	var (
+		writeLock sync.Mutex
+		lock      sync.Mutex
+		errs      []error
+		// The pods currently streaming logs, removed when their stream ends so restarted pods are streamed again
+		active = map[string]bool{}
+		// When the last stream of each pod ended
+		streamEnded = map[string]time.Time{}
+	)
+
+	// Streams the logs of the pod, only the logs newer than since when set
+	startStream := func(podName string, since time.Time) {
+		active[podName] = true
+		streamsWg.Add(1)
+
+		go func() {
+			defer streamsWg.Done()
+
+			writer := newPrefixWriter(out, fmt.Sprintf("[%s] ", podName), &writeLock)
+			err := cli.podLogs(streamCtx, podName, follow, since, namespace, writer)
+			writer.Flush()
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			delete(active, podName)
+			streamEnded[podName] = cli.clock.Now()
+			if err != nil && streamCtx.Err() == nil {
+				errs = append(errs, fmt.Errorf("failed streaming logs for pod '%s', %w", podName, err))
+			}
+		}()
+	}
+
+	for {
+		pods, err := cli.podsForSelector(ctx, selector, namespace)
+		if ctx.Err() != nil {
+			// No new streams are started once canceled
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		for _, pod := range pods {
+			// Logs are not available until at least one container has started
+			if active[pod.Metadata.Name] || pod.Status.Phase == "Pending" {
+				continue
+			}
+
+			// A pod streamed before is only streamed again while running, ex. after a container restart, and only
+			// with the logs written since its last stream ended. A completed or failed pod has no new logs.
+			since, streamedBefore := streamEnded[pod.Metadata.Name]
+			if streamedBefore && pod.Status.Phase != "Running" {
+				continue
+			}
+
+			startStream(pod.Metadata.Name, since)
+		}
+		lock.Unlock()
+
+		if !follow {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-cli.clock.After(podDiscoveryInterval):
+		}
+
+		// The streams of deleted pods end on their own, failures are reported without stopping the other streams
+		lock.Lock()
+		for _, err := range errs {
+			log.Printf("%v", err)
+		}
+		errs = nil
+		lock.Unlock()
+	}
+
+	streamsWg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Gets the pods matching the label selector
+func (cli *kubectlCli) podsForSelector(ctx context.Context, selector string, namespace string) ([]Pod, error) {
+	res, err := cli.Exec(ctx, &KubeCliFlags{
+		Namespace: namespace,
+		Output:    OutputTypeJson,
+	}, "get", string(ResourceTypePod), "-l", selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting pods, %w", err)
+	}
+
+	var pods List[Pod]
+	if err := json.Unmarshal([]byte(res.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling pods JSON, %w", err)
+	}
+
+	return pods.Items, nil
+}
+
+// Writes the logs of all the containers of the pod to the writer, only the logs newer than since when set
+func (cli *kubectlCli) podLogs(
+	ctx context.Context,
+	podName string,
+	follow bool,
+	since time.Time,
+	namespace string,
+	out io.Writer,
+) error {
+	args := []string{"logs", podName, "--all-containers"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if !since.IsZero() {
+		args = append(args, fmt.Sprintf("--since-time=%s", since.UTC().Format(time.RFC3339)))
+	}
+
+	runArgs := exec.
+		NewRunArgs("kubectl", args...).
+		WithStdOut(out)
+
+	_, err := cli.executeCommandWithArgs(ctx, runArgs, &KubeCliFlags{Namespace: namespace})
+	return err
+}
+
+// Formats the labels as a k8s label selector, ex. 'app=api,tier=web'
+func labelSelector(labels map[string]string) string {
+	selectors := make([]string, 0, len(labels))
+	for key, value := range labels {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(selectors)
+	return strings.Join(selectors, ",")
+}
+
+// A writer that prefixes each line and only writes complete lines to the underlying writer,
+// so that lines from multiple concurrent writers sharing the same lock are never interleaved.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	lock   *sync.Mutex
+	buffer bytes.Buffer
+}
+
+func newPrefixWriter(out io.Writer, prefix string, lock *sync.Mutex) *prefixWriter {
+	return &prefixWriter{
+		out:    out,
+		prefix: prefix,
+		lock:   lock,
+	}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+
+	for {
+		index := bytes.IndexByte(w.buffer.Bytes(), '\n')
+		if index < 0 {
+			return len(p), nil
+		}
+
+		if err := w.writeLine(w.buffer.Next(index + 1)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Writes any remaining partial line
+func (w *prefixWriter) Flush() {
+	if w.buffer.Len() == 0 {
+		return
+	}
+
+	_ = w.writeLine(append(w.buffer.Bytes(), '\n'))
+	w.buffer.Reset()
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
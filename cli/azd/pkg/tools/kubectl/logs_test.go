@@ -0,0 +1,321 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+const testLogsDeploymentJson = `{
+	"apiVersion": "apps/v1",
+	"kind": "Deployment",
+	"metadata": { "name": "api", "namespace": "test" },
+	"spec": { "replicas": 2, "selector": { "matchLabels": { "app": "api", "tier": "web" } } }
+}`
+
+func testPodsJson(pods ...string) string {
+	items := make([]string, len(pods))
+	for i, pod := range pods {
+		items[i] = fmt.Sprintf(`{ "kind": "Pod", "metadata": { "name": "%s" }, "status": { "phase": "Running" } }`, pod)
+	}
+
+	return fmt.Sprintf(`{ "kind": "List", "items": [%s] }`, strings.Join(items, ","))
+}
+
+func mockPodLogs(mockContext *mocks.MockContext, podName string, lines ...string) {
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs "+podName)
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		for _, line := range lines {
+			// Write partial lines to validate lines are never split across pods
+			_, _ = args.StdOut.Write([]byte(line[:2]))
+			time.Sleep(time.Millisecond)
+			_, _ = args.StdOut.Write([]byte(line[2:] + "\n"))
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+}
+
+func Test_Logs(t *testing.T) {
+	tests := map[string]struct {
+		opts         LogOptions
+		expectedArgs []string
+	}{
+		"Default": {
+			opts:         LogOptions{},
+			expectedArgs: []string{"logs", "api-1", "-n", "test"},
+		},
+		"AllOptions": {
+			opts: LogOptions{
+				Container: "sidecar",
+				Tail:      100,
+				Since:     5 * time.Minute,
+				Previous:  true,
+			},
+			expectedArgs: []string{
+				"logs", "api-1", "--container", "sidecar", "--tail=100", "--since=5m0s", "--previous", "-n", "test",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl logs api-1")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				require.Equal(t, test.expectedArgs, args.Args)
+				return exec.NewRunResult(0, "panic: connection refused\n", ""), nil
+			})
+
+			cli := NewKubectl(mockContext.CommandRunner)
+
+			res, err := cli.Logs(*mockContext.Context, "api-1", test.opts, &KubeCliFlags{Namespace: "test"})
+			require.NoError(t, err)
+			require.Equal(t, "panic: connection refused\n", res.Stdout)
+		})
+	}
+}
+
+func Test_LogsForDeployment(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment api")
+	}).Respond(exec.NewRunResult(0, testLogsDeploymentJson, ""))
+
+	var getPodsArgs exec.RunArgs
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		getPodsArgs = args
+		return exec.NewRunResult(0, testPodsJson("api-1", "api-2"), ""), nil
+	})
+
+	mockPodLogs(mockContext, "api-1", "one: starting", "one: listening")
+	mockPodLogs(mockContext, "api-2", "two: starting", "two: listening")
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	output := &strings.Builder{}
+
+	err := cli.LogsForDeployment(*mockContext.Context, "api", false, &KubeCliFlags{Namespace: "test"}, output)
+	require.NoError(t, err)
+	require.Equal(t, []string{"get", "pods", "-l", "app=api,tier=web", "-n", "test", "-o", "json"}, getPodsArgs.Args)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.ElementsMatch(t, []string{
+		"[api-1] one: starting",
+		"[api-1] one: listening",
+		"[api-2] two: starting",
+		"[api-2] two: listening",
+	}, lines)
+}
+
+func Test_LogsForDeployment_Follow(t *testing.T) {
+	originalInterval := podDiscoveryInterval
+	podDiscoveryInterval = time.Millisecond
+	t.Cleanup(func() { podDiscoveryInterval = originalInterval })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockContext := mocks.NewMockContext(ctx)
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment api")
+	}).Respond(exec.NewRunResult(0, testLogsDeploymentJson, ""))
+
+	// The second pod is created after following starts
+	polls := 0
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		polls++
+		if polls == 1 {
+			return exec.NewRunResult(0, testPodsJson("api-1"), ""), nil
+		}
+
+		return exec.NewRunResult(0, testPodsJson("api-1", "api-2"), ""), nil
+	})
+
+	var followArgs []string
+	var lock sync.Mutex
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs api-1")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		// Keeps following until canceled so the pod isn't streamed again
+		_, _ = args.StdOut.Write([]byte("one: starting\n"))
+		<-ctx.Done()
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs api-2")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		lock.Lock()
+		followArgs = args.Args
+		lock.Unlock()
+
+		_, _ = args.StdOut.Write([]byte("two: starting\n"))
+		cancel()
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	output := &strings.Builder{}
+
+	err := cli.LogsForDeployment(*mockContext.Context, "api", true, &KubeCliFlags{Namespace: "test"}, output)
+	require.NoError(t, err)
+	require.Equal(t, []string{"logs", "api-2", "--all-containers", "--follow", "-n", "test"}, followArgs)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.ElementsMatch(t, []string{"[api-1] one: starting", "[api-2] two: starting"}, lines)
+}
+
+func Test_LogsForDeployment_FollowRestartedPod(t *testing.T) {
+	originalInterval := podDiscoveryInterval
+	podDiscoveryInterval = time.Millisecond
+	t.Cleanup(func() { podDiscoveryInterval = originalInterval })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockContext := mocks.NewMockContext(ctx)
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment api")
+	}).Respond(exec.NewRunResult(0, testLogsDeploymentJson, ""))
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).Respond(exec.NewRunResult(0, testPodsJson("api-1"), ""))
+
+	// The stream of the pod ends when its container restarts, the restarted container is streamed again with only the
+	// logs written since the first stream ended
+	streams := 0
+	var streamArgs [][]string
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs api-1")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		streams++
+		streamArgs = append(streamArgs, args.Args)
+		_, _ = args.StdOut.Write([]byte(fmt.Sprintf("stream %d\n", streams)))
+		if streams == 2 {
+			cancel()
+		}
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	output := &strings.Builder{}
+
+	err := cli.LogsForDeployment(*mockContext.Context, "api", true, &KubeCliFlags{Namespace: "test"}, output)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Equal(t, []string{"[api-1] stream 1", "[api-1] stream 2"}, lines)
+
+	require.Equal(t, []string{"logs", "api-1", "--all-containers", "--follow", "-n", "test"}, streamArgs[0])
+	require.Len(t, streamArgs[1], 7)
+	require.True(t, strings.HasPrefix(streamArgs[1][4], "--since-time="))
+	_, err = time.Parse(time.RFC3339, strings.TrimPrefix(streamArgs[1][4], "--since-time="))
+	require.NoError(t, err)
+}
+
+func Test_LogsForDeployment_FollowFailedPod(t *testing.T) {
+	originalInterval := podDiscoveryInterval
+	podDiscoveryInterval = time.Millisecond
+	t.Cleanup(func() { podDiscoveryInterval = originalInterval })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockContext := mocks.NewMockContext(ctx)
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment api")
+	}).Respond(exec.NewRunResult(0, testLogsDeploymentJson, ""))
+
+	// The evicted pod is still listed by the selector on every discovery
+	var polls atomic.Int32
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		polls.Add(1)
+		return exec.NewRunResult(0, `{ "kind": "List", "items": [
+			{ "kind": "Pod", "metadata": { "name": "api-1" }, "status": { "phase": "Failed" } },
+			{ "kind": "Pod", "metadata": { "name": "api-2" }, "status": { "phase": "Running" } }
+		] }`, ""), nil
+	})
+
+	// The stream of the failed pod ends right away, as kubectl does for terminated containers
+	mockPodLogs(mockContext, "api-1", "one: evicted")
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs api-2")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		_, _ = args.StdOut.Write([]byte("two: starting\n"))
+		for polls.Load() < 10 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	output := &strings.Builder{}
+
+	err := cli.LogsForDeployment(*mockContext.Context, "api", true, &KubeCliFlags{Namespace: "test"}, output)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.ElementsMatch(t, []string{"[api-1] one: evicted", "[api-2] two: starting"}, lines)
+}
+
+func Test_LogsForDeployment_FollowPodsError(t *testing.T) {
+	originalInterval := podDiscoveryInterval
+	podDiscoveryInterval = time.Millisecond
+	t.Cleanup(func() { podDiscoveryInterval = originalInterval })
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment api")
+	}).Respond(exec.NewRunResult(0, testLogsDeploymentJson, ""))
+
+	polls := 0
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		polls++
+		if polls == 1 {
+			return exec.NewRunResult(0, testPodsJson("api-1"), ""), nil
+		}
+
+		return exec.NewRunResult(1, "", "connection refused"), errors.New("exit code: 1")
+	})
+
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl logs api-1")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = args.StdOut.Write([]byte("one: stopping\n"))
+
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	output := &strings.Builder{}
+
+	err := cli.LogsForDeployment(*mockContext.Context, "api", true, &KubeCliFlags{Namespace: "test"}, output)
+	require.ErrorContains(t, err, "failed getting pods")
+
+	// The stream has ended before returning, nothing is written to the output afterwards
+	require.Equal(t, "[api-1] one: stopping\n", output.String())
+}
@@ -8,10 +8,13 @@ import (
 type ResourceType string
 
 const (
-	ResourceTypeDeployment ResourceType = "deployment"
-	ResourceTypeIngress    ResourceType = "ing"
-	ResourceTypeService    ResourceType = "svc"
-	KubeConfigEnvVarName   string       = "KUBECONFIG"
+	ResourceTypeDeployment    ResourceType = "deployment"
+	ResourceTypePod           ResourceType = "pods"
+	ResourceTypeIngress       ResourceType = "ing"
+	ResourceTypeService       ResourceType = "svc"
+	ResourceTypeResourceQuota ResourceType = "resourcequota"
+	ResourceTypeEvent         ResourceType = "events"
+	KubeConfigEnvVarName      string       = "KUBECONFIG"
 )
 
 type Resource struct {
@@ -20,6 +23,14 @@ type Resource struct {
 	Metadata   ResourceMetadata `json:"metadata"   yaml:"metadata"`
 }
 
+// A reference to a resource, tagged with the namespace it was found in
+type ResourceRef struct {
+	ApiVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+}
+
 type List[T any] struct {
 	Resource
 	Items []T `json:"items" yaml:"items"`
@@ -32,22 +43,71 @@ type ResourceWithSpec[T any, S any] struct {
 }
 
 type ResourceMetadata struct {
-	Name        string `json:"name"      yaml:"name"`
-	Namespace   string `json:"namespace" yaml:"namespace"`
+	Name        string `json:"name"       yaml:"name"`
+	Namespace   string `json:"namespace"  yaml:"namespace"`
+	Generation  int64  `json:"generation" yaml:"generation"`
 	Annotations map[string]any
 }
 
 type Deployment ResourceWithSpec[DeploymentSpec, DeploymentStatus]
 
 type DeploymentSpec struct {
-	Replicas int `json:"replicas" yaml:"replicas"`
+	Replicas int           `json:"replicas" yaml:"replicas"`
+	Selector LabelSelector `json:"selector" yaml:"selector"`
+}
+
+type LabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels" yaml:"matchLabels"`
 }
 
 type DeploymentStatus struct {
-	AvailableReplicas int `json:"availableReplicas" yaml:"availableReplicas"`
-	ReadyReplicas     int `json:"readyReplicas"     yaml:"readyReplicas"`
-	Replicas          int `json:"replicas"          yaml:"replicas"`
-	UpdatedReplicas   int `json:"updatedReplicas"   yaml:"updatedReplicas"`
+	AvailableReplicas  int                   `json:"availableReplicas"  yaml:"availableReplicas"`
+	ReadyReplicas      int                   `json:"readyReplicas"      yaml:"readyReplicas"`
+	Replicas           int                   `json:"replicas"           yaml:"replicas"`
+	UpdatedReplicas    int                   `json:"updatedReplicas"    yaml:"updatedReplicas"`
+	ObservedGeneration int64                 `json:"observedGeneration" yaml:"observedGeneration"`
+	Conditions         []DeploymentCondition `json:"conditions"         yaml:"conditions"`
+}
+
+type DeploymentCondition struct {
+	Type    string `json:"type"    yaml:"type"`
+	Status  string `json:"status"  yaml:"status"`
+	Reason  string `json:"reason"  yaml:"reason"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type Pod ResourceWithSpec[PodSpec, PodStatus]
+
+type PodSpec struct {
+	Containers []PodContainer `json:"containers" yaml:"containers"`
+	NodeName   string         `json:"nodeName"   yaml:"nodeName"`
+}
+
+type PodContainer struct {
+	Name  string `json:"name"  yaml:"name"`
+	Image string `json:"image" yaml:"image"`
+}
+
+type PodStatus struct {
+	Phase             string               `json:"phase"             yaml:"phase"`
+	Reason            string               `json:"reason"            yaml:"reason"`
+	ContainerStatuses []PodContainerStatus `json:"containerStatuses" yaml:"containerStatuses"`
+}
+
+type PodContainerStatus struct {
+	Name         string                  `json:"name"         yaml:"name"`
+	Ready        bool                    `json:"ready"        yaml:"ready"`
+	RestartCount int                     `json:"restartCount" yaml:"restartCount"`
+	State        PodContainerStatusState `json:"state"        yaml:"state"`
+}
+
+type PodContainerStatusState struct {
+	Waiting    *PodContainerStateReason `json:"waiting"    yaml:"waiting"`
+	Terminated *PodContainerStateReason `json:"terminated" yaml:"terminated"`
+}
+
+type PodContainerStateReason struct {
+	Reason string `json:"reason" yaml:"reason"`
 }
 
 type Ingress ResourceWithSpec[IngressSpec, IngressStatus]
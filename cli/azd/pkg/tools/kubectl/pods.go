@@ -0,0 +1,81 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// A summary of the status of a pod, ex. to report the pods that are failing after a rollout
+type PodSummary struct {
+	Name string
+	// The pod phase, ex. 'Pending', 'Running' or 'Failed'
+	Phase string
+	// Why the pod or one of its containers is not running, ex. 'CrashLoopBackOff' or 'ImagePullBackOff'.
+	// Empty when all the containers are running.
+	Reason string
+	// The number of containers that are ready
+	ReadyContainers int
+	// The number of containers of the pod
+	TotalContainers int
+	// The total number of container restarts
+	Restarts int
+	// The node the pod is scheduled on, empty when not yet scheduled
+	Node string
+}
+
+// Gets a summary of the status of each pod within the namespace of the flags
+func (cli *kubectlCli) GetPods(ctx context.Context, flags *KubeCliFlags) ([]PodSummary, error) {
+	getFlags := &KubeCliFlags{}
+	if flags != nil {
+		*getFlags = *flags
+	}
+	getFlags.Output = OutputTypeJson
+
+	res, err := cli.Exec(ctx, getFlags, "get", string(ResourceTypePod))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting pods, %w", err)
+	}
+
+	var pods List[Pod]
+	if err := json.Unmarshal([]byte(res.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling pods JSON, %w", err)
+	}
+
+	summaries := make([]PodSummary, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		summaries = append(summaries, summarizePod(pod))
+	}
+
+	return summaries, nil
+}
+
+func summarizePod(pod Pod) PodSummary {
+	summary := PodSummary{
+		Name:            pod.Metadata.Name,
+		Phase:           pod.Status.Phase,
+		Reason:          pod.Status.Reason,
+		TotalContainers: len(pod.Spec.Containers),
+		Node:            pod.Spec.NodeName,
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Ready {
+			summary.ReadyContainers++
+		}
+
+		summary.Restarts += status.RestartCount
+
+		// The reason of the first container that is not running is reported, same as 'kubectl get pods'
+		if summary.Reason == "" {
+			switch {
+			case status.State.Waiting != nil:
+				summary.Reason = status.State.Waiting.Reason
+			case status.State.Terminated != nil:
+				summary.Reason = status.State.Terminated.Reason
+			}
+		}
+	}
+
+	return summary
+}
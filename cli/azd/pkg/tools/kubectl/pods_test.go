@@ -0,0 +1,55 @@
+package kubectl
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetPods(t *testing.T) {
+	podsJson, err := os.ReadFile("../../../test/testdata/k8s/parse/pods.json")
+	require.NoError(t, err)
+
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = args
+		return exec.NewRunResult(0, string(podsJson), ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	pods, err := cli.GetPods(*mockContext.Context, &KubeCliFlags{Namespace: "todo"})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"get", "pods", "-n", "todo", "-o", "json"}, runArgs.Args)
+	require.Equal(t, []PodSummary{
+		{
+			Name:            "api-7c9d8f6b5d-x2k4q",
+			Phase:           "Running",
+			ReadyContainers: 1,
+			TotalContainers: 1,
+			Node:            "aks-system-12345678-vmss000000",
+		},
+		{
+			Name:            "web-5d4f7b9c8-m7zpw",
+			Phase:           "Running",
+			Reason:          "CrashLoopBackOff",
+			ReadyContainers: 1,
+			TotalContainers: 2,
+			Restarts:        5,
+			Node:            "aks-system-12345678-vmss000001",
+		},
+		{
+			Name:            "worker-6b8c7d5f4-q9r2t",
+			Phase:           "Pending",
+			TotalContainers: 1,
+		},
+	}, pods)
+}
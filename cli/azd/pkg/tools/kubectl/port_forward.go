@@ -0,0 +1,148 @@
+package kubectl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// A long running kubectl port-forward started by PortForward
+type PortForwardSession interface {
+	// Closed once forwarding is established for all the ports
+	Ready() <-chan struct{}
+	// Closed once kubectl exits, either because the session was stopped or because forwarding failed
+	Done() <-chan struct{}
+	// Gets the error kubectl exited with, nil while running or when the session was stopped
+	Err() error
+	// Stops forwarding and waits for kubectl to exit
+	Stop() error
+}
+
+// Matches the line kubectl prints once a port is forwarded, ex. 'Forwarding from 127.0.0.1:8080 -> 80'
+var forwardingRegex = regexp.MustCompile(`^Forwarding from \S+ -> (\d+)`)
+
+type portForwardSession struct {
+	cancel context.CancelFunc
+	ready  chan struct{}
+	done   chan struct{}
+
+	lock    sync.Mutex
+	err     error
+	stopped bool
+}
+
+// Forwards the local ports to the resource, ex. 'svc/api' or 'pod/api-7d9f', until the session is stopped or the
+// context is canceled. Ports are formatted as '<local>:<remote>', '<port>' to use the same port or ':<remote>' to use
+// a random local port.
+//
+// Returns once kubectl is started, without waiting for forwarding to be established. Use the ready channel of the
+// session to wait until the ports can be used.
+func (cli *kubectlCli) PortForward(
+	ctx context.Context,
+	resource string,
+	ports []string,
+	flags *KubeCliFlags,
+) (PortForwardSession, error) {
+	if len(ports) == 0 {
+		return nil, errors.New("at least one port is required to port-forward")
+	}
+
+	remotePorts := map[string]bool{}
+	for _, port := range ports {
+		remotePorts[port[strings.LastIndex(port, ":")+1:]] = true
+	}
+
+	forwardFlags := &KubeCliFlags{}
+	if flags != nil {
+		forwardFlags.Namespace = flags.Namespace
+	}
+
+	forwardCtx, cancel := context.WithCancel(ctx)
+	session := &portForwardSession{
+		cancel: cancel,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	reader, writer := io.Pipe()
+	go session.watchOutput(reader, remotePorts)
+
+	runArgs := exec.
+		NewRunArgs("kubectl", append([]string{"port-forward", resource}, ports...)...).
+		WithStdOut(writer)
+
+	go func() {
+		defer close(session.done)
+
+		_, err := cli.executeCommandWithArgs(forwardCtx, runArgs, forwardFlags)
+		writer.Close()
+
+		session.lock.Lock()
+		defer session.lock.Unlock()
+
+		// Stopping the session or canceling the context are the expected ways for kubectl to exit
+		switch {
+		case session.stopped || ctx.Err() != nil:
+		case err != nil:
+			session.err = fmt.Errorf("kubectl port-forward %s: %w", resource, err)
+		default:
+			session.err = fmt.Errorf("kubectl port-forward %s exited unexpectedly", resource)
+		}
+	}()
+
+	return session, nil
+}
+
+// Closes the ready channel once kubectl reports forwarding for all the remote ports
+func (s *portForwardSession) watchOutput(reader io.Reader, remotePorts map[string]bool) {
+	forwarded := map[string]bool{}
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		matches := forwardingRegex.FindStringSubmatch(scanner.Text())
+		if matches == nil || forwarded[matches[1]] || !remotePorts[matches[1]] {
+			continue
+		}
+
+		forwarded[matches[1]] = true
+		if len(forwarded) == len(remotePorts) {
+			close(s.ready)
+		}
+	}
+
+	// Keeps draining the output so kubectl never blocks writing to it
+	_, _ = io.Copy(io.Discard, reader)
+}
+
+func (s *portForwardSession) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func (s *portForwardSession) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *portForwardSession) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+func (s *portForwardSession) Stop() error {
+	s.lock.Lock()
+	s.stopped = true
+	s.lock.Unlock()
+
+	s.cancel()
+	<-s.done
+
+	return s.Err()
+}
@@ -0,0 +1,125 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PortForward(t *testing.T) {
+	output := strings.Join([]string{
+		"Forwarding from 127.0.0.1:8080 -> 80",
+		"Forwarding from [::1]:8080 -> 80",
+		"Forwarding from 127.0.0.1:9090 -> 9090",
+		"Forwarding from [::1]:9090 -> 9090",
+	}, "\n") + "\n"
+
+	// Simulates kubectl, writing the output and then running until released
+	setup := func(output string, exitErr error) (*mocks.MockContext, *exec.RunArgs, chan struct{}) {
+		var runArgs exec.RunArgs
+		release := make(chan struct{})
+
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl port-forward")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			runArgs = args
+			_, _ = args.StdOut.Write([]byte(output))
+			<-release
+
+			return exec.NewRunResult(1, "", ""), exitErr
+		})
+
+		return mockContext, &runArgs, release
+	}
+
+	waitFor := func(t *testing.T, ch <-chan struct{}) {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for the port-forward")
+		}
+	}
+
+	// Stops the session, releasing kubectl once it is killed
+	stop := func(t *testing.T, session PortForwardSession, release chan struct{}) error {
+		stopErr := make(chan error, 1)
+		go func() {
+			stopErr <- session.Stop()
+		}()
+
+		forwardSession := session.(*portForwardSession)
+		require.Eventually(t, func() bool {
+			forwardSession.lock.Lock()
+			defer forwardSession.lock.Unlock()
+			return forwardSession.stopped
+		}, 5*time.Second, time.Millisecond)
+
+		close(release)
+		return <-stopErr
+	}
+
+	t.Run("ReadyAndStop", func(t *testing.T) {
+		mockContext, runArgs, release := setup(output, errors.New("signal: killed"))
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		session, err := cli.PortForward(
+			*mockContext.Context, "svc/api", []string{"8080:80", "9090"}, &KubeCliFlags{Namespace: "app"})
+		require.NoError(t, err)
+
+		waitFor(t, session.Ready())
+		require.Equal(t, []string{"port-forward", "svc/api", "8080:80", "9090", "-n", "app"}, runArgs.Args)
+
+		require.NoError(t, stop(t, session, release))
+		waitFor(t, session.Done())
+		require.NoError(t, session.Err())
+	})
+
+	t.Run("NotReadyUntilAllPorts", func(t *testing.T) {
+		mockContext, _, release := setup("Forwarding from 127.0.0.1:8080 -> 80\n", errors.New("signal: killed"))
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		session, err := cli.PortForward(*mockContext.Context, "svc/api", []string{"8080:80", ":9090"}, nil)
+		require.NoError(t, err)
+
+		select {
+		case <-session.Ready():
+			require.Fail(t, "the session should not be ready before all the ports are forwarded")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, stop(t, session, release))
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		mockContext, _, release := setup("", errors.New("pods \"api\" not found"))
+		close(release)
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		session, err := cli.PortForward(*mockContext.Context, "pod/api", []string{"8080"}, nil)
+		require.NoError(t, err)
+
+		waitFor(t, session.Done())
+		require.ErrorContains(t, session.Err(), "pods \"api\" not found")
+
+		select {
+		case <-session.Ready():
+			require.Fail(t, "the session should never be ready")
+		default:
+		}
+	})
+
+	t.Run("NoPorts", func(t *testing.T) {
+		mockContext, _, _ := setup("", nil)
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		_, err := cli.PortForward(*mockContext.Context, "svc/api", nil, nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,273 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// The resource types considered for pruning when none are specified
+var defaultPruneResourceTypes = []string{
+	"deployments",
+	"statefulsets",
+	"daemonsets",
+	"services",
+	"ingresses",
+	"configmaps",
+	"secrets",
+}
+
+// Resources created more recently than this are not pruned by default
+const defaultPruneMinAge = 5 * time.Minute
+
+// Optional settings used when pruning the resources no longer defined by a set of manifests
+type PruneOptions struct {
+	// The resource types considered for pruning, defaults to common workload, networking and config types
+	ResourceTypes []string
+	// Resources created more recently than this are never pruned, since they may have just been created by another
+	// process that is applying its own manifests to the namespace. Defaults to 5 minutes.
+	MinAge time.Duration
+}
+
+// The subset of a resource used to decide whether it can be pruned
+type prunableResource struct {
+	ApiVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace"`
+		Labels            map[string]string `json:"labels"`
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+	} `json:"metadata"`
+}
+
+func (r prunableResource) resource() Resource {
+	return Resource{
+		ApiVersion: r.ApiVersion,
+		Kind:       r.Kind,
+		Metadata: ResourceMetadata{
+			Name:      r.Metadata.Name,
+			Namespace: r.Metadata.Namespace,
+		},
+	}
+}
+
+// Deletes the resources matching the label selector that are not defined by the manifests at the specified path and
+// returns the pruned resources.
+//
+// Another process may create resources matching the selector while pruning, so before deleting a candidate it is
+// fetched again to verify it still matches the selector and that it is older than the minimum age.
+// Candidates that fail either check are skipped.
+func (cli *kubectlCli) Prune(
+	ctx context.Context,
+	path string,
+	selector string,
+	flags *KubeCliFlags,
+	options *PruneOptions,
+) ([]Resource, error) {
+	if options == nil {
+		options = &PruneOptions{}
+	}
+
+	resourceTypes := options.ResourceTypes
+	if len(resourceTypes) == 0 {
+		resourceTypes = defaultPruneResourceTypes
+	}
+
+	minAge := options.MinAge
+	if minAge == 0 {
+		minAge = defaultPruneMinAge
+	}
+
+	requirements, err := parseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := ""
+	if flags != nil {
+		namespace = flags.Namespace
+	}
+
+	// Resources listed from the cluster always have a namespace, which is needed to match the manifests without one
+	if namespace == "" {
+		namespace, err = cli.CurrentNamespace(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifests, err := cli.readManifests(path, flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading manifests, %w", err)
+	}
+
+	desired := map[string]bool{}
+	for _, manifest := range manifests {
+		resources, err := parseManifestResources(manifest.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing manifest '%s', %w", manifest.Path, err)
+		}
+
+		for _, resource := range resources {
+			desired[pruneKey(resource, namespace)] = true
+		}
+	}
+
+	res, err := cli.Exec(ctx, &KubeCliFlags{
+		Namespace: namespace,
+		Output:    OutputTypeJson,
+	}, "get", strings.Join(resourceTypes, ","), "-l", selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing prune candidates, %w", err)
+	}
+
+	var candidates List[prunableResource]
+	if err := json.Unmarshal([]byte(res.Stdout), &candidates); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling prune candidates JSON, %w", err)
+	}
+
+	pruned := []Resource{}
+	for _, candidate := range candidates.Items {
+		resource := candidate.resource()
+		if desired[pruneKey(resource, namespace)] {
+			continue
+		}
+
+		current, err := cli.getPrunableResource(ctx, resource, namespace)
+		if err != nil {
+			return pruned, err
+		}
+
+		switch {
+		case current == nil:
+			continue
+		case !requirements.matches(current.Metadata.Labels):
+			log.Printf(
+				"skipping prune of %s '%s', it no longer matches selector '%s'",
+				resource.Kind, resource.Metadata.Name, selector)
+			continue
+		case cli.clock.Since(current.Metadata.CreationTimestamp) < minAge:
+			log.Printf(
+				"skipping prune of %s '%s', it was created less than %s ago",
+				resource.Kind, resource.Metadata.Name, minAge)
+			continue
+		}
+
+		deleteFlags := &KubeCliFlags{Namespace: namespace}
+		if resource.Metadata.Namespace != "" {
+			deleteFlags.Namespace = resource.Metadata.Namespace
+		}
+
+		_, err = cli.Exec(
+			ctx, deleteFlags, "delete", resourceTypeName(resource), resource.Metadata.Name, "--ignore-not-found")
+		if err != nil {
+			return pruned, fmt.Errorf("failed pruning %s '%s', %w", resource.Kind, resource.Metadata.Name, err)
+		}
+
+		pruned = append(pruned, resource)
+	}
+
+	return pruned, nil
+}
+
+// Gets the current state of the resource, nil when it no longer exists
+func (cli *kubectlCli) getPrunableResource(
+	ctx context.Context,
+	resource Resource,
+	namespace string,
+) (*prunableResource, error) {
+	if resource.Metadata.Namespace != "" {
+		namespace = resource.Metadata.Namespace
+	}
+
+	res, err := cli.Exec(ctx, &KubeCliFlags{
+		Namespace: namespace,
+		Output:    OutputTypeJson,
+	}, "get", resourceTypeName(resource), resource.Metadata.Name, "--ignore-not-found")
+	if err != nil {
+		return nil, fmt.Errorf("failed getting %s '%s', %w", resource.Kind, resource.Metadata.Name, err)
+	}
+
+	if strings.TrimSpace(res.Stdout) == "" {
+		return nil, nil
+	}
+
+	var current prunableResource
+	if err := json.Unmarshal([]byte(res.Stdout), &current); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling %s '%s' JSON, %w", resource.Kind, resource.Metadata.Name, err)
+	}
+
+	return &current, nil
+}
+
+// Identifies a resource by kind, namespace and name, using the specified namespace for resources without one
+func pruneKey(resource Resource, namespace string) string {
+	if resource.Metadata.Namespace != "" {
+		namespace = resource.Metadata.Namespace
+	}
+
+	return fmt.Sprintf("%s/%s/%s", strings.ToLower(resource.Kind), namespace, resource.Metadata.Name)
+}
+
+// A single requirement of an equality based label selector, ex. 'app=api', 'tier!=web' or '!canary'
+type labelRequirement struct {
+	key      string
+	value    string
+	negate   bool
+	existsOp bool
+}
+
+type labelRequirements []labelRequirement
+
+// Parses an equality based label selector. Set based selectors (ex. 'env in (dev,test)') are not supported.
+func parseLabelSelector(selector string) (labelRequirements, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, fmt.Errorf("a label selector is required to prune resources")
+	}
+
+	requirements := labelRequirements{}
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if strings.ContainsAny(term, "() ") {
+			return nil, fmt.Errorf("unsupported label selector '%s', only equality based selectors are supported", selector)
+		}
+
+		if key, value, has := strings.Cut(term, "!="); has {
+			requirements = append(requirements, labelRequirement{key: key, value: value, negate: true})
+		} else if key, value, has := strings.Cut(term, "=="); has {
+			requirements = append(requirements, labelRequirement{key: key, value: value})
+		} else if key, value, has := strings.Cut(term, "="); has {
+			requirements = append(requirements, labelRequirement{key: key, value: value})
+		} else if key, has := strings.CutPrefix(term, "!"); has {
+			requirements = append(requirements, labelRequirement{key: key, existsOp: true, negate: true})
+		} else {
+			requirements = append(requirements, labelRequirement{key: term, existsOp: true})
+		}
+	}
+
+	return requirements, nil
+}
+
+// Gets whether the labels satisfy all the requirements
+func (requirements labelRequirements) matches(labels map[string]string) bool {
+	for _, requirement := range requirements {
+		value, has := labels[requirement.key]
+
+		var match bool
+		if requirement.existsOp {
+			match = has
+		} else {
+			match = has && value == requirement.value
+		}
+
+		if match == requirement.negate {
+			return false
+		}
+	}
+
+	return true
+}
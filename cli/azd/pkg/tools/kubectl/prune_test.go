@@ -0,0 +1,138 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Prune(t *testing.T) {
+	tempDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(tempDir, "deployment.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  labels:
+    app.kubernetes.io/managed-by: azd
+`), osutil.PermissionFile)
+	require.NoError(t, err)
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.AdvanceTime(365 * 24 * time.Hour)
+	now := mockContext.Clock.Now()
+
+	old := now.Add(-time.Hour).UTC().Format(time.RFC3339)
+	recent := now.Add(-10*time.Minute + time.Second).UTC().Format(time.RFC3339)
+	minAge := now.Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+	resourceJson := func(name string, created string, managedBy string) string {
+		return fmt.Sprintf(`{
+			"apiVersion": "apps/v1",
+			"kind": "Deployment",
+			"metadata": {
+				"name": "%s",
+				"namespace": "app",
+				"labels": {"app.kubernetes.io/managed-by": "%s"},
+				"creationTimestamp": "%s"
+			}
+		}`, name, managedBy, created)
+	}
+
+	// The state of each resource when it is fetched again before pruning
+	current := map[string]string{
+		"old-worker": resourceJson("old-worker", old, "azd"),
+		"new-worker": resourceJson("new-worker", recent, "azd"),
+		"min-age":    resourceJson("min-age", minAge, "azd"),
+		"relabeled":  resourceJson("relabeled", old, "helm"),
+		"gone":       "",
+	}
+
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployments -l app.kubernetes.io/managed-by=azd")
+	}).Respond(exec.NewRunResult(0, fmt.Sprintf(`{"items": [%s, %s, %s, %s, %s, %s]}`,
+		resourceJson("api", old, "azd"),
+		resourceJson("old-worker", old, "azd"),
+		resourceJson("new-worker", old, "azd"),
+		resourceJson("min-age", old, "azd"),
+		resourceJson("relabeled", old, "azd"),
+		resourceJson("gone", old, "azd"),
+	), ""))
+
+	fetched := []string{}
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl get deployment.v1.apps")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		fetched = append(fetched, args.Args[2])
+		return exec.NewRunResult(0, current[args.Args[2]], ""), nil
+	})
+
+	deleted := []string{}
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl delete")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		deleted = append(deleted, args.Args[2])
+		return exec.NewRunResult(0, "", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	cli.(*kubectlCli).clock = mockContext.Clock
+	pruned, err := cli.Prune(
+		*mockContext.Context,
+		tempDir,
+		"app.kubernetes.io/managed-by=azd",
+		&KubeCliFlags{Namespace: "app"},
+		&PruneOptions{ResourceTypes: []string{"deployments"}, MinAge: 10 * time.Minute},
+	)
+	require.NoError(t, err)
+
+	// The desired resource is never considered, the recent and relabeled resources are skipped.
+	// A resource created exactly the minimum age ago is pruned.
+	require.Equal(t, []string{"old-worker", "new-worker", "min-age", "relabeled", "gone"}, fetched)
+	require.Equal(t, []string{"old-worker", "min-age"}, deleted)
+	require.Len(t, pruned, 2)
+	require.Equal(t, "old-worker", pruned[0].Metadata.Name)
+	require.Equal(t, "min-age", pruned[1].Metadata.Name)
+}
+
+func Test_LabelSelector(t *testing.T) {
+	labels := map[string]string{"app": "api", "tier": "web"}
+
+	tests := map[string]bool{
+		"app=api":           true,
+		"app==api":          true,
+		"app=api,tier=web":  true,
+		"app=api,tier=db":   false,
+		"tier!=db":          true,
+		"tier!=web":         false,
+		"missing!=value":    true,
+		"app":               true,
+		"missing":           false,
+		"!canary":           true,
+		"!tier":             false,
+		"app=api, tier=web": true,
+	}
+
+	for selector, expected := range tests {
+		t.Run(selector, func(t *testing.T) {
+			requirements, err := parseLabelSelector(selector)
+			require.NoError(t, err)
+			require.Equal(t, expected, requirements.matches(labels))
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := parseLabelSelector("env in (dev,test)")
+		require.Error(t, err)
+
+		_, err = parseLabelSelector("")
+		require.Error(t, err)
+	})
+}
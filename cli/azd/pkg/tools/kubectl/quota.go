@@ -0,0 +1,407 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrQuotaExceeded = errors.New("resource quota exceeded")
+
+type QuotaCheckMode string
+
+const (
+	// Resource quotas are not checked before applying manifests
+	QuotaCheckNone QuotaCheckMode = ""
+	// Logs a warning when applying manifests would exceed a resource quota
+	QuotaCheckWarn QuotaCheckMode = "warn"
+	// Fails the apply when applying manifests would exceed a resource quota
+	QuotaCheckError QuotaCheckMode = "error"
+)
+
+type ResourceQuota ResourceWithSpec[ResourceQuotaSpec, ResourceQuotaStatus]
+
+type ResourceQuotaSpec struct {
+	Hard map[string]string `json:"hard" yaml:"hard"`
+}
+
+type ResourceQuotaStatus struct {
+	Hard map[string]string `json:"hard" yaml:"hard"`
+	Used map[string]string `json:"used" yaml:"used"`
+}
+
+// A resource quota that would be exceeded by applying a set of manifests
+type QuotaViolation struct {
+	// The name of the ResourceQuota
+	Quota string
+	// The quota resource name, ex. requests.cpu
+	Resource  string
+	Hard      float64
+	Used      float64
+	Requested float64
+}
+
+func (v QuotaViolation) String() string {
+	return fmt.Sprintf(
+		"quota '%s' %s: used %s + requested %s exceeds hard limit %s",
+		v.Quota,
+		v.Resource,
+		formatQuantity(v.Used),
+		formatQuantity(v.Requested),
+		formatQuantity(v.Hard),
+	)
+}
+
+// A manifest read from disk, rendered when it is a template
+type manifestContent struct {
+	Path    string
+	Content string
+	// Whether the content was rendered from a template
+	Rendered bool
+}
+
+// The subset of a workload manifest used to compute the resources requested by its pods
+type workloadManifest struct {
+	Resource `yaml:",inline"`
+	Spec     struct {
+		Replicas    *int `yaml:"replicas"`
+		Parallelism *int `yaml:"parallelism"`
+		podSpec     `yaml:",inline"`
+		Template    struct {
+			Spec podSpec `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type podSpec struct {
+	Containers []struct {
+		Resources struct {
+			Requests map[string]string `yaml:"requests"`
+			Limits   map[string]string `yaml:"limits"`
+		} `yaml:"resources"`
+	} `yaml:"containers"`
+}
+
+// Compares the resources requested by the manifests with the namespace resource quotas.
+// This is a best-effort check: all resources within the manifests are treated as new usage, so updating existing
+// workloads can over-estimate the resulting usage, and only cpu, memory & object counts are considered.
+func (cli *kubectlCli) checkResourceQuota(ctx context.Context, manifests []manifestContent, flags *KubeCliFlags) error {
+	if flags == nil || flags.QuotaCheck == QuotaCheckNone {
+		return nil
+	}
+
+	quotas, err := GetResources[ResourceQuota](ctx, cli, ResourceTypeResourceQuota, &KubeCliFlags{
+		Namespace: flags.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed getting resource quotas, %w", err)
+	}
+
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+
+	requested := map[string]float64{}
+	for _, manifest := range manifests {
+		if err := addRequestedResources(requested, manifest.Content, flags.Namespace); err != nil {
+			return fmt.Errorf("failed reading requested resources from '%s', %w", manifest.Path, err)
+		}
+	}
+
+	violations := quotaViolations(quotas.Items, requested)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.String()
+	}
+
+	if flags.QuotaCheck == QuotaCheckError {
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, strings.Join(messages, "; "))
+	}
+
+	for _, message := range messages {
+		log.Printf("warning: applying manifests may exceed resource quota, %s", message)
+	}
+
+	return nil
+}
+
+// Sums the resources requested by all the documents within the manifest into the requested map
+func addRequestedResources(requested map[string]float64, manifest string, namespace string) error {
+	decoder := yaml.NewDecoder(bytes.NewBufferString(manifest))
+
+	for {
+		var workload workloadManifest
+		err := decoder.Decode(&workload)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if workload.Kind == "" {
+			continue
+		}
+
+		// Resources targeting other namespaces are not subject to this namespace's quota
+		if namespace != "" && workload.Metadata.Namespace != "" && workload.Metadata.Namespace != namespace {
+			continue
+		}
+
+		switch workload.Kind {
+		case "Service":
+			requested["services"]++
+		case "ConfigMap":
+			requested["configmaps"]++
+		case "Secret":
+			requested["secrets"]++
+		case "PersistentVolumeClaim":
+			requested["persistentvolumeclaims"]++
+		}
+
+		var pods int
+		var spec podSpec
+
+		switch workload.Kind {
+		case "Pod":
+			pods, spec = 1, workload.Spec.podSpec
+		case "Deployment", "StatefulSet", "ReplicaSet":
+			pods, spec = valueOrDefault(workload.Spec.Replicas, 1), workload.Spec.Template.Spec
+		case "Job":
+			pods, spec = valueOrDefault(workload.Spec.Parallelism, 1), workload.Spec.Template.Spec
+		default:
+			continue
+		}
+
+		requested["pods"] += float64(pods)
+
+		for _, container := range spec.Containers {
+			for name, value := range container.Resources.Requests {
+				quantity, err := parseQuantity(value)
+				if err != nil {
+					return err
+				}
+
+				requested["requests."+name] += quantity * float64(pods)
+			}
+
+			for name, value := range container.Resources.Limits {
+				quantity, err := parseQuantity(value)
+				if err != nil {
+					return err
+				}
+
+				requested["limits."+name] += quantity * float64(pods)
+			}
+		}
+	}
+}
+
+// Finds all the quota resources where the current usage plus the requested amount exceeds the hard limit
+func quotaViolations(quotas []ResourceQuota, requested map[string]float64) []QuotaViolation {
+	violations := []QuotaViolation{}
+
+	for _, quota := range quotas {
+		hardLimits := quota.Status.Hard
+		if len(hardLimits) == 0 {
+			hardLimits = quota.Spec.Hard
+		}
+
+		for resource, hardValue := range hardLimits {
+			// 'cpu' and 'memory' quotas are equivalent to 'requests.cpu' and 'requests.memory'
+			requestedAmount, has := requested[resource]
+			if !has && (resource == "cpu" || resource == "memory") {
+				requestedAmount, has = requested["requests."+resource]
+			}
+			if !has {
+				continue
+			}
+
+			hard, err := parseQuantity(hardValue)
+			if err != nil {
+				log.Printf("failed parsing quota '%s' %s hard limit: %v", quota.Metadata.Name, resource, err)
+				continue
+			}
+
+			var used float64
+			if usedValue, has := quota.Status.Used[resource]; has {
+				used, err = parseQuantity(usedValue)
+				if err != nil {
+					log.Printf("failed parsing quota '%s' %s usage: %v", quota.Metadata.Name, resource, err)
+					continue
+				}
+			}
+
+			if used+requestedAmount > hard {
+				violations = append(violations, QuotaViolation{
+					Quota:     quota.Metadata.Name,
+					Resource:  resource,
+					Hard:      hard,
+					Used:      used,
+					Requested: requestedAmount,
+				})
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Quota != violations[j].Quota {
+			return violations[i].Quota < violations[j].Quota
+		}
+
+		return violations[i].Resource < violations[j].Resource
+	})
+
+	return violations
+}
+
+var quantitySuffixes = map[string]float64{
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// Parses a k8s resource quantity (ex. 500m, 128Mi, 2) into its numeric value
+func parseQuantity(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	for _, suffixLength := range []int{2, 1} {
+		if len(value) <= suffixLength {
+			continue
+		}
+
+		multiplier, has := quantitySuffixes[value[len(value)-suffixLength:]]
+		if !has {
+			continue
+		}
+
+		number, err := strconv.ParseFloat(value[:len(value)-suffixLength], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid quantity '%s', %w", value, err)
+		}
+
+		return number * multiplier, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity '%s', %w", value, err)
+	}
+
+	return number, nil
+}
+
+func formatQuantity(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func valueOrDefault(value *int, defaultValue int) int {
+	if value == nil {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// Reads the k8s manifests at the specified path, rendering template files.
+// The path is either a single manifest file or a directory, which is read recursively.
+func (cli *kubectlCli) readManifests(path string, flags *KubeCliFlags) ([]manifestContent, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading path '%s', %w", path, err)
+	}
+
+	// A file explicitly specified by the user is always applied, regardless of its extension
+	if !info.IsDir() {
+		_, isTemplateFile := manifestFileType(info.Name())
+		manifest, err := cli.readManifest(path, isTemplateFile, flags)
+		if err != nil {
+			return nil, err
+		}
+
+		return []manifestContent{manifest}, nil
+	}
+
+	return cli.readManifestsInDirectory(path, flags)
+}
+
+// Recursively reads all the k8s manifests within the directory, rendering template files
+func (cli *kubectlCli) readManifestsInDirectory(directoryPath string, flags *KubeCliFlags) ([]manifestContent, error) {
+	entries, err := os.ReadDir(directoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading files in path, '%s', %w", directoryPath, err)
+	}
+
+	manifests := []manifestContent{}
+	for _, entry := range entries {
+		entryPath := filepath.Join(directoryPath, entry.Name())
+
+		if entry.IsDir() {
+			children, err := cli.readManifestsInDirectory(entryPath, flags)
+			if err != nil {
+				return nil, err
+			}
+
+			manifests = append(manifests, children...)
+			continue
+		}
+
+		isManifest, isTemplateFile := manifestFileType(entry.Name())
+		if !isManifest {
+			continue
+		}
+
+		manifest, err := cli.readManifest(entryPath, isTemplateFile, flags)
+		if err != nil {
+			return nil, err
+		}
+
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// Reads the k8s manifest file, rendering it when it is a template or when all manifests are rendered
+func (cli *kubectlCli) readManifest(filePath string, isTemplateFile bool, flags *KubeCliFlags) (manifestContent, error) {
+	rendered := isTemplateFile || (flags != nil && flags.RenderTemplates)
+	if rendered {
+		content, err := cli.renderTemplate(filePath, flags != nil && flags.StrictEnvSubst)
+		if err != nil {
+			return manifestContent{}, err
+		}
+
+		return manifestContent{Path: filePath, Content: content, Rendered: true}, nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return manifestContent{}, fmt.Errorf("failed reading file '%s', %w", filePath, err)
+	}
+
+	return manifestContent{Path: filePath, Content: string(content)}, nil
+}
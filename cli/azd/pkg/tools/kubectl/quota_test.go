@@ -0,0 +1,146 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+const testQuotaJson = `{
+	"apiVersion": "v1",
+	"kind": "List",
+	"items": [{
+		"apiVersion": "v1",
+		"kind": "ResourceQuota",
+		"metadata": { "name": "compute", "namespace": "test" },
+		"spec": { "hard": { "requests.cpu": "2", "requests.memory": "2Gi", "pods": "10" } },
+		"status": {
+			"hard": { "requests.cpu": "2", "requests.memory": "2Gi", "pods": "10" },
+			"used": { "requests.cpu": "1500m", "requests.memory": "512Mi", "pods": "3" }
+		}
+	}]
+}`
+
+const testQuotaDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: {{ .Env.REPLICAS }}
+  template:
+    spec:
+      containers:
+        - name: api
+          image: api:latest
+          resources:
+            requests:
+              cpu: 250m
+              memory: 256Mi
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+`
+
+func Test_Apply_QuotaCheck(t *testing.T) {
+	tests := map[string]struct {
+		mode        QuotaCheckMode
+		replicas    string
+		expectError bool
+		expectApply bool
+	}{
+		"WithinQuota": {
+			mode:        QuotaCheckError,
+			replicas:    "2",
+			expectApply: true,
+		},
+		"ExceedsQuotaError": {
+			mode:        QuotaCheckError,
+			replicas:    "3",
+			expectError: true,
+		},
+		"ExceedsQuotaWarn": {
+			mode:        QuotaCheckWarn,
+			replicas:    "3",
+			expectApply: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			err := os.WriteFile(
+				filepath.Join(tempDir, "deployment.tmpl.yaml"),
+				[]byte(testQuotaDeployment),
+				osutil.PermissionFile,
+			)
+			require.NoError(t, err)
+
+			applied := false
+			mockContext := mocks.NewMockContext(context.Background())
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl get resourcequota")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				require.Contains(t, args.Args, "test")
+				return exec.NewRunResult(0, testQuotaJson, ""), nil
+			})
+			mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+				return strings.Contains(command, "kubectl apply")
+			}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+				applied = true
+				return exec.NewRunResult(0, "", ""), nil
+			})
+
+			cli := NewKubectl(mockContext.CommandRunner)
+			cli.SetEnv(map[string]string{"REPLICAS": test.replicas})
+
+			_, err = cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{
+				Namespace:  "test",
+				QuotaCheck: test.mode,
+			})
+
+			if test.expectError {
+				require.Error(t, err)
+				require.True(t, errors.Is(err, ErrQuotaExceeded))
+				require.Contains(t, err.Error(), "requests.cpu")
+				require.NotContains(t, err.Error(), "requests.memory")
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, test.expectApply, applied)
+		})
+	}
+}
+
+func Test_ParseQuantity(t *testing.T) {
+	tests := map[string]float64{
+		"2":     2,
+		"500m":  0.5,
+		"1.5":   1.5,
+		"1k":    1000,
+		"128Mi": 128 * 1024 * 1024,
+		"1Gi":   1024 * 1024 * 1024,
+		"2G":    2e9,
+	}
+
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			actual, err := parseQuantity(value)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+
+	_, err := parseQuantity("lots")
+	require.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package kubectl
+
+import (
+	"context"
+)
+
+// A k8s manifest as it would be applied, after rendering its template
+type RenderedManifest struct {
+	// The path of the manifest file
+	Path string
+	// The manifest content, with the environment variables substituted when the file is a template
+	Content string
+	// Whether the content was rendered from a template
+	Rendered bool
+}
+
+// Renders the manifest file, or all the manifest files within the directory, at the specified path without invoking
+// kubectl, so the exact manifests that Apply would apply can be inspected before touching a cluster.
+// Templates are rendered with the azd environment variables according to the RenderTemplates and StrictEnvSubst
+// flags, and the manifests are returned in the order they would be applied.
+func (cli *kubectlCli) RenderManifests(ctx context.Context, path string, flags *KubeCliFlags) ([]RenderedManifest, error) {
+	manifests, err := cli.readManifests(path, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]RenderedManifest, 0, len(manifests))
+	for _, manifest := range manifestApplyOrder(manifests) {
+		rendered = append(rendered, RenderedManifest{
+			Path:     manifest.Path,
+			Content:  manifest.Content,
+			Rendered: manifest.Rendered,
+		})
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,67 @@
+package kubectl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RenderManifests(t *testing.T) {
+	tempDir := t.TempDir()
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Env.SERVICE_NAME }}\n"
+	namespace := "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: \"{{ .Env.SERVICE_NAME }}\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "deployment.tmpl.yaml"), []byte(deployment), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "namespace.yaml"), []byte(namespace), osutil.PermissionFile))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "README.md"), []byte("docs"), osutil.PermissionFile))
+
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return true
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		require.Fail(t, "kubectl should not be invoked", args.Cmd)
+		return exec.RunResult{}, nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	cli.SetEnv(map[string]string{"SERVICE_NAME": "api"})
+
+	t.Run("Directory", func(t *testing.T) {
+		manifests, err := cli.RenderManifests(*mockContext.Context, tempDir, nil)
+		require.NoError(t, err)
+
+		// The namespace is applied first, and only the template is rendered
+		require.Equal(t, []RenderedManifest{
+			{
+				Path:    filepath.Join(tempDir, "namespace.yaml"),
+				Content: namespace,
+			},
+			{
+				Path:     filepath.Join(tempDir, "deployment.tmpl.yaml"),
+				Content:  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n",
+				Rendered: true,
+			},
+		}, manifests)
+	})
+
+	t.Run("RenderTemplates", func(t *testing.T) {
+		manifests, err := cli.RenderManifests(
+			*mockContext.Context,
+			filepath.Join(tempDir, "namespace.yaml"),
+			&KubeCliFlags{RenderTemplates: true},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []RenderedManifest{
+			{
+				Path:     filepath.Join(tempDir, "namespace.yaml"),
+				Content:  "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: \"api\"\n",
+				Rendered: true,
+			},
+		}, manifests)
+	})
+}
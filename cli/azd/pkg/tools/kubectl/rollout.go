@@ -0,0 +1,99 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// The state of a deployment rollout, as reported by 'kubectl rollout status --watch=false'
+type RolloutState struct {
+	// Whether all the replicas of the deployment have been updated and are available
+	Complete bool
+	// Whether the rollout exceeded the progress deadline of the deployment, in which case it won't complete by itself
+	Failed            bool
+	DesiredReplicas   int
+	UpdatedReplicas   int
+	ReadyReplicas     int
+	AvailableReplicas int
+	// A human readable description of the state, ex. 'Waiting for deployment "api" rollout to finish: ...'
+	Message string
+	// The result of getting the deployment the state is computed from
+	RunResult exec.RunResult
+}
+
+// Gets the deployment rollout state without waiting for the rollout to complete, so that callers can decide whether
+// to keep waiting. The state is computed from the deployment status the same way 'kubectl rollout status' does.
+func (cli *kubectlCli) GetRolloutState(
+	ctx context.Context,
+	deploymentName string,
+	flags *KubeCliFlags,
+) (*RolloutState, error) {
+	getFlags := &KubeCliFlags{}
+	if flags != nil {
+		*getFlags = *flags
+	}
+	getFlags.Output = OutputTypeJson
+
+	res, err := cli.Exec(ctx, getFlags, "get", string(ResourceTypeDeployment), deploymentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting deployment '%s', %w", deploymentName, err)
+	}
+
+	var deployment Deployment
+	if err := json.Unmarshal([]byte(res.Stdout), &deployment); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling deployment '%s' JSON, %w", deploymentName, err)
+	}
+
+	state := newRolloutState(deployment)
+	state.RunResult = res
+
+	return state, nil
+}
+
+// Computes the rollout state of the deployment, following the status viewer of 'kubectl rollout status'
+func newRolloutState(deployment Deployment) *RolloutState {
+	name := deployment.Metadata.Name
+	status := deployment.Status
+	state := &RolloutState{
+		DesiredReplicas:   deployment.Spec.Replicas,
+		UpdatedReplicas:   status.UpdatedReplicas,
+		ReadyReplicas:     status.ReadyReplicas,
+		AvailableReplicas: status.AvailableReplicas,
+	}
+
+	if deployment.Metadata.Generation > status.ObservedGeneration {
+		state.Message = "Waiting for deployment spec update to be observed..."
+		return state
+	}
+
+	for _, condition := range status.Conditions {
+		if condition.Type == "Progressing" && condition.Reason == "ProgressDeadlineExceeded" {
+			state.Failed = true
+			state.Message = fmt.Sprintf("deployment %q exceeded its progress deadline", name)
+			return state
+		}
+	}
+
+	switch {
+	case status.UpdatedReplicas < deployment.Spec.Replicas:
+		state.Message = fmt.Sprintf(
+			"Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...",
+			name, status.UpdatedReplicas, deployment.Spec.Replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		state.Message = fmt.Sprintf(
+			"Waiting for deployment %q rollout to finish: %d old replicas are pending termination...",
+			name, status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		state.Message = fmt.Sprintf(
+			"Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...",
+			name, status.AvailableReplicas, status.UpdatedReplicas)
+	default:
+		state.Complete = true
+		state.Message = fmt.Sprintf("deployment %q successfully rolled out", name)
+	}
+
+	return state
+}
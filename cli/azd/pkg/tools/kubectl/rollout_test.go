@@ -0,0 +1,106 @@
+package kubectl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetRolloutState(t *testing.T) {
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.
+		WhenKubectl("get", "deployment", "api").
+		RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			runArgs = args
+			return exec.NewRunResult(0, `{
+				"kind": "Deployment",
+				"metadata": {"name": "api", "generation": 2},
+				"spec": {"replicas": 3},
+				"status": {
+					"observedGeneration": 2,
+					"replicas": 3,
+					"updatedReplicas": 3,
+					"readyReplicas": 2,
+					"availableReplicas": 2
+				}
+			}`, ""), nil
+		})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	state, err := cli.GetRolloutState(*mockContext.Context, "api", &KubeCliFlags{Namespace: "todo"})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"get", "deployment", "api", "-n", "todo", "-o", "json"}, runArgs.Args)
+	require.False(t, state.Complete)
+	require.Equal(t, 3, state.DesiredReplicas)
+	require.Equal(t, 3, state.UpdatedReplicas)
+	require.Equal(t, 2, state.ReadyReplicas)
+	require.Equal(t, `Waiting for deployment "api" rollout to finish: 2 of 3 updated replicas are available...`, state.Message)
+	require.Contains(t, state.RunResult.Stdout, `"kind": "Deployment"`)
+}
+
+func Test_NewRolloutState(t *testing.T) {
+	deployment := func(generation int64, status DeploymentStatus) Deployment {
+		return Deployment{
+			Resource: Resource{Metadata: ResourceMetadata{Name: "api", Generation: generation}},
+			Spec:     DeploymentSpec{Replicas: 2},
+			Status:   status,
+		}
+	}
+
+	tests := map[string]struct {
+		deployment Deployment
+		complete   bool
+		failed     bool
+		message    string
+	}{
+		"SpecUpdateNotObserved": {
+			deployment: deployment(2, DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2}),
+			message:    "Waiting for deployment spec update to be observed...",
+		},
+		"UpdatingReplicas": {
+			deployment: deployment(1, DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 1}),
+			message:    `Waiting for deployment "api" rollout to finish: 1 out of 2 new replicas have been updated...`,
+		},
+		"TerminatingOldReplicas": {
+			deployment: deployment(1, DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 2}),
+			message:    `Waiting for deployment "api" rollout to finish: 1 old replicas are pending termination...`,
+		},
+		"ProgressDeadlineExceeded": {
+			deployment: deployment(1, DeploymentStatus{
+				ObservedGeneration: 1,
+				Replicas:           2,
+				UpdatedReplicas:    1,
+				Conditions: []DeploymentCondition{
+					{Type: "Progressing", Status: "False", Reason: "ProgressDeadlineExceeded"},
+				},
+			}),
+			failed:  true,
+			message: `deployment "api" exceeded its progress deadline`,
+		},
+		"Complete": {
+			deployment: deployment(1, DeploymentStatus{
+				ObservedGeneration: 1,
+				Replicas:           2,
+				UpdatedReplicas:    2,
+				ReadyReplicas:      2,
+				AvailableReplicas:  2,
+			}),
+			complete: true,
+			message:  `deployment "api" successfully rolled out`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := newRolloutState(test.deployment)
+			require.Equal(t, test.complete, state.Complete)
+			require.Equal(t, test.failed, state.Failed)
+			require.Equal(t, test.message, state.Message)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// Returned by kubectl top when the cluster doesn't serve the metrics API, typically because metrics-server is
+// not installed
+var ErrMetricsNotAvailable = errors.New("metrics API not available")
+
+// The current resource usage of a pod
+type PodMetrics struct {
+	Name string
+	// The CPU usage in millicores, ex. 250 for 250m
+	CpuMillicores int64
+	MemoryBytes   int64
+}
+
+// The current resource usage of a node
+type NodeMetrics struct {
+	Name          string
+	CpuMillicores int64
+	// The CPU usage as a percentage of the allocatable CPU of the node
+	CpuPercent  int
+	MemoryBytes int64
+	// The memory usage as a percentage of the allocatable memory of the node
+	MemoryPercent int
+}
+
+// Gets the current CPU and memory usage of each pod within the namespace
+func (cli *kubectlCli) TopPods(ctx context.Context, flags *KubeCliFlags) ([]PodMetrics, error) {
+	rows, err := cli.top(ctx, flags, "pods")
+	if err != nil {
+		return nil, err
+	}
+
+	pods := []PodMetrics{}
+	for _, row := range rows {
+		// NAME CPU(cores) MEMORY(bytes)
+		if len(row) < 3 {
+			return nil, fmt.Errorf("failed parsing pod metrics '%s'", strings.Join(row, " "))
+		}
+
+		cpu, err := parseCpuMillicores(row[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing CPU usage of pod '%s', %w", row[0], err)
+		}
+
+		memory, err := parseMemoryBytes(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing memory usage of pod '%s', %w", row[0], err)
+		}
+
+		pods = append(pods, PodMetrics{
+			Name:          row[0],
+			CpuMillicores: cpu,
+			MemoryBytes:   memory,
+		})
+	}
+
+	return pods, nil
+}
+
+// Gets the current CPU and memory usage of each node of the cluster.
+// Nodes without metrics, ex. nodes that are not ready, report a zero usage.
+func (cli *kubectlCli) TopNodes(ctx context.Context, flags *KubeCliFlags) ([]NodeMetrics, error) {
+	rows, err := cli.top(ctx, flags, "nodes")
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []NodeMetrics{}
+	for _, row := range rows {
+		// NAME CPU(cores) CPU% MEMORY(bytes) MEMORY%
+		if len(row) < 5 {
+			return nil, fmt.Errorf("failed parsing node metrics '%s'", strings.Join(row, " "))
+		}
+
+		node := NodeMetrics{Name: row[0]}
+		if row[1] == metricsUnknown {
+			nodes = append(nodes, node)
+			continue
+		}
+
+		if node.CpuMillicores, err = parseCpuMillicores(row[1]); err != nil {
+			return nil, fmt.Errorf("failed parsing CPU usage of node '%s', %w", row[0], err)
+		}
+		if node.CpuPercent, err = parsePercent(row[2]); err != nil {
+			return nil, fmt.Errorf("failed parsing CPU usage of node '%s', %w", row[0], err)
+		}
+		if node.MemoryBytes, err = parseMemoryBytes(row[3]); err != nil {
+			return nil, fmt.Errorf("failed parsing memory usage of node '%s', %w", row[0], err)
+		}
+		if node.MemoryPercent, err = parsePercent(row[4]); err != nil {
+			return nil, fmt.Errorf("failed parsing memory usage of node '%s', %w", row[0], err)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// The value printed by kubectl top for nodes without metrics
+const metricsUnknown = "<unknown>"
+
+// Runs kubectl top for the resource type and splits its output into the columns of each row.
+// kubectl top doesn't support JSON output, so the columns of the table are parsed.
+func (cli *kubectlCli) top(ctx context.Context, flags *KubeCliFlags, resourceType string) ([][]string, error) {
+	topFlags := &KubeCliFlags{}
+	if flags != nil {
+		*topFlags = *flags
+	}
+	topFlags.Output = ""
+
+	res, err := cli.Exec(ctx, topFlags, "top", resourceType, "--no-headers")
+	if err != nil {
+		if isMetricsNotAvailableError(res, err) {
+			return nil, fmt.Errorf("failed getting %s metrics, %w", resourceType, ErrMetricsNotAvailable)
+		}
+
+		return nil, fmt.Errorf("failed getting %s metrics, %w", resourceType, err)
+	}
+
+	rows := [][]string{}
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if columns := strings.Fields(line); len(columns) > 0 {
+			rows = append(rows, columns)
+		}
+	}
+
+	return rows, nil
+}
+
+// Gets whether kubectl top failed because the metrics API isn't served by the cluster,
+// ex. 'error: Metrics API not available'
+func isMetricsNotAvailableError(res exec.RunResult, err error) bool {
+	return strings.Contains(res.Stderr, "Metrics API not available") ||
+		strings.Contains(err.Error(), "Metrics API not available")
+}
+
+// Parses a CPU quantity, ex. 250m or 2, into millicores
+func parseCpuMillicores(value string) (int64, error) {
+	cores, err := parseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(math.Round(cores * 1000)), nil
+}
+
+// Parses a memory quantity, ex. 128Mi, into bytes
+func parseMemoryBytes(value string) (int64, error) {
+	bytes, err := parseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(math.Round(bytes)), nil
+}
+
+// Parses a percentage, ex. 12%
+func parsePercent(value string) (int, error) {
+	percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage '%s', %w", value, err)
+	}
+
+	return percent, nil
+}
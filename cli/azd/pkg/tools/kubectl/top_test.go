@@ -0,0 +1,77 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TopPods(t *testing.T) {
+	var runArgs exec.RunArgs
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl top pods")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		runArgs = args
+		return exec.NewRunResult(0, "api-7c9d8f6b5d-x2k4q   250m   128Mi\nweb-5d4f7b9c8-m7zpw    1      1Gi\n", ""), nil
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	pods, err := cli.TopPods(*mockContext.Context, &KubeCliFlags{Namespace: "todo", Output: OutputTypeJson})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"top", "pods", "--no-headers", "-n", "todo"}, runArgs.Args)
+	require.Equal(t, []PodMetrics{
+		{Name: "api-7c9d8f6b5d-x2k4q", CpuMillicores: 250, MemoryBytes: 128 << 20},
+		{Name: "web-5d4f7b9c8-m7zpw", CpuMillicores: 1000, MemoryBytes: 1 << 30},
+	}, pods)
+}
+
+func Test_TopNodes(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl top nodes")
+	}).Respond(exec.NewRunResult(
+		0,
+		"aks-system-12345678-vmss000000   312m   16%   2048Mi   45%\n"+
+			"aks-system-12345678-vmss000001   <unknown>   <unknown>   <unknown>   <unknown>\n",
+		"",
+	))
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	nodes, err := cli.TopNodes(*mockContext.Context, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []NodeMetrics{
+		{
+			Name:          "aks-system-12345678-vmss000000",
+			CpuMillicores: 312,
+			CpuPercent:    16,
+			MemoryBytes:   2048 << 20,
+			MemoryPercent: 45,
+		},
+		{Name: "aks-system-12345678-vmss000001"},
+	}, nodes)
+}
+
+func Test_Top_MetricsNotAvailable(t *testing.T) {
+	mockContext := mocks.NewMockContext(context.Background())
+	mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+		return strings.Contains(command, "kubectl top")
+	}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+		stderr := "error: Metrics API not available"
+		return exec.NewRunResult(1, "", stderr), errors.New(stderr)
+	})
+
+	cli := NewKubectl(mockContext.CommandRunner)
+	_, err := cli.TopPods(*mockContext.Context, nil)
+	require.ErrorIs(t, err, ErrMetricsNotAvailable)
+
+	_, err = cli.TopNodes(*mockContext.Context, nil)
+	require.ErrorIs(t, err, ErrMetricsNotAvailable)
+}
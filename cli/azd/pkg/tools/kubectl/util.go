@@ -14,6 +14,7 @@ import (
 var (
 	ErrResourceNotFound = errors.New("cannot find resource")
 	ErrResourceNotReady = errors.New("resource is not ready")
+	ErrCommandTimeout   = errors.New("kubectl command timed out")
 )
 
 func GetResource[T any](
@@ -0,0 +1,52 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Validates the manifests with a server-side dry run of each of them, so that nothing is applied when any of them is
+// invalid. Returns the validation failures of all the manifests instead of only the first one.
+//
+// Custom resources can't be validated before their CRDs are registered, so when the manifests define CRDs the failures
+// caused by kinds that are not registered yet are ignored.
+func (cli *kubectlCli) validateManifests(ctx context.Context, manifests []manifestContent, flags *KubeCliFlags) error {
+	validateFlags := &KubeCliFlags{}
+	if flags != nil {
+		*validateFlags = *flags
+	}
+	validateFlags.DryRun = DryRunTypeServer
+	validateFlags.ValidateFirst = false
+	// Nothing is persisted by the dry run, so there is no prior state to record and nothing to prune
+	validateFlags.Journal = nil
+	validateFlags.Prune = false
+
+	definesCrds := false
+	for _, manifest := range manifests {
+		if containsCrd(manifest.Content) {
+			definesCrds = true
+			break
+		}
+	}
+
+	errs := []error{}
+	for _, manifest := range manifests {
+		_, err := cli.ApplyWithStdIn(ctx, manifest.Content, validateFlags)
+		if err != nil && definesCrds && isCrdNotReadyError(err) {
+			log.Printf("skipping validation of '%s', it references a CRD that is not registered yet: %v", manifest.Path, err)
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("manifest '%s' is invalid, %w", manifest.Path, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("validating manifests failed, nothing was applied:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
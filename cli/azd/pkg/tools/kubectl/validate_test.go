@@ -0,0 +1,113 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/pkg/osutil"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Apply_ValidateFirst(t *testing.T) {
+	tempDir := t.TempDir()
+	manifests := map[string]string{
+		"config.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n",
+		"service.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n",
+	}
+
+	for name, content := range manifests {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), osutil.PermissionFile))
+	}
+
+	// Applies the manifests, failing the dry run of the specified kinds
+	apply := func(t *testing.T, invalidKinds ...string) (validated []string, applied []string, err error) {
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl apply -f")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			if !slices.Contains(args.Args, "--dry-run=server") {
+				applied = append(applied, filepath.Base(args.Args[2]))
+				return exec.NewRunResult(0, "", ""), nil
+			}
+
+			input, err := io.ReadAll(args.StdIn)
+			require.NoError(t, err)
+			resources, err := parseManifestResources(string(input))
+			require.NoError(t, err)
+
+			kind := resources[0].Kind
+			validated = append(validated, kind)
+			if slices.Contains(invalidKinds, kind) {
+				return exec.NewRunResult(1, "", ""), errors.New("invalid " + kind)
+			}
+
+			return exec.NewRunResult(0, "", ""), nil
+		})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		_, err = cli.Apply(*mockContext.Context, tempDir, &KubeCliFlags{ValidateFirst: true})
+		return validated, applied, err
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		validated, applied, err := apply(t)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"ConfigMap", "Service", "Deployment"}, validated)
+		require.Equal(t, []string{"config.yaml", "service.yaml", "deployment.yaml"}, applied)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		validated, applied, err := apply(t, "ConfigMap", "Deployment")
+		require.Error(t, err)
+
+		// All the manifests are validated and all the failures are reported
+		require.Len(t, validated, 3)
+		require.ErrorContains(t, err, "invalid ConfigMap")
+		require.ErrorContains(t, err, "invalid Deployment")
+		require.NotContains(t, err.Error(), "service.yaml")
+
+		require.Empty(t, applied)
+	})
+
+	t.Run("UnregisteredCrd", func(t *testing.T) {
+		crdDir := t.TempDir()
+		crd := "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: widgets.example.com\n"
+		widget := "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"
+		require.NoError(t, os.WriteFile(filepath.Join(crdDir, "crd.yaml"), []byte(crd), osutil.PermissionFile))
+		require.NoError(t, os.WriteFile(filepath.Join(crdDir, "widget.yaml"), []byte(widget), osutil.PermissionFile))
+
+		applied := []string{}
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl apply -f")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			if slices.Contains(args.Args, "--dry-run=server") {
+				input, err := io.ReadAll(args.StdIn)
+				require.NoError(t, err)
+				if strings.Contains(string(input), "kind: Widget") {
+					return exec.NewRunResult(1, "", ""), errors.New(`no matches for kind "Widget" in version "example.com/v1"`)
+				}
+
+				return exec.NewRunResult(0, "", ""), nil
+			}
+
+			applied = append(applied, filepath.Base(args.Args[2]))
+			return exec.NewRunResult(0, "", ""), nil
+		})
+
+		cli := NewKubectl(mockContext.CommandRunner)
+		_, err := cli.Apply(*mockContext.Context, crdDir, &KubeCliFlags{ValidateFirst: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"crd.yaml", "widget.yaml"}, applied)
+	})
+}
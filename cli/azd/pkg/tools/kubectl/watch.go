@@ -0,0 +1,106 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+type WatchEventType string
+
+const (
+	WatchEventTypeAdded    WatchEventType = "ADDED"
+	WatchEventTypeModified WatchEventType = "MODIFIED"
+	WatchEventTypeDeleted  WatchEventType = "DELETED"
+)
+
+// A change to a k8s resource observed while watching
+type WatchEvent struct {
+	// The type of change, ADDED, MODIFIED or DELETED
+	Type WatchEventType `json:"type"`
+	// The raw JSON of the resource that changed
+	Object json.RawMessage `json:"object"`
+	// The identity of the resource that changed
+	Resource Resource `json:"-"`
+}
+
+// Handles a watch event. Returning an error stops the watch.
+type WatchHandlerFn func(event WatchEvent) error
+
+// Watches resources of the specified type and invokes the handler for each change until the context is canceled.
+// Returns nil when the watch is stopped by canceling the context, or the handler error when the handler fails.
+func (cli *kubectlCli) Watch(
+	ctx context.Context,
+	resourceType ResourceType,
+	flags *KubeCliFlags,
+	handler WatchHandlerFn,
+) error {
+	watchFlags := &KubeCliFlags{Output: OutputTypeJson}
+	if flags != nil {
+		watchFlags.Namespace = flags.Namespace
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	handlerErr := make(chan error, 1)
+
+	go func() {
+		handlerErr <- decodeWatchEvents(reader, handler)
+		// Stop kubectl once events can no longer be handled
+		cancel()
+	}()
+
+	runArgs := exec.
+		NewRunArgs("kubectl", "get", string(resourceType), "--watch", "--output-watch-events").
+		WithStdOut(writer)
+
+	_, runErr := cli.executeCommandWithArgs(watchCtx, runArgs, watchFlags)
+	writer.Close()
+
+	if err := <-handlerErr; err != nil {
+		return err
+	}
+
+	// Canceling the context is the expected way to stop watching
+	if runErr != nil && ctx.Err() == nil {
+		return fmt.Errorf("kubectl get --watch: %w", runErr)
+	}
+
+	return nil
+}
+
+// Decodes the stream of JSON watch events and invokes the handler for each event
+func decodeWatchEvents(reader *io.PipeReader, handler WatchHandlerFn) error {
+	decoder := json.NewDecoder(reader)
+
+	for {
+		var event WatchEvent
+		err := decoder.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			err = fmt.Errorf("failed decoding watch event, %w", err)
+			reader.CloseWithError(err)
+			return err
+		}
+
+		if err := json.Unmarshal(event.Object, &event.Resource); err != nil {
+			err = fmt.Errorf("failed decoding watch event resource, %w", err)
+			reader.CloseWithError(err)
+			return err
+		}
+
+		if err := handler(event); err != nil {
+			// Unblocks any pending writes from kubectl
+			reader.CloseWithError(err)
+			return err
+		}
+	}
+}
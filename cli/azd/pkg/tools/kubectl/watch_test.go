@@ -0,0 +1,90 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+	"github.com/azure/azure-dev/cli/azd/test/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Watch(t *testing.T) {
+	watchOutput := strings.Join([]string{
+		watchEventJson("ADDED", "api-7d9f"),
+		watchEventJson("MODIFIED", "api-7d9f"),
+		watchEventJson("DELETED", "api-7d9f"),
+	}, "\n")
+
+	setup := func() (*mocks.MockContext, *exec.RunArgs) {
+		var runArgs exec.RunArgs
+		mockContext := mocks.NewMockContext(context.Background())
+		mockContext.CommandRunner.When(func(args exec.RunArgs, command string) bool {
+			return strings.Contains(command, "kubectl get pods --watch")
+		}).RespondFn(func(args exec.RunArgs) (exec.RunResult, error) {
+			runArgs = args
+			_, err := args.StdOut.Write([]byte(watchOutput))
+			// The write fails once the handler stops the watch
+			if err != nil {
+				return exec.NewRunResult(1, "", ""), err
+			}
+
+			return exec.NewRunResult(0, watchOutput, ""), nil
+		})
+
+		return mockContext, &runArgs
+	}
+
+	t.Run("AllEvents", func(t *testing.T) {
+		mockContext, runArgs := setup()
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		events := []WatchEvent{}
+		err := cli.Watch(*mockContext.Context, "pods", &KubeCliFlags{Namespace: "test"}, func(event WatchEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			[]string{"get", "pods", "--watch", "--output-watch-events", "-n", "test", "-o", "json"},
+			runArgs.Args,
+		)
+
+		require.Len(t, events, 3)
+		require.Equal(t, WatchEventTypeAdded, events[0].Type)
+		require.Equal(t, WatchEventTypeModified, events[1].Type)
+		require.Equal(t, WatchEventTypeDeleted, events[2].Type)
+		require.Equal(t, "api-7d9f", events[0].Resource.Metadata.Name)
+		require.Equal(t, "Pod", events[0].Resource.Kind)
+		require.Contains(t, string(events[0].Object), "api-7d9f")
+	})
+
+	t.Run("HandlerError", func(t *testing.T) {
+		mockContext, _ := setup()
+		cli := NewKubectl(mockContext.CommandRunner)
+
+		handlerErr := errors.New("stop watching")
+		calls := 0
+		err := cli.Watch(*mockContext.Context, "pods", nil, func(event WatchEvent) error {
+			calls++
+			return handlerErr
+		})
+		require.ErrorIs(t, err, handlerErr)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func watchEventJson(eventType string, podName string) string {
+	return fmt.Sprintf(`{
+		"type": "%s",
+		"object": {
+			"apiVersion": "v1",
+			"kind": "Pod",
+			"metadata": { "name": "%s", "namespace": "test" }
+		}
+	}`, eventType, podName)
+}
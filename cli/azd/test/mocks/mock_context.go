@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
@@ -17,6 +18,7 @@ import (
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockexec"
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockhttp"
 	"github.com/azure/azure-dev/cli/azd/test/mocks/mockinput"
+	"github.com/benbjohnson/clock"
 )
 
 type MockContext struct {
@@ -33,6 +35,8 @@ type MockContext struct {
 	SubscriptionCredentialProvider *MockSubscriptionCredentialProvider
 	MultiTenantCredentialProvider  *MockMultiTenantCredentialProvider
 	Config                         config.Config
+	// A mock clock, registered as the clock.Clock of the container, set to the Unix epoch until advanced
+	Clock *clock.Mock
 }
 
 func NewMockContext(ctx context.Context) *MockContext {
@@ -60,6 +64,7 @@ func NewMockContext(ctx context.Context) *MockContext {
 		Container:                      ioc.NewNestedContainer(nil),
 		Config:                         config,
 		AlphaFeaturesManager:           alpha.NewFeaturesManagerWithConfig(config),
+		Clock:                          clock.NewMock(),
 	}
 
 	registerCommonMocks(mockContext)
@@ -67,6 +72,23 @@ func NewMockContext(ctx context.Context) *MockContext {
 	return mockContext
 }
 
+// Moves the mock clock forward, firing any timers and tickers that expire within the duration
+func (m *MockContext) AdvanceTime(d time.Duration) {
+	m.Clock.Add(d)
+}
+
+// Sends the HTTP requests to the real services and records them to the JSON cassette at the specified path, so they
+// can later be replayed deterministically with EnableReplay. The requests are authenticated with the credentials
+// of the context, which need to be replaced with real credentials before recording.
+func (m *MockContext) EnableRecording(path string) {
+	m.HttpClient.EnableRecording(path)
+}
+
+// Serves the HTTP requests from the JSON cassette at the specified path, recorded with EnableRecording
+func (m *MockContext) EnableReplay(path string) error {
+	return m.HttpClient.EnableReplay(path)
+}
+
 func registerCommonMocks(mockContext *MockContext) {
 	mockContext.Container.MustRegisterSingleton(func() ioc.ServiceLocator {
 		return mockContext.Container
@@ -89,4 +111,7 @@ func registerCommonMocks(mockContext *MockContext) {
 	mockContext.Container.MustRegisterSingleton(func() *alpha.FeatureManager {
 		return mockContext.AlphaFeaturesManager
 	})
+	mockContext.Container.MustRegisterSingleton(func() clock.Clock {
+		return mockContext.Clock
+	})
 }
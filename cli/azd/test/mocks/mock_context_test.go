@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MockContext_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"echo":"` + string(body) + `"}`))
+	}))
+	defer server.Close()
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	send := func(mockContext *MockContext) (*http.Response, string) {
+		request, err := http.NewRequest(http.MethodPut, server.URL+"/deployments/dev", strings.NewReader("hello"))
+		require.NoError(t, err)
+
+		response, err := mockContext.HttpClient.Do(request)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+
+		return response, string(body)
+	}
+
+	recordContext := NewMockContext(context.Background())
+	recordContext.EnableRecording(cassettePath)
+
+	response, body := send(recordContext)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	require.Equal(t, `{"echo":"hello"}`, body)
+
+	// Replays the recorded interaction once, then falls back to the registered mocks
+	server.Close()
+	replayContext := NewMockContext(context.Background())
+	require.NoError(t, replayContext.EnableReplay(cassettePath))
+	replayContext.HttpClient.When(func(request *http.Request) bool {
+		return request.Method == http.MethodPut
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		return CreateEmptyHttpResponse(request, http.StatusNoContent)
+	})
+
+	response, body = send(replayContext)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	require.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	require.Equal(t, `{"echo":"hello"}`, body)
+
+	response, _ = send(replayContext)
+	require.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	require.Error(t, replayContext.EnableReplay(filepath.Join(t.TempDir(), "missing.json")))
+}
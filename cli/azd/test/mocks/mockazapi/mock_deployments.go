@@ -0,0 +1,440 @@
+package mockazapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/azure/azure-dev/cli/azd/pkg/azapi"
+	"github.com/azure/azure-dev/cli/azd/pkg/azure"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ azapi.Deployments = (*MockDeployments)(nil)
+
+// A mock of azapi.Deployments. Return values are programmed with On(...).Return(...) and every call is recorded,
+// including the deployment name, template and parameters, so it can be asserted on.
+//
+//	deployments := &mockazapi.MockDeployments{}
+//	deployments.
+//		On("DeployToResourceGroup", mock.Anything, "SUB", "RG", "NAME", mock.Anything, mock.Anything, mock.Anything,
+//			mock.Anything).
+//		Return(&armresources.DeploymentExtended{}, nil)
+type MockDeployments struct {
+	mock.Mock
+}
+
+// Asserts that DeployToResourceGroup was called for the resource group with the deployment name and parameters
+func (m *MockDeployments) AssertDeployedToResourceGroup(
+	t *testing.T,
+	resourceGroup string,
+	deploymentName string,
+	parameters azure.ArmParameters,
+) bool {
+	return m.AssertCalled(
+		t,
+		"DeployToResourceGroup",
+		mock.Anything,
+		mock.Anything,
+		resourceGroup,
+		deploymentName,
+		mock.Anything,
+		parameters,
+		mock.Anything,
+		mock.Anything,
+	)
+}
+
+// Asserts that DeployToSubscription was called with the deployment name and parameters
+func (m *MockDeployments) AssertDeployedToSubscription(
+	t *testing.T,
+	deploymentName string,
+	parameters azure.ArmParameters,
+) bool {
+	return m.AssertCalled(
+		t,
+		"DeployToSubscription",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		deploymentName,
+		mock.Anything,
+		parameters,
+		mock.Anything,
+		mock.Anything,
+	)
+}
+
+// Gets the template passed to the last call of the method, ex. 'DeployToResourceGroup', nil when it wasn't called
+func (m *MockDeployments) DeployedTemplate(method string) azure.RawArmTemplate {
+	for i := len(m.Calls) - 1; i >= 0; i-- {
+		call := m.Calls[i]
+		if call.Method != method {
+			continue
+		}
+
+		for _, arg := range call.Arguments {
+			if template, ok := arg.(azure.RawArmTemplate); ok {
+				return template
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *MockDeployments) ListSubscriptionDeployments(
+	ctx context.Context,
+	subscriptionId string,
+) ([]*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId)
+	result, _ := args.Get(0).([]*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ListSubscriptionDeploymentsWithOptions(
+	ctx context.Context,
+	subscriptionId string,
+	options *azapi.ListDeploymentsOptions,
+) ([]*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, options)
+	result, _ := args.Get(0).([]*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) GetSubscriptionDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	deploymentName string,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, deploymentName)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ListResourceGroupDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+) ([]*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroupName)
+	result, _ := args.Get(0).([]*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) GetResourceGroupDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroupName, deploymentName)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeployToSubscription(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, location, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeployToResourceGroup(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroup string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroup, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) WhatIfDeployToSubscription(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *azapi.DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	args := m.Called(ctx, subscriptionId, location, deploymentName, armTemplate, parameters, options)
+	result, _ := args.Get(0).(*armresources.WhatIfOperationResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) WhatIfDeployToResourceGroup(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroup string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *azapi.DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroup, deploymentName, armTemplate, parameters, options)
+	result, _ := args.Get(0).(*armresources.WhatIfOperationResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ListManagementGroupDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+) ([]*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, managementGroupId)
+	result, _ := args.Get(0).([]*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) GetManagementGroupDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	deploymentName string,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, managementGroupId, deploymentName)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeployToManagementGroup(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, managementGroupId, location, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) WhatIfDeployToManagementGroup(
+	ctx context.Context,
+	subscriptionId string,
+	managementGroupId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *azapi.DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	args := m.Called(ctx, subscriptionId, managementGroupId, location, deploymentName, armTemplate, parameters, options)
+	result, _ := args.Get(0).(*armresources.WhatIfOperationResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeployToTenant(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, subscriptionId, location, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) WhatIfDeployToTenant(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *azapi.DeployOptions,
+) (*armresources.WhatIfOperationResult, error) {
+	args := m.Called(ctx, subscriptionId, location, deploymentName, armTemplate, parameters, options)
+	result, _ := args.Get(0).(*armresources.WhatIfOperationResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ValidateDeployToSubscription(
+	ctx context.Context,
+	subscriptionId string,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentValidateResult, error) {
+	args := m.Called(ctx, subscriptionId, location, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentValidateResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ValidateDeployToResourceGroup(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroup string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	tags map[string]*string,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentValidateResult, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroup, deploymentName, armTemplate, parameters, tags, options)
+	result, _ := args.Get(0).(*armresources.DeploymentValidateResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) PreflightDeploy(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	location string,
+	deploymentName string,
+	armTemplate azure.RawArmTemplate,
+	parameters azure.ArmParameters,
+	options *azapi.DeployOptions,
+) (*azapi.PreflightResult, error) {
+	args := m.Called(ctx, scope, location, deploymentName, armTemplate, parameters, options)
+	result, _ := args.Get(0).(*azapi.PreflightResult)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ExportDeploymentTemplate(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	deploymentName string,
+) (azure.RawArmTemplate, error) {
+	args := m.Called(ctx, scope, deploymentName)
+	result, _ := args.Get(0).(azure.RawArmTemplate)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) ExportDeploymentBundle(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) (azure.RawArmTemplate, azure.ArmParameters, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroupName, deploymentName)
+	result0, _ := args.Get(0).(azure.RawArmTemplate)
+	result1, _ := args.Get(1).(azure.ArmParameters)
+	return result0, result1, args.Error(2)
+}
+
+func (m *MockDeployments) ListDeploymentOperations(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	deploymentName string,
+) ([]*armresources.DeploymentOperation, error) {
+	args := m.Called(ctx, scope, deploymentName)
+	result, _ := args.Get(0).([]*armresources.DeploymentOperation)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) GetDeploymentErrorReport(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	deploymentName string,
+) (string, error) {
+	args := m.Called(ctx, scope, deploymentName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDeployments) CancelDeployment(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	deploymentName string,
+) error {
+	args := m.Called(ctx, scope, deploymentName)
+	return args.Error(0)
+}
+
+func (m *MockDeployments) WatchDeploymentStates(
+	ctx context.Context,
+	getDeployment func(ctx context.Context) (*armresources.DeploymentExtended, error),
+	frequency time.Duration,
+	onChange func(change azapi.DeploymentStateChange),
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, getDeployment, frequency, onChange)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) RedeployLastSuccessful(
+	ctx context.Context,
+	scope azapi.DeploymentScope,
+	predicate func(deployment *armresources.DeploymentExtended) bool,
+	options *azapi.DeployOptions,
+) (*armresources.DeploymentExtended, error) {
+	args := m.Called(ctx, scope, predicate, options)
+	result, _ := args.Get(0).(*armresources.DeploymentExtended)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeleteSubscriptionDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	deploymentName string,
+) error {
+	args := m.Called(ctx, subscriptionId, deploymentName)
+	return args.Error(0)
+}
+
+func (m *MockDeployments) PruneSubscriptionDeployments(
+	ctx context.Context,
+	subscriptionId string,
+	keepCount int,
+	olderThan time.Duration,
+) ([]string, error) {
+	args := m.Called(ctx, subscriptionId, keepCount, olderThan)
+	result, _ := args.Get(0).([]string)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeleteResourceGroupDeployment(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) error {
+	args := m.Called(ctx, subscriptionId, resourceGroupName, deploymentName)
+	return args.Error(0)
+}
+
+func (m *MockDeployments) CalculateTemplateHash(
+	ctx context.Context,
+	subscriptionId string,
+	template azure.RawArmTemplate,
+) (armresources.DeploymentsClientCalculateTemplateHashResponse, error) {
+	args := m.Called(ctx, subscriptionId, template)
+	result, _ := args.Get(0).(armresources.DeploymentsClientCalculateTemplateHashResponse)
+	return result, args.Error(1)
+}
+
+func (m *MockDeployments) DeployedTemplateHash(
+	ctx context.Context,
+	subscriptionId string,
+	resourceGroupName string,
+	deploymentName string,
+) (string, error) {
+	args := m.Called(ctx, subscriptionId, resourceGroupName, deploymentName)
+	return args.String(0), args.Error(1)
+}
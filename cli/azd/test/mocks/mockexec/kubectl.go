@@ -0,0 +1,91 @@
+package mockexec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/azure/azure-dev/cli/azd/pkg/exec"
+)
+
+// The kubectl flags that take their value as the next argument when not set with '--flag=value'
+var kubectlValueFlags = map[string]bool{
+	"-c":           true,
+	"--container":  true,
+	"-f":           true,
+	"--filename":   true,
+	"-k":           true,
+	"--kustomize":  true,
+	"-l":           true,
+	"--selector":   true,
+	"-n":           true,
+	"--namespace":  true,
+	"-o":           true,
+	"--output":     true,
+	"-p":           true,
+	"--patch":      true,
+	"--context":    true,
+	"--kubeconfig": true,
+	"--type":       true,
+}
+
+// Represents a mocked expression against a kubectl command
+type KubectlExpression struct {
+	*CommandExpression
+}
+
+// Registers a mock expression matching the kubectl commands starting with the subcommand, ex. 'rollout status'.
+// Flags and their values are ignored, so the expression matches regardless of where the flags are specified.
+//
+//	mockContext.CommandRunner.WhenKubectl("get", "pods").Respond(podsJson, 0)
+func (m *MockCommandRunner) WhenKubectl(subcommand ...string) *KubectlExpression {
+	return &KubectlExpression{
+		CommandExpression: m.When(func(args exec.RunArgs, command string) bool {
+			if args.Cmd != "kubectl" {
+				return false
+			}
+
+			positional := kubectlPositionalArgs(args.Args)
+			if len(positional) < len(subcommand) {
+				return false
+			}
+
+			for i, word := range subcommand {
+				if positional[i] != word {
+					return false
+				}
+			}
+
+			return true
+		}),
+	}
+}
+
+// Sets the stdout and exit code returned for the current expression.
+// A non-zero exit code also returns an error, the same as a kubectl command that fails.
+func (e *KubectlExpression) Respond(stdout string, exitCode int) *MockCommandRunner {
+	e.response = exec.NewRunResult(exitCode, stdout, "")
+	if exitCode != 0 {
+		e.error = fmt.Errorf("exit code: %d", exitCode)
+	}
+
+	return e.exec
+}
+
+// Gets the arguments that are not flags or flag values
+func kubectlPositionalArgs(args []string) []string {
+	positional := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		if !strings.Contains(arg, "=") && kubectlValueFlags[arg] {
+			// Skips the value of the flag
+			i++
+		}
+	}
+
+	return positional
+}
@@ -0,0 +1,92 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// The number of status polls that report the operation as still running before it reaches its final status
+const lroInProgressPolls = 1
+
+// Registers an ARM long running operation (LRO) using the Azure-AsyncOperation pattern.
+//
+// The initial request matching the method and URL is accepted with a 201 pointing to a mock operation status URL.
+// Polling the status reports the operation as in progress before reporting the final status, ex. 'Succeeded' or
+// 'Failed'. When the operation succeeds the final body is returned by the GET on the original URL (for PUT and PATCH)
+// or on the Location URL (for POST) that completes the operation. Otherwise the final body is returned as the error
+// of the operation, ex. {"code": "DeploymentFailed", "message": "..."}.
+func (c *MockHttpClient) RegisterLRO(method string, urlMatch string, finalStatus string, finalBody any) *MockHttpClient {
+	operationId := len(c.expressions)
+	statusUrl := fmt.Sprintf("https://management.azure.com/mock-lro/%d/status", operationId)
+	resultUrl := fmt.Sprintf("https://management.azure.com/mock-lro/%d/result", operationId)
+	succeeded := strings.EqualFold(finalStatus, "Succeeded")
+
+	c.When(func(request *http.Request) bool {
+		return request.Method == method && strings.Contains(request.URL.String(), urlMatch)
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		response, err := createJsonResponse(request, http.StatusCreated, map[string]any{})
+		if err != nil {
+			return nil, err
+		}
+
+		response.Header.Set("Azure-AsyncOperation", statusUrl)
+		if method == http.MethodPost {
+			response.Header.Set("Location", resultUrl)
+		}
+
+		return response, nil
+	})
+
+	var polls atomic.Int32
+	c.When(func(request *http.Request) bool {
+		return request.Method == http.MethodGet && request.URL.String() == statusUrl
+	}).RespondFn(func(request *http.Request) (*http.Response, error) {
+		if polls.Add(1) <= lroInProgressPolls {
+			return createJsonResponse(request, http.StatusOK, map[string]any{"status": "InProgress"})
+		}
+
+		status := map[string]any{"status": finalStatus}
+		if !succeeded {
+			status["error"] = finalBody
+		}
+
+		return createJsonResponse(request, http.StatusOK, status)
+	})
+
+	if succeeded {
+		c.When(func(request *http.Request) bool {
+			if request.Method != http.MethodGet {
+				return false
+			}
+
+			if method == http.MethodPost {
+				return request.URL.String() == resultUrl
+			}
+
+			return strings.Contains(request.URL.String(), urlMatch)
+		}).RespondFn(func(request *http.Request) (*http.Response, error) {
+			return createJsonResponse(request, http.StatusOK, finalBody)
+		})
+	}
+
+	return c
+}
+
+func createJsonResponse(request *http.Request, statusCode int, body any) (*http.Response, error) {
+	responseJson, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Request:    request,
+		Body:       io.NopCloser(bytes.NewBuffer(responseJson)),
+	}, nil
+}
@@ -7,6 +7,8 @@ import (
 
 type MockHttpClient struct {
 	expressions []*HttpExpression
+	// When set, records the requests to the real transport or replays them from a cassette
+	recorder *recorder
 }
 
 type HttpExpression struct {
@@ -27,6 +29,17 @@ func NewMockHttpUtil() *MockHttpClient {
 }
 
 func (c *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	if c.recorder != nil {
+		switch c.recorder.mode {
+		case recorderModeRecord:
+			return c.recorder.record(req)
+		case recorderModeReplay:
+			if response, has := c.recorder.replay(req); has {
+				return response, nil
+			}
+		}
+	}
+
 	var match *HttpExpression
 
 	for i := len(c.expressions) - 1; i >= 0; i-- {
@@ -0,0 +1,184 @@
+package mockhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// A JSON cassette of recorded HTTP interactions, replayed in the order they were recorded
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// A recorded HTTP request and the response it received
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// The request of a recorded interaction. Request headers aren't recorded since they hold the credentials.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Url    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// The response of a recorded interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type recorderMode int
+
+const (
+	// Requests are sent to the real transport and the interactions are written to the cassette
+	recorderModeRecord recorderMode = iota
+	// Requests are served from the interactions of the cassette
+	recorderModeReplay
+)
+
+type recorder struct {
+	mode      recorderMode
+	path      string
+	transport *http.Client
+
+	lock     sync.Mutex
+	cassette Cassette
+	// Whether each interaction of the cassette has already been replayed
+	replayed []bool
+}
+
+// Sends all the requests to the real HTTP transport instead of the registered mocks, and records each of the
+// interactions to the JSON cassette at the specified path. The cassette is written after each interaction so it
+// doesn't need to be saved explicitly.
+func (c *MockHttpClient) EnableRecording(path string) {
+	c.recorder = &recorder{
+		mode:      recorderModeRecord,
+		path:      path,
+		transport: http.DefaultClient,
+	}
+}
+
+// Serves the requests from the interactions of the JSON cassette at the specified path. Each interaction is replayed
+// once, in the order it was recorded, for the first request with the same method and URL. Requests that don't match
+// any remaining interaction fall back to the registered mocks.
+func (c *MockHttpClient) EnableReplay(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading cassette '%s': %w", path, err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(contents, &cassette); err != nil {
+		return fmt.Errorf("unmarshalling cassette '%s': %w", path, err)
+	}
+
+	c.recorder = &recorder{
+		mode:     recorderModeReplay,
+		path:     path,
+		cassette: cassette,
+		replayed: make([]bool, len(cassette.Interactions)),
+	}
+
+	return nil
+}
+
+// Records the interaction of the request with the real transport
+func (r *recorder) record(request *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(&request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	response, err := r.transport.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := readBody(&response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: request.Method,
+			Url:    request.URL.String(),
+			Body:   requestBody,
+		},
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     response.Header,
+			Body:       responseBody,
+		},
+	})
+
+	contents, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling cassette: %w", err)
+	}
+
+	if err := os.WriteFile(r.path, contents, 0600); err != nil {
+		return nil, fmt.Errorf("writing cassette '%s': %w", r.path, err)
+	}
+
+	return response, nil
+}
+
+// Replays the first interaction of the cassette matching the request that hasn't been replayed yet.
+// Returns false when no interaction matches.
+func (r *recorder) replay(request *http.Request) (*http.Response, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.replayed[i] ||
+			interaction.Request.Method != request.Method ||
+			interaction.Request.Url != request.URL.String() {
+			continue
+		}
+
+		r.replayed[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		return &http.Response{
+			StatusCode: interaction.Response.StatusCode,
+			Header:     header,
+			Request:    request,
+			Body:       io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+		}, true
+	}
+
+	return nil, false
+}
+
+// Reads the body and replaces it with a copy, so it can still be read by the transport or the caller
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+
+	contents, err := io.ReadAll(*body)
+	if err != nil {
+		return "", err
+	}
+
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewBuffer(contents))
+
+	return string(contents), nil
+}
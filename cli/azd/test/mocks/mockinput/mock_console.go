@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
+	"testing"
 
 	"github.com/azure/azure-dev/cli/azd/pkg/alpha"
 	"github.com/azure/azure-dev/cli/azd/pkg/input"
@@ -34,6 +36,10 @@ type MockConsole struct {
 	expressions []*MockConsoleExpression
 	log         []string
 	spinnerOps  []SpinnerOp
+	// Responses queued by QueueResponses, consumed in order before the registered expressions
+	queue []QueuedResponse
+	// The number of queued responses consumed so far
+	consumed int
 }
 
 func NewMockConsole() *MockConsole {
@@ -162,6 +168,10 @@ func (c *MockConsole) Flush() {
 
 // Finds a matching mock expression and returns the configured value
 func (c *MockConsole) respond(command string, options input.ConsoleOptions) (any, error) {
+	if len(c.queue) > 0 {
+		return c.respondQueued(command, options)
+	}
+
 	var match *MockConsoleExpression
 
 	for _, expr := range c.expressions {
@@ -239,3 +249,117 @@ func (e *MockConsoleExpression) RespondFn(respond RespondFn) *MockConsole {
 	e.respond = respond
 	return e.console
 }
+
+// A scripted response for QueueResponses, answering a single prompt of a specific kind
+type QueuedResponse struct {
+	command string
+	// Whether the prompt must be a password prompt, only for secret responses
+	secret bool
+	value  any
+	err    error
+}
+
+// Queues the response to a confirmation prompt
+func ConfirmResponse(value bool) QueuedResponse {
+	return QueuedResponse{command: "Confirm", value: value}
+}
+
+// Queues the response to a single answer prompt
+func PromptResponse(value string) QueuedResponse {
+	return QueuedResponse{command: "Prompt", value: value}
+}
+
+// Queues the response to a single answer prompt that must be a password prompt
+func SecretResponse(value string) QueuedResponse {
+	return QueuedResponse{command: "Prompt", secret: true, value: value}
+}
+
+// Queues the index of the option chosen for a multiple choice selection
+func SelectResponse(index int) QueuedResponse {
+	return QueuedResponse{command: "Select", value: index}
+}
+
+// Queues the options chosen for a multiple choice selection with multiple answers
+func MultiSelectResponse(values []string) QueuedResponse {
+	return QueuedResponse{command: "MultiSelect", value: values}
+}
+
+// Sets the error that will be returned for the prompt along with the zero value of the response
+func (r QueuedResponse) WithError(err error) QueuedResponse {
+	r.err = err
+	return r
+}
+
+// Queues responses to be returned for the next prompts, in order. Each prompt consumes the next queued response,
+// which must be for the same kind of prompt, otherwise the mock panics with the expected and actual prompts.
+// Once all queued responses are consumed, prompts are matched against the registered expressions again.
+func (c *MockConsole) QueueResponses(responses ...QueuedResponse) *MockConsole {
+	c.queue = append(c.queue, responses...)
+	return c
+}
+
+// Fails the test when any of the responses queued by QueueResponses hasn't been consumed by a prompt
+func (c *MockConsole) AssertResponsesConsumed(t *testing.T) {
+	t.Helper()
+
+	if len(c.queue) == 0 {
+		return
+	}
+
+	pending := make([]string, len(c.queue))
+	for i, response := range c.queue {
+		pending[i] = response.String()
+	}
+
+	t.Errorf(
+		"expected all queued console responses to be consumed, %d consumed and %d pending: %s",
+		c.consumed,
+		len(c.queue),
+		strings.Join(pending, ", "),
+	)
+}
+
+func (r QueuedResponse) String() string {
+	if r.secret {
+		return fmt.Sprintf("Prompt (secret) '%v'", r.value)
+	}
+
+	return fmt.Sprintf("%s '%v'", r.command, r.value)
+}
+
+// Consumes the next queued response, which must match the kind of prompt
+func (c *MockConsole) respondQueued(command string, options input.ConsoleOptions) (any, error) {
+	next := c.queue[0]
+	if next.command != command || (next.secret && !options.IsPassword) {
+		panic(fmt.Sprintf(
+			"Queued console response #%d is %s, but got command: '%s' with options: '%+v'",
+			c.consumed+1,
+			next.String(),
+			command,
+			options,
+		))
+	}
+
+	c.queue = c.queue[1:]
+	c.consumed++
+
+	if next.err != nil {
+		return zeroResponse(command), next.err
+	}
+
+	return next.value, nil
+}
+
+// The zero value of the response for the kind of prompt
+func zeroResponse(command string) any {
+	switch command {
+	case "Confirm":
+		return false
+	case "Select":
+		return 0
+	case "MultiSelect":
+		return []string(nil)
+	default:
+		return ""
+	}
+}